@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git-ac/internal/bench"
+	"git-ac/internal/color"
+	"git-ac/internal/config"
+	"git-ac/internal/git"
+	"git-ac/pkg/gitac"
+)
+
+// defaultBenchSamples is the number of recent commits benchmarked without --samples
+const defaultBenchSamples = 5
+
+// runBench compares models on the staged changes, or on recent commits when
+// nothing is staged. The models are those named as arguments (a provider
+// type or profile, optionally with @MODEL), or the configured provider and
+// every profile.
+func runBench() error {
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	samples, err := benchSamples(repo, cfg)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("nothing to benchmark - stage some changes, or run in a repository with commits")
+	}
+
+	targets := benchTargets(repo, cfg)
+	project := gitac.LoadProjectContext(repo.Dir, cfg)
+
+	fmt.Fprintf(os.Stderr, "Benchmarking %d models on %d samples, one attempt each...\n", len(targets), len(samples))
+	var results []bench.Result
+	for _, target := range targets {
+		results = append(results, bench.Run(ctx, target, samples, project, func(sample bench.Sample) {
+			color.FaintPrintf("%s: %s\n", target.Name, sample.Name)
+		}))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPASSED\tPASS RATE\tAVG LATENCY\tMAX LATENCY\tERRORS")
+	for _, r := range results {
+		if r.Runs == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\n", r.Target)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%.0f%%\t%v\t%v\t%d\n", r.Target, r.Passed, r.Runs, r.PassRate()*100,
+			r.MeanLatency().Round(100*time.Millisecond), r.MaxLatency().Round(100*time.Millisecond), r.Errors)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", r.Target, r.Err)
+		}
+	}
+	return nil
+}
+
+// benchSamples returns the staged changes, or else up to --samples recent
+// commits, prepared for sending
+func benchSamples(repo git.Repo, cfg *config.Config) ([]bench.Sample, error) {
+	diff, err := gitac.StagedDiff(repo.Dir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if diff != "" {
+		prepared, err := gitac.PrepareDiff(diff, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []bench.Sample{{Name: "staged changes", Diff: prepared.Diff}}, nil
+	}
+
+	n := defaultBenchSamples
+	if samplesFlag != "" {
+		n, err = strconv.Atoi(samplesFlag)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid --samples '%s' (must be a positive number)", samplesFlag)
+		}
+	}
+
+	hashes, err := repo.GetRecentCommits(n)
+	if err != nil {
+		return nil, err
+	}
+	var samples []bench.Sample
+	for _, hash := range hashes {
+		diff, err := commitDiff(repo, hash, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if diff == "" {
+			continue
+		}
+		samples = append(samples, bench.Sample{Name: "commit " + hash[:7], Diff: diff})
+	}
+	return samples, nil
+}
+
+// benchTargets resolves the models to benchmark. Targets that can't be used,
+// including remote ones the repository doesn't allow, carry the reason.
+func benchTargets(repo git.Repo, cfg *config.Config) []bench.Target {
+	specs := commandArgs
+	if len(specs) == 0 {
+		specs = append([]string{""}, slices.Sorted(maps.Keys(cfg.Profiles))...)
+	}
+
+	var targets []bench.Target
+	for _, spec := range specs {
+		name, model, _ := strings.Cut(spec, "@")
+
+		target := bench.Target{Name: spec}
+		target.Config, target.Err = loadConfig()
+		if target.Err == nil && name != "" {
+			target.Err = target.Config.SelectProvider(name)
+		}
+		if target.Err == nil {
+			if model != "" {
+				target.Config.SetModel(model)
+			}
+			if name == "" {
+				name = target.Config.Provider.Type
+			}
+			target.Name = name + "@" + target.Config.Model()
+			target.Err = checkRemotePolicy(repo, target.Config)
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
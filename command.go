@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
+	"git-ac/internal/git"
+	"git-ac/internal/provider"
+	"git-ac/pkg/gitac"
+)
+
+// subcommand holds what the subcommands that read the repository and ask the
+// model share: the configuration, the repository, and a context limited to
+// --time-budget
+type subcommand struct {
+	ctx      context.Context
+	cfg      *config.Config
+	repo     git.Repo
+	cancel   context.CancelFunc
+	debugLog *debuglog.Logger
+}
+
+// startSubcommand loads the configuration and checks that git-ac is running in
+// a repository. The caller calls close when done.
+func startSubcommand() (*subcommand, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	repo := git.Repo{}
+	if err := repo.ValidateRepository(); err != nil {
+		return nil, fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeBudget > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeBudget)
+	}
+	return &subcommand{ctx: ctx, cfg: cfg, repo: repo, cancel: cancel}, nil
+}
+
+// openProvider checks that the repository allows the configured provider and
+// creates it, returning it with the project context to send along
+func (s *subcommand) openProvider() (provider.LLMProvider, gitac.ProjectContext, error) {
+	if err := checkRemotePolicy(s.repo, s.cfg); err != nil {
+		return nil, gitac.ProjectContext{}, err
+	}
+	llmProvider, debugLog, err := newProvider(s.ctx, s.cfg)
+	if err != nil {
+		return nil, gitac.ProjectContext{}, err
+	}
+	s.debugLog = debugLog
+	return llmProvider, gitac.LoadProjectContext(s.repo.Dir, s.cfg), nil
+}
+
+// close releases the context and the debug log
+func (s *subcommand) close() {
+	s.cancel()
+	_ = s.debugLog.Close()
+}
+
+// commitDiff returns the changes made by a commit, prepared for sending
+func commitDiff(repo git.Repo, hash string, cfg *config.Config) (string, error) {
+	base := repo.GetParent(hash)
+	if base == "" {
+		var err error
+		if base, err = repo.GetEmptyTree(); err != nil {
+			return "", err
+		}
+	}
+	diff, err := gitac.RangeDiff(repo.Dir, base, hash, false, cfg)
+	if err != nil || diff == "" {
+		return "", err
+	}
+	prepared, err := gitac.PrepareDiff(diff, cfg)
+	if err != nil {
+		return "", err
+	}
+	return prepared.Diff, nil
+}
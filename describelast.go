@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git-ac/internal/color"
+	"git-ac/internal/editor"
+	"git-ac/internal/llm"
+)
+
+// runDescribeLast generates an extended body for HEAD's subject line and
+// amends HEAD with it, to add detail to a terse message before pushing
+func runDescribeLast() error {
+	if subjectFlag != "" || noBodyFlag {
+		return fmt.Errorf("describe-last keeps HEAD's subject and generates a body - it cannot be used with --subject or --no-body")
+	}
+
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	hash, err := repo.ResolveCommit("HEAD")
+	if err != nil {
+		return err
+	}
+	message, err := repo.GetCommitMessage(hash)
+	if err != nil {
+		return err
+	}
+	subject, body, trailers := llm.SplitMessage(message)
+	if body != "" {
+		return fmt.Errorf("HEAD already has a body - use git commit --amend to change it")
+	}
+	diff, err := commitDiff(repo, hash, cfg)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		return fmt.Errorf("HEAD has no changes to describe")
+	}
+
+	// Keep HEAD's subject as it is, whatever convention it follows, and
+	// write the body for its type and scope
+	cfg.Commit.Subject = subject
+	if h, ok := llm.ParseHeader(subject); ok && cfg.Commit.Type == "" && cfg.Commit.Scope == "" {
+		cfg.Commit.Type, cfg.Commit.Scope = h.Type, h.Scope
+	}
+	cfg.Commit.IncludeBody = "true"
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	color.FaintPrintf("Describing %s %s...\n", hash[:7], subject)
+	candidates, err := llmProvider.GenerateCandidates(ctx, diff, project)
+	if err != nil {
+		return fmt.Errorf("failed to generate a body for %s: %w", hash[:7], err)
+	}
+	commitMsg := candidates[0].Message
+	if len(candidates) > 1 && !yesFlag && interactive() {
+		if commitMsg, err = chooseCandidate(candidates); err != nil {
+			return err
+		}
+	}
+	// Keep the trailers, such as Signed-off-by, the commit already had
+	if trailers != "" {
+		commitMsg += "\n\n" + trailers
+	}
+
+	if editFlag {
+		if commitMsg, err = editor.Edit(commitMsg, editorComment()); err != nil {
+			return fmt.Errorf("failed to edit commit message: %w", err)
+		}
+		if commitMsg == "" {
+			fmt.Fprintln(os.Stderr, "Aborting amend due to empty commit message.")
+			return nil
+		}
+	} else if !yesFlag {
+		diffStat, _ := repo.GetCommitStat(hash)
+		if commitMsg, err = confirmCommit(commitMsg, diffStat); err != nil {
+			return err
+		}
+		if commitMsg == "" {
+			fmt.Fprintln(os.Stderr, "Amend aborted.")
+			return nil
+		}
+	}
+
+	if err := repo.AmendMessage(commitMsg); err != nil {
+		return fmt.Errorf("%w: %w", errCommitFailed, err)
+	}
+	printCommitted(repo, "Successfully amended HEAD with message", commitMsg)
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"git-ac/internal/color"
+	"git-ac/internal/eval"
+	"git-ac/internal/llm"
+)
+
+// defaultEvalCount is the number of commits evaluated without --count
+const defaultEvalCount = 20
+
+// runEval regenerates the messages of recent commits and scores them against
+// the real ones
+func runEval() error {
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	n := defaultEvalCount
+	if countFlag != "" {
+		n, err = strconv.Atoi(countFlag)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid --count '%s' (must be a positive number)", countFlag)
+		}
+	}
+
+	hashes, err := repo.GetRecentCommits(n)
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return fmt.Errorf("no commits to evaluate")
+	}
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Evaluating %d commits with %s@%s...\n", len(hashes), cfg.Provider.Type, cfg.Model())
+	var cases []eval.Case
+	for i, hash := range hashes {
+		if ctx.Err() != nil {
+			break
+		}
+		color.FaintPrintf("[%d/%d] %s\n", i+1, len(hashes), hash[:7])
+
+		c := eval.Case{Commit: hash[:7]}
+		if c.Actual, err = repo.GetCommitMessage(hash); err != nil {
+			return err
+		}
+		var diff string
+		if diff, err = commitDiff(repo, hash, cfg); err != nil {
+			return err
+		}
+		if diff == "" {
+			continue
+		}
+		c.Generated, c.Err = llmProvider.GenerateCommitMessage(ctx, diff, project)
+		cases = append(cases, c)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	for _, c := range cases {
+		actualSubject, _, _ := llm.SplitMessage(c.Actual)
+		if c.Err != nil {
+			fmt.Printf("%s  failed: %v\n", c.Commit, c.Err)
+			fmt.Println(color.Faint("  actual:    " + actualSubject))
+			continue
+		}
+
+		score := eval.Compare(c.Generated, c.Actual)
+		typeNote := ""
+		if score.HasType && !score.TypeMatch {
+			typeNote = "  type differs"
+		}
+		generatedSubject, _, _ := llm.SplitMessage(c.Generated)
+		fmt.Printf("%s  similarity %.2f%s\n", c.Commit, score.Similarity, typeNote)
+		fmt.Println(color.Faint("  actual:    " + actualSubject))
+		fmt.Println("  generated: " + generatedSubject)
+	}
+
+	report := eval.Summarize(cases)
+	fmt.Println()
+	fmt.Printf("Commits:          %d", report.Cases)
+	if report.Failed > 0 {
+		fmt.Printf(" (%d failed)", report.Failed)
+	}
+	fmt.Println()
+	if report.Typed > 0 {
+		fmt.Printf("Type match:       %d/%d (%.0f%%)\n", report.TypeMatches, report.Typed, float64(report.TypeMatches)/float64(report.Typed)*100)
+	}
+	fmt.Printf("Mean similarity:  %.2f\n", report.MeanSimilarity)
+	return nil
+}
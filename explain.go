@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git-ac/internal/color"
+	"git-ac/internal/llm"
+)
+
+// runExplain describes in plain language what a commit (HEAD by default) does
+// and why, from its diff and message
+func runExplain() error {
+	if len(commandArgs) > 1 {
+		return fmt.Errorf("explain takes one commit (got %d)", len(commandArgs))
+	}
+	rev := "HEAD"
+	if len(commandArgs) == 1 {
+		rev = commandArgs[0]
+	}
+
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	hash, err := repo.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	message, err := repo.GetCommitMessage(hash)
+	if err != nil {
+		return err
+	}
+	diff, err := commitDiff(repo, hash, cfg)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		diff = "(no changes)"
+	}
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	subject, _, _ := llm.SplitMessage(message)
+	color.FaintPrintf("Explaining %s %s...\n", hash[:7], subject)
+	diff, _ = llm.FitDiff(diff, cfg.Commit.DiffTokenLimit)
+	explanation, err := llmProvider.GenerateText(ctx, llm.BuildExplainPrompt(message, diff, project))
+	if err != nil {
+		return fmt.Errorf("failed to explain %s: %w", rev, err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Println(explanation)
+	return nil
+}
@@ -4,10 +4,8 @@ go 1.25.0
 
 require (
 	github.com/ollama/ollama v0.11.11
+	golang.org/x/sys v0.31.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	golang.org/x/crypto v0.36.0 // indirect
-	golang.org/x/sys v0.31.0 // indirect
-)
+require golang.org/x/crypto v0.36.0 // indirect
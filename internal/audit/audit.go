@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"git-ac/internal/config"
+)
+
+// requestTimeout limits how long delivering an event to the webhook may take
+const requestTimeout = 10 * time.Second
+
+// Actions recorded in events
+const (
+	ActionCommit = "commit" // git-ac committed the message
+	ActionCopy   = "copy"   // The message was copied to the clipboard
+	ActionHook   = "hook"   // The message was written into the commit message file by the hook
+)
+
+// Event records who used which provider and model to produce a message
+type Event struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	User       string    `json:"user"`  // Git identity, "Name <email>"
+	Login      string    `json:"login"` // Operating system user
+	Hostname   string    `json:"hostname"`
+	Repository string    `json:"repository"`       // Repository root
+	Remote     string    `json:"remote,omitempty"` // URL of origin, without credentials
+	Commit     string    `json:"commit,omitempty"` // Hash of the commit made, for ActionCommit
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	Endpoint   string    `json:"endpoint"`           // Where the diff was sent
+	Generated  string    `json:"generated"`          // The message as generated
+	Message    string    `json:"message"`            // The message as used, after any edits
+	Version    string    `json:"version"`            // git-ac version
+	Redacted   int       `json:"redacted,omitempty"` // Possible secrets removed from the diff before sending
+	Withheld   []string  `json:"withheld,omitempty"` // Files whose contents weren't sent
+}
+
+// StripCredentials removes any user name and password from a URL, such as a
+// token in a remote's URL. Other strings, like scp-style remotes, are returned
+// as they are.
+func StripCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Logger delivers events to the configured sinks
+type Logger struct {
+	cfg    config.AuditConfig
+	client *http.Client
+}
+
+// New creates a logger for the sinks in cfg
+func New(cfg config.AuditConfig) *Logger {
+	return &Logger{cfg: cfg, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Enabled reports whether any sink is configured
+func (l *Logger) Enabled() bool {
+	return l.cfg.Webhook != "" || l.cfg.Syslog
+}
+
+// Record delivers an event to every configured sink, returning the errors of
+// any that failed
+func (l *Logger) Record(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	var errs []error
+	if l.cfg.Webhook != "" {
+		if err := l.post(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if l.cfg.Syslog {
+		if err := writeSyslog(string(data)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends an event to the webhook
+func (l *Logger) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", l.cfg.Webhook, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create audit webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range l.cfg.WebhookHeaders {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach audit webhook: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("audit webhook failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package audit
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// writeSyslog records a message in the system log
+func writeSyslog(message string) error {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "git-ac")
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	defer func() {
+		_ = w.Close()
+	}()
+
+	if err := w.Info(message); err != nil {
+		return fmt.Errorf("failed to write to syslog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package audit
+
+import "fmt"
+
+// writeSyslog fails: Windows has no syslog
+func writeSyslog(message string) error {
+	return fmt.Errorf("audit.syslog is not supported on Windows - use audit.webhook")
+}
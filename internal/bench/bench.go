@@ -0,0 +1,113 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+	"git-ac/internal/provider"
+)
+
+// Target is a provider and model to benchmark
+type Target struct {
+	Name   string         // How the target is shown, e.g. "ollama@qwen3:4b"
+	Config *config.Config // Configuration selecting the provider and model
+	Err    error          // Why the target can't be benchmarked, if it can't
+}
+
+// Sample is a diff, ready to be sent, to generate a message for
+type Sample struct {
+	Name string // e.g. "staged changes" or a commit hash
+	Diff string
+}
+
+// Result is how one target did across the samples
+type Result struct {
+	Target    string
+	Runs      int             // Samples attempted
+	Passed    int             // Messages that passed validation on the first attempt
+	Errors    int             // Generations that failed for reasons other than validation
+	Latencies []time.Duration // Time taken by each generation that produced a message, valid or not
+	Err       error           // The first error other than a validation failure
+}
+
+// PassRate returns the fraction of samples that produced a valid message
+func (r Result) PassRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Runs)
+}
+
+// MeanLatency returns the average time to generate a message
+func (r Result) MeanLatency() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, l := range r.Latencies {
+		total += l
+	}
+	return total / time.Duration(len(r.Latencies))
+}
+
+// MaxLatency returns the longest time taken to generate a message
+func (r Result) MaxLatency() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	return slices.Max(r.Latencies)
+}
+
+// Run generates a message for every sample with target, allowing one attempt
+// and one candidate each, so the pass rate reflects what the model produces
+// without retries. progress is called before each generation.
+func Run(ctx context.Context, target Target, samples []Sample, project llm.ProjectContext, progress func(sample Sample)) Result {
+	result := Result{Target: target.Name, Err: target.Err}
+	if target.Err != nil {
+		return result
+	}
+
+	cfg := *target.Config
+	cfg.Commit.MaxAttempts = 1
+	cfg.Commit.Candidates = 1
+
+	p, err := provider.NewProvider(&cfg, nil)
+	if err == nil {
+		err = p.HealthCheck(ctx)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, sample := range samples {
+		if ctx.Err() != nil {
+			break
+		}
+		progress(sample)
+
+		result.Runs++
+		started := time.Now()
+		_, err := p.GenerateCommitMessage(ctx, sample.Diff, project)
+		elapsed := time.Since(started)
+
+		var invalid *llm.ValidationError
+		switch {
+		case err == nil:
+			result.Passed++
+			result.Latencies = append(result.Latencies, elapsed)
+		case errors.As(err, &invalid):
+			result.Latencies = append(result.Latencies, elapsed)
+		default:
+			result.Errors++
+			if result.Err == nil {
+				result.Err = err
+			}
+		}
+	}
+	return result
+}
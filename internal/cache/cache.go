@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"git-ac/internal/xdg"
+)
+
+// remoteTimeout limits how long a shared backend may delay a lookup or store
+const remoteTimeout = 5 * time.Second
+
+// Remote is a shared cache backend, such as Redis or an S3 bucket, that a team
+// or CI fleet uses alongside each machine's local cache
+type Remote interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Put(ctx context.Context, key, value string) error
+}
+
+// Cache stores small text values on disk, keyed by hashes of their inputs
+type Cache struct {
+	dir    string // Empty for a cache kept only in remote
+	remote Remote
+
+	// remoteFailed is set after the first remote error, so an unreachable
+	// backend costs one timeout rather than one per lookup
+	remoteFailed atomic.Bool
+}
+
+// New returns a cache stored in dir
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Default returns the cache in $XDG_CACHE_HOME/git-ac (~/.cache/git-ac by default)
+func Default() (*Cache, error) {
+	cacheHome, err := xdg.CacheHome()
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(cacheHome, "git-ac")), nil
+}
+
+// NewRemote returns a cache kept only in remote, for when there's no usable
+// local cache directory
+func NewRemote(remote Remote) *Cache {
+	return &Cache{remote: remote}
+}
+
+// WithRemote returns a cache that also reads from and writes to remote. Values
+// found only in remote are copied to the local cache.
+func (c *Cache) WithRemote(remote Remote) *Cache {
+	return &Cache{dir: c.dir, remote: remote}
+}
+
+// Key derives a cache key from the given parts
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the value stored under key in the given namespace, if any
+func (c *Cache) Get(namespace, key string) (string, bool) {
+	if c.dir != "" {
+		if data, err := os.ReadFile(c.path(namespace, key)); err == nil {
+			return string(data), true
+		}
+	}
+	if !c.useRemote() {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	value, ok, err := c.remote.Get(ctx, namespace+"/"+key)
+	if err != nil {
+		c.remoteFailed.Store(true)
+		return "", false
+	}
+	if ok {
+		// A local copy is only an optimization
+		_ = c.putLocal(namespace, key, value)
+	}
+	return value, ok
+}
+
+// Put stores value under key in the given namespace. Failing to store it in
+// the shared backend is not an error; the value is still cached locally, if
+// there's a local cache.
+func (c *Cache) Put(namespace, key, value string) error {
+	if err := c.putLocal(namespace, key, value); err != nil {
+		return err
+	}
+	if !c.useRemote() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	if err := c.remote.Put(ctx, namespace+"/"+key, value); err != nil {
+		c.remoteFailed.Store(true)
+	}
+	return nil
+}
+
+func (c *Cache) useRemote() bool {
+	return c.remote != nil && !c.remoteFailed.Load()
+}
+
+func (c *Cache) putLocal(namespace, key, value string) error {
+	if c.dir == "" {
+		return nil
+	}
+	path := c.path(namespace, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so concurrent readers never see partial values
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.WriteString(value); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close cache file: %w", err)
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func (c *Cache) path(namespace, key string) string {
+	// Fan out by key prefix to keep directories small
+	return filepath.Join(c.dir, namespace, key[:2], key)
+}
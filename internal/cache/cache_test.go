@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// memoryRemote is a Remote kept in memory, which fails every request once
+// failing is set
+type memoryRemote struct {
+	values  map[string]string
+	calls   int
+	failing bool
+}
+
+func (m *memoryRemote) Get(ctx context.Context, key string) (string, bool, error) {
+	m.calls++
+	if m.failing {
+		return "", false, errors.New("unreachable")
+	}
+	value, ok := m.values[key]
+	return value, ok, nil
+}
+
+func (m *memoryRemote) Put(ctx context.Context, key, value string) error {
+	m.calls++
+	if m.failing {
+		return errors.New("unreachable")
+	}
+	m.values[key] = value
+	return nil
+}
+
+func TestCacheWithRemote(t *testing.T) {
+	dir := t.TempDir()
+	remote := &memoryRemote{values: map[string]string{"summary/shared": "from the team"}}
+	c := New(dir).WithRemote(remote)
+
+	if err := c.Put("summary", "mine", "from here"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if remote.values["summary/mine"] != "from here" {
+		t.Errorf("Put() didn't store the value remotely: %v", remote.values)
+	}
+	if value, ok := New(dir).Get("summary", "mine"); !ok || value != "from here" {
+		t.Errorf("Put() didn't store the value locally: %q, %v", value, ok)
+	}
+
+	if value, ok := c.Get("summary", "shared"); !ok || value != "from the team" {
+		t.Errorf("Get() = %q, %v, want the remote value", value, ok)
+	}
+	if value, ok := New(dir).Get("summary", "shared"); !ok || value != "from the team" {
+		t.Errorf("Get() didn't copy the remote value locally: %q, %v", value, ok)
+	}
+}
+
+func TestCacheRemoteOnly(t *testing.T) {
+	remote := &memoryRemote{values: map[string]string{}}
+	c := NewRemote(remote)
+
+	if err := c.Put("summary", "key", "value"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if value, ok := c.Get("summary", "key"); !ok || value != "value" {
+		t.Errorf("Get() = %q, %v, want the stored value", value, ok)
+	}
+	// With no directory, local files would land in the working directory
+	if _, err := os.Stat("summary"); err == nil {
+		t.Errorf("a cache kept only in remote wrote files")
+	}
+	if _, ok := c.Get("summary", "missing"); ok {
+		t.Errorf("Get() found a value that was never stored")
+	}
+}
+
+func TestCacheRemoteFailure(t *testing.T) {
+	remote := &memoryRemote{values: map[string]string{}, failing: true}
+	c := New(t.TempDir()).WithRemote(remote)
+
+	if err := c.Put("summary", "key", "value"); err != nil {
+		t.Fatalf("Put() error = %v, want the remote failure ignored", err)
+	}
+	if value, ok := c.Get("summary", "key"); !ok || value != "value" {
+		t.Errorf("Get() = %q, %v, want the local value", value, ok)
+	}
+	c.Get("summary", "missing")
+	if remote.calls != 1 {
+		t.Errorf("the remote was asked %d times, want once before it's given up on", remote.calls)
+	}
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisKeyPrefix keeps git-ac's keys apart from others in a shared database
+const redisKeyPrefix = "git-ac:"
+
+// Redis is a shared cache backend in a Redis (or Redis-compatible) server
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+	ttl      time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis returns a backend for the server at rawURL, which has the form
+// redis://[[user]:password@]host[:port][/db], or rediss:// for TLS. Entries
+// expire after ttl; 0 leaves expiry to the server's eviction policy.
+func NewRedis(rawURL string, ttl time.Duration) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL %q - must start with redis:// or rediss://", rawURL)
+	}
+
+	r := &Redis{
+		addr: u.Host,
+		tls:  u.Scheme == "rediss",
+		ttl:  ttl,
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		r.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q in URL", db)
+		}
+	}
+	return r, nil
+}
+
+// Get returns the value stored under key, if any
+func (r *Redis) Get(ctx context.Context, key string) (string, bool, error) {
+	reply, err := r.do(ctx, "GET", redisKeyPrefix+key)
+	if err != nil {
+		return "", false, err
+	}
+	if reply == nil {
+		return "", false, nil
+	}
+	return *reply, true, nil
+}
+
+// Put stores value under key
+func (r *Redis) Put(ctx context.Context, key, value string) error {
+	args := []string{"SET", redisKeyPrefix + key, value}
+	if r.ttl > 0 {
+		args = append(args, "EX", strconv.Itoa(int(r.ttl.Seconds())))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// do sends a command and returns its reply, which is nil for a missing value.
+// The connection is reused between commands and reopened after an error.
+func (r *Redis) do(ctx context.Context, args ...string) (*string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := r.roundTrip(ctx, args)
+	if err != nil {
+		if _, ok := err.(redisError); !ok {
+			// The connection may hold a partial reply
+			_ = r.conn.Close()
+			r.conn = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (r *Redis) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis at %s: %w", r.addr, err)
+	}
+	if r.tls {
+		host, _, _ := net.SplitHostPort(r.addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("TLS handshake with Redis at %s failed: %w", r.addr, err)
+		}
+		conn = tlsConn
+	}
+	r.conn = conn
+	r.reader = bufio.NewReader(conn)
+
+	var setup [][]string
+	switch {
+	case r.username != "":
+		setup = append(setup, []string{"AUTH", r.username, r.password})
+	case r.password != "":
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		if _, err := r.roundTrip(ctx, args); err != nil {
+			_ = conn.Close()
+			r.conn = nil
+			return fmt.Errorf("redis %s failed: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// redisError is an error reply from the server, after which the connection
+// remains usable
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+func (r *Redis) roundTrip(ctx context.Context, args []string) (*string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(remoteTimeout)
+	}
+	if err := r.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, cmd.String()); err != nil {
+		return nil, fmt.Errorf("failed to send Redis command: %w", err)
+	}
+
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		value := line[1:]
+		return &value, nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r.reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+		}
+		value := string(data[:n])
+		return &value, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
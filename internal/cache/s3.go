@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 is a shared cache backend in a bucket of an S3-compatible object store,
+// such as AWS S3, MinIO, or Cloudflare R2
+type S3 struct {
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+// S3Options configures an S3 backend
+type S3Options struct {
+	Endpoint     string // e.g. "https://s3.us-east-1.amazonaws.com"
+	Bucket       string
+	Prefix       string // Prepended to each object key
+	Region       string // Defaults to us-east-1
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // Only needed for temporary credentials
+}
+
+// NewS3 returns a backend for the bucket described by opts. Objects are
+// addressed path-style (endpoint/bucket/key), which all S3-compatible stores
+// support.
+func NewS3(opts S3Options) (*S3, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q - must be a URL starting with http:// or https://", opts.Endpoint)
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required - set an access key and secret key")
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		endpoint:     endpoint,
+		bucket:       opts.Bucket,
+		prefix:       opts.Prefix,
+		region:       region,
+		accessKey:    opts.AccessKey,
+		secretKey:    opts.SecretKey,
+		sessionToken: opts.SessionToken,
+		client:       &http.Client{},
+	}, nil
+}
+
+// Get returns the value stored under key, if any
+func (s *S3) Get(ctx context.Context, key string) (string, bool, error) {
+	resp, err := s.do(ctx, "GET", key, "")
+	if err != nil {
+		return "", false, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read S3 object: %w", err)
+		}
+		return string(data), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, s3Error(resp)
+	}
+}
+
+// Put stores value under key
+func (s *S3) Put(ctx context.Context, key, value string) error {
+	resp, err := s.do(ctx, "PUT", key, value)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *S3) do(ctx context.Context, method, key, body string) (*http.Response, error) {
+	path := s.endpoint.EscapedPath() + "/" + escapeS3Path(s.bucket) + "/" + escapeS3Path(s.prefix+key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+s.endpoint.Host+path, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	s.sign(req, path, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach S3 endpoint %s: %w", s.endpoint.Host, err)
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (s *S3) sign(req *http.Request, path, body string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + s.sessionToken + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // No query string
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// escapeS3Path percent-encodes everything but unreserved characters and
+// slashes, as Signature Version 4 requires
+func escapeS3Path(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		return fmt.Errorf("S3 request denied (403) - check the cache's S3 credentials and bucket permissions")
+	default:
+		return fmt.Errorf("S3 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
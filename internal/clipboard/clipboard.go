@@ -0,0 +1,54 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Copy places text on the system clipboard
+func Copy(text string) error {
+	name, args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	return nil
+}
+
+// clipboardCommand finds a clipboard utility for the current platform
+func clipboardCommand() (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "pbcopy", nil, nil
+	case "windows":
+		return "clip", nil, nil
+	}
+
+	// On Linux and BSDs, prefer Wayland when running under it, then X11 tools
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"wl-copy"}}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			return candidate[0], candidate[1:], nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("no clipboard utility found - install wl-copy, xclip, or xsel")
+}
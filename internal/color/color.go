@@ -2,6 +2,7 @@ package color
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 )
@@ -11,8 +12,66 @@ const (
 	Reset = "\033[0m"
 	Gray  = "\033[90m" // Bright black (gray)
 	Dim   = "\033[2m"  // Dim/faint
+	Bold  = "\033[1m"
 )
 
+// Color modes accepted by SetMode
+const (
+	ModeAuto   = "auto"
+	ModeAlways = "always"
+	ModeNever  = "never"
+)
+
+var mode = ModeAuto
+
+// output receives the status messages printed by FaintPrintf. They go to
+// stderr, so stdout only carries results, such as messages, for piping.
+var output io.Writer = os.Stderr
+
+// SetMode selects whether color is used: auto (detect), always, or never
+func SetMode(m string) error {
+	switch m {
+	case ModeAuto, ModeAlways, ModeNever:
+		mode = m
+		return nil
+	default:
+		return fmt.Errorf("invalid color mode '%s' (valid: auto, always, never)", m)
+	}
+}
+
+// Enabled reports whether output should be colored
+func Enabled() bool {
+	switch mode {
+	case ModeAlways:
+		enableVirtualTerminal()
+		return true
+	case ModeNever:
+		return false
+	}
+
+	// https://no-color.org: any non-empty NO_COLOR disables color
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	// FORCE_COLOR and CLICOLOR_FORCE enable color even when not writing to a terminal
+	if isForced(os.Getenv("FORCE_COLOR")) || isForced(os.Getenv("CLICOLOR_FORCE")) {
+		enableVirtualTerminal()
+		return true
+	}
+
+	if os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+
+	return isTerminal() && supportsColor()
+}
+
+// isForced interprets the value of a force-color environment variable
+func isForced(value string) bool {
+	return value != "" && value != "0" && value != "false"
+}
+
 // isTerminal checks if the output is going to a terminal
 func isTerminal() bool {
 	// Check if stdout is a terminal
@@ -32,6 +91,11 @@ func isTerminal() bool {
 
 // supportsColor checks if the terminal supports color output
 func supportsColor() bool {
+	// Windows consoles render color once virtual terminal processing is on
+	if runtime.GOOS == "windows" && enableVirtualTerminal() {
+		return true
+	}
+
 	// Check common environment variables that indicate color support
 	term := os.Getenv("TERM")
 	colorTerm := os.Getenv("COLORTERM")
@@ -55,14 +119,22 @@ func supportsColor() bool {
 
 // Faint returns text in a lighter/dimmed color if the terminal supports it
 func Faint(text string) string {
-	if isTerminal() && supportsColor() {
+	if Enabled() {
 		return Dim + text + Reset
 	}
 	return text
 }
 
+// Strong returns text in bold if the terminal supports it
+func Strong(text string) string {
+	if Enabled() {
+		return Bold + text + Reset
+	}
+	return text
+}
+
 // Printf prints formatted text in a lighter/dimmed color if the terminal supports it
 func FaintPrintf(format string, args ...interface{}) {
 	text := fmt.Sprintf(format, args...)
-	fmt.Print(Faint(text))
+	_, _ = fmt.Fprint(output, Faint(text))
 }
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package color
+
+// enableVirtualTerminal is only needed on Windows; other terminals handle ANSI escapes natively
+func enableVirtualTerminal() bool {
+	return false
+}
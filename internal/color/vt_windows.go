@@ -0,0 +1,38 @@
+//go:build windows
+
+package color
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	vtOnce    sync.Once
+	vtEnabled bool
+)
+
+// enableVirtualTerminal turns on ANSI escape sequence processing for the console
+// attached to stdout, reporting whether escape sequences will render
+func enableVirtualTerminal() bool {
+	vtOnce.Do(func() {
+		handle := windows.Handle(os.Stdout.Fd())
+
+		var consoleMode uint32
+		if err := windows.GetConsoleMode(handle, &consoleMode); err != nil {
+			// Not a console (e.g. a pipe or mintty); leave detection to the environment checks
+			return
+		}
+
+		if consoleMode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+			vtEnabled = true
+			return
+		}
+
+		// Older consoles (before Windows 10 1511) reject this flag; color stays off for them
+		vtEnabled = windows.SetConsoleMode(handle, consoleMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+	})
+	return vtEnabled
+}
@@ -1,70 +1,423 @@
 package config
 
 import (
+	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"git-ac/internal/xdg"
+
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Provider ProviderConfig `yaml:"provider"`
-	Commit   CommitConfig   `yaml:"commit"`
+	Provider ProviderConfig            `yaml:"provider"`
+	Profiles map[string]ProviderConfig `yaml:"profiles"` // Named alternative providers, selectable with --provider
+	Commit   CommitConfig              `yaml:"commit"`
+	Diff     DiffConfig                `yaml:"diff"`
+	Cache    CacheConfig               `yaml:"cache"`
+	History  HistoryConfig             `yaml:"history"`
+	Privacy  PrivacyConfig             `yaml:"privacy"`
+	Prompt   PromptConfig              `yaml:"prompt"`
+	Linear   LinearConfig              `yaml:"linear"`
+	Audit    AuditConfig               `yaml:"audit"`
+	Standup  StandupConfig             `yaml:"standup"`
+	Watch    WatchConfig               `yaml:"watch"`
+	DebugLog string                    `yaml:"debug_log"` // File that LLM requests and raw responses are appended to
+
+	Warnings []string `yaml:"-"` // Problems with the config file that don't stop it from loading
+
+	// commit.diff_token_limit and commit.large_diff_threshold as configured,
+	// before applyContextWindow fits them to the provider's context window
+	diffTokenLimit, largeDiffThreshold int
 }
 
+// ProviderTypes are the supported provider types
+var ProviderTypes = []string{"ollama", "openai", "gateway"}
+
 type ProviderConfig struct {
-	Type    string        `yaml:"type"` // "ollama" or "openai"
-	Timeout time.Duration `yaml:"timeout"`
+	Type       string        `yaml:"type"` // "ollama", "openai", or "gateway"
+	Timeout    time.Duration `yaml:"timeout"`
+	MaxRetries int           `yaml:"max_retries"` // Retries of requests that failed to reach the provider, or were rate limited
+	OnFailure  string        `yaml:"on_failure"`  // "abort", "edit" to write the message yourself when generation fails, or "template" to start from a template
+
+	// Tokens the model can attend to, prompt and response together. 0 means
+	// unknown: Ollama gets num_ctx 4096 and diffs are only limited by
+	// commit.diff_token_limit.
+	ContextWindow int `yaml:"context_window"`
 
 	// Ollama-specific config
 	Ollama *OllamaConfig `yaml:"ollama,omitempty"`
 
 	// OpenAI-compatible config
 	OpenAI *OpenAIConfig `yaml:"openai,omitempty"`
+
+	// Team gateway (git-ac serve --auth) config
+	Gateway *GatewayConfig `yaml:"gateway,omitempty"`
 }
 
 type OllamaConfig struct {
-	Host    string        `yaml:"host"`
-	Model   string        `yaml:"model"`
-	Timeout time.Duration `yaml:"-"` // Not serialized, passed from provider config
+	Host      string        `yaml:"host"`
+	Model     string        `yaml:"model"`
+	KeepAlive time.Duration `yaml:"keep_alive"` // How long Ollama keeps the model loaded after a request; 0 means Ollama's default
+	Timeout   time.Duration `yaml:"-"`          // Not serialized, passed from provider config
+
+	SkipHealthCheck bool `yaml:"-"` // Set by --no-health-check
+}
+
+// GatewayConfig points at a team's git-ac gateway, which holds the provider credentials
+type GatewayConfig struct {
+	URL   string `yaml:"url"`   // e.g. "https://git-ac.example.com"
+	Token string `yaml:"token"` // The gateway's --auth token
 }
 
 type OpenAIConfig struct {
-	BaseURL string `yaml:"base_url"`
-	APIKey  string `yaml:"api_key"`
-	Model   string `yaml:"model"`
+	BaseURL         string `yaml:"base_url"`
+	APIKey          string `yaml:"api_key"`
+	Model           string `yaml:"model"`
+	CompletionsPath string `yaml:"completions_path"` // Path of the chat completions endpoint under base_url; "/chat/completions" if empty
+
+	OAuth2 *OAuth2Config `yaml:"oauth2"` // Get access tokens from a token endpoint instead of using api_key
+}
+
+// OAuth2Config gets access tokens with the OAuth 2.0 client credentials grant,
+// for gateways that don't accept static API keys
+type OAuth2Config struct {
+	TokenURL     string   `yaml:"token_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
 }
 
 type CommitConfig struct {
-	MaxLength        int `yaml:"max_length"`
-	DiffTokenLimit   int `yaml:"diff_token_limit"`
+	MaxLength          int            `yaml:"max_length"`
+	DiffTokenLimit     int            `yaml:"diff_token_limit"`
+	Types              []CommitType   `yaml:"types"`                // Commit types the model may choose from
+	Scopes             []string       `yaml:"scopes"`               // Allowed scopes; empty means scopes are not used
+	IncludeBody        string         `yaml:"include_body"`         // "true", "false", or "auto"
+	Copy               bool           `yaml:"copy"`                 // Copy the message to the clipboard instead of committing
+	LargeDiffThreshold int            `yaml:"large_diff_threshold"` // Approximate tokens above which two-stage mode is used; 0 means half of diff_token_limit
+	TwoStage           string         `yaml:"two_stage"`            // "auto", "always", or "never"
+	StructuredOutput   bool           `yaml:"structured_output"`    // Request JSON message parts and assemble the message locally
+	BodyWidth          int            `yaml:"body_width"`           // Column at which the body is hard-wrapped; 0 disables wrapping
+	BodyStyle          string         `yaml:"body_style"`           // "bullets", "paragraph", or "none"
+	Mood               string         `yaml:"mood"`                 // Subject verb form: "imperative", "present", or "past"
+	Tone               string         `yaml:"tone"`                 // "terse" or "descriptive"
+	MaxBodyLines       int            `yaml:"max_body_lines"`       // Lines beyond this are dropped from the body; 0 means no limit
+	MaxTotalChars      int            `yaml:"max_total_chars"`      // Body lines are dropped until the message fits; 0 means no limit
+	DiffStatFooter     bool           `yaml:"diffstat_footer"`      // End the body with a line such as "3 files changed, +120/-45"
+	BannedPhrases      []string       `yaml:"banned_phrases"`       // Vague phrases that make a subject invalid, case-insensitive
+	Candidates         int            `yaml:"candidates"`           // Number of messages generated to choose from
+	MaxAttempts        int            `yaml:"max_attempts"`         // Generation attempts before giving up on messages that fail validation
+	Cleaning           CleaningConfig `yaml:"cleaning"`
+	Filters            []string       `yaml:"filters"` // Commands each generated message is passed through, in order
+	Stream             bool           `yaml:"stream"`  // Show the message in the terminal as it is generated
+
+	// Command-line overrides; not read from the config file
+	Type    string `yaml:"-"` // Forced commit type
+	Scope   string `yaml:"-"` // Forced commit scope
+	Subject string `yaml:"-"` // The user's subject line; only the body is generated
 }
 
-func Load() (*Config, error) {
+// CommitType is a conventional commit type. In the config file it may be written as
+// just a name, or as a mapping with a name and description.
+type CommitType struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// typeDescriptions describes common conventional commit types, so they can be listed by name alone
+var typeDescriptions = map[string]string{
+	"feat":     "new or improved feature work",
+	"fix":      "fixing bugs or shortcomings",
+	"refactor": "internal refactoring that improves quality, is not user-facing, and does not affect program behavior",
+	"docs":     "documentation",
+	"style":    "formatting",
+	"test":     "testing",
+	"chore":    "maintenance that is not feature-related or user-facing",
+	"perf":     "performance improvements",
+	"build":    "changes to the build system or dependencies",
+	"ci":       "changes to CI configuration and scripts",
+	"revert":   "reverting a previous commit",
+}
+
+func (t *CommitType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Name = node.Value
+		t.Description = typeDescriptions[node.Value]
+		return nil
+	}
+
+	type plain CommitType
+	if err := node.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	if t.Description == "" {
+		t.Description = typeDescriptions[t.Name]
+	}
+	return nil
+}
+
+// DefaultCommitTypes returns the commit types used when none are configured
+func DefaultCommitTypes() []CommitType {
+	var types []CommitType
+	for _, name := range []string{"feat", "fix", "refactor", "docs", "style", "test", "chore"} {
+		types = append(types, CommitType{Name: name, Description: typeDescriptions[name]})
+	}
+	return types
+}
+
+// HasType reports whether name is one of the configured commit types
+func (c CommitConfig) HasType(name string) bool {
+	for _, t := range c.Types {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeNames returns the names of the configured commit types
+func (c CommitConfig) TypeNames() []string {
+	names := make([]string, 0, len(c.Types))
+	for _, t := range c.Types {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// CheckScope checks that scope can be used in a header and, if scopes are
+// listed, is one of them
+func (c CommitConfig) CheckScope(scope string) error {
+	if err := ValidateScope(scope); err != nil {
+		return err
+	}
+	if len(c.Scopes) > 0 && !slices.ContainsFunc(c.Scopes, func(s string) bool { return strings.EqualFold(s, scope) }) {
+		return fmt.Errorf("scope %q is not in commit.scopes (allowed: %s)", scope, strings.Join(c.Scopes, ", "))
+	}
+	return nil
+}
+
+// CleaningConfig controls how raw model output is cleaned up into a commit message
+type CleaningConfig struct {
+	ThinkTags     []string `yaml:"think_tags"`     // Tags whose contents are model reasoning, e.g. "think" for <think>...</think>
+	StripPrefixes []string `yaml:"strip_prefixes"` // Preambles removed from the start of the response
+	StopPhrases   []string `yaml:"stop_phrases"`   // Everything from one of these phrases on is dropped (below the subject line)
+}
+
+// DefaultCleaningConfig returns the cleaning rules used unless configured otherwise
+func DefaultCleaningConfig() CleaningConfig {
+	return CleaningConfig{
+		ThinkTags: []string{"think"},
+		StripPrefixes: []string{
+			"Here is the commit message:",
+			"Here's the commit message:",
+			"Commit message:",
+			"Output:",
+		},
+		StopPhrases: []string{
+			"No extended description",
+			"Extended description: none",
+			"Explanation:",
+			"Reasoning:",
+		},
+	}
+}
+
+type DiffConfig struct {
+	ContextLines bool   `yaml:"context_lines"`   // Include unchanged lines around each change
+	Format       string `yaml:"format"`          // "annotated" (ADDED:/REMOVED: lines) or "unified" (git's +/- lines)
+	MinMoved     int    `yaml:"min_moved_lines"` // Moved blocks of at least this many lines are collapsed to a note; 0 disables
+	MaxBytes     int    `yaml:"max_bytes"`       // Larger staged diffs are described from a summary; 0 means no limit
+	Show         bool   `yaml:"show"`            // Show the staged diff for confirmation before generating, when interactive
+}
+
+type CacheConfig struct {
+	Enabled bool             `yaml:"enabled"` // Cache per-file summaries in $XDG_CACHE_HOME/git-ac
+	Backend string           `yaml:"backend"` // "local", or "redis" or "s3" to also share summaries with others using the same backend
+	Redis   RedisCacheConfig `yaml:"redis"`
+	S3      S3CacheConfig    `yaml:"s3"`
+}
+
+// RedisCacheConfig locates a shared summary cache in Redis
+type RedisCacheConfig struct {
+	URL string        `yaml:"url"` // redis://[[user]:password@]host[:port][/db], or rediss:// for TLS
+	TTL time.Duration `yaml:"ttl"` // How long summaries are kept; 0 leaves expiry to the server
+}
+
+// S3CacheConfig locates a shared summary cache in an S3-compatible bucket
+type S3CacheConfig struct {
+	Endpoint  string `yaml:"endpoint"` // e.g. https://s3.us-east-1.amazonaws.com
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"` // Prepended to object keys
+	Region    string `yaml:"region"` // Defaults to us-east-1
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled"` // Record generated messages and their outcomes in $XDG_STATE_HOME/git-ac, for git-ac stats
+}
+
+// PromptConfig controls the project context included in the prompt
+type PromptConfig struct {
+	Readme           ReadmeConfig `yaml:"readme"`
+	ContextFiles     []string     `yaml:"context_files"`      // Repository files included as project context
+	ContextFileLines int          `yaml:"context_file_lines"` // Limit on the lines included from each context file; 0 means no limit
+	Conventions      bool         `yaml:"conventions"`        // Include the repository's commit conventions file, such as .gitmessage
+	LearnScopes      bool         `yaml:"learn_scopes"`       // Limit scopes to those the repository's history uses, when commit.scopes isn't set
+
+	SystemPrefix      string `yaml:"system_prefix"`      // Text placed before the built-in instructions
+	ExtraInstructions string `yaml:"extra_instructions"` // Standing rules added after the built-in requirements
+}
+
+// ReadmeConfig controls which parts of the README are included in the prompt
+type ReadmeConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	MaxLines int      `yaml:"max_lines"` // Limit on the lines included; 0 means no limit
+	Sections []string `yaml:"sections"`  // Headings of sections included after the first paragraph
+}
+
+// LinearConfig controls the use of Linear issues named in branch names
+type LinearConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	APIKey    string   `yaml:"api_key"`    // Personal API key used to look up issue titles; LINEAR_API_KEY if empty
+	Teams     []string `yaml:"teams"`      // Team keys (e.g. "ENG") of issue IDs to recognize; empty means any team
+	MagicWord string   `yaml:"magic_word"` // Word before the issue ID in the message footer, e.g. "Fixes"; "none" omits the footer
+}
+
+// linearMagicWords are the words Linear recognizes before an issue ID in a
+// commit message. The first group closes the issue when the commit is merged.
+var linearMagicWords = []string{
+	"close", "closes", "closed", "closing", "fix", "fixes", "fixed", "fixing",
+	"resolve", "resolves", "resolved", "resolving", "complete", "completes", "completed", "completing",
+	"ref", "refs", "references", "part of", "related to", "contributes to", "toward", "towards",
+}
+
+// StandupConfig controls which commits git-ac standup summarizes
+type StandupConfig struct {
+	Days     int      `yaml:"days"`     // How many days back to look; 1 looks back to the last weekday
+	Branches []string `yaml:"branches"` // Branches to look at; all local branches if empty
+}
+
+// WatchConfig controls when git-ac watch pre-generates a message
+type WatchConfig struct {
+	Settle time.Duration `yaml:"settle"` // How long the staged changes must stay the same first
+}
+
+// AuditConfig controls where a record of each generated message that is used is sent
+type AuditConfig struct {
+	Webhook        string            `yaml:"webhook"`         // URL that events are POSTed to as JSON
+	WebhookHeaders map[string]string `yaml:"webhook_headers"` // Extra request headers, e.g. for authentication
+	Syslog         bool              `yaml:"syslog"`          // Also write events to the system log
+}
+
+// PrivacyConfig controls what is removed from the diff before it is sent to the provider
+type PrivacyConfig struct {
+	RedactSecrets     bool            `yaml:"redact_secrets"`      // Replace detected secrets with placeholders
+	DetectHighEntropy bool            `yaml:"detect_high_entropy"` // Also treat random-looking tokens as secrets
+	SecretPatterns    []SecretPattern `yaml:"secret_patterns"`     // Additional detectors, on top of the built-in ones
+	RedactPaths       []string        `yaml:"redact_paths"`        // Globs of files whose contents are never sent, only their names
+	AllowRemote       bool            `yaml:"allow_remote"`        // Allow providers that aren't on this machine; repos can opt out with git-ac.allowRemote
+	ConfirmRemote     bool            `yaml:"confirm_remote"`      // Ask once per repository before sending its diff to a remote provider
+}
+
+// SecretPattern is a user-defined secret detector
+type SecretPattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"` // Regular expression matching the secret
+}
+
+// Path returns the config file: $XDG_CONFIG_HOME/git-ac/config.yaml
+// (~/.config/git-ac/config.yaml by default, or %APPDATA%\git-ac\config.yaml on
+// Windows), or if there's none, the older ~/.config/git-ac.yaml. The file
+// doesn't necessarily exist.
+func Path() (string, error) {
+	configHome, err := xdg.ConfigHome()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(configHome, "git-ac", "config.yaml")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
 	}
+	legacyPath := filepath.Join(homeDir, ".config", "git-ac.yaml")
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath, nil
+	}
+	return path, nil
+}
 
-	configPath := filepath.Join(homeDir, ".config", "git-ac.yaml")
+func Load() (*Config, error) {
+	configPath, err := Path()
+	if err != nil {
+		return nil, err
+	}
 
 	// Start with defaults
 	cfg := &Config{
 		Provider: ProviderConfig{
-			Type:    "ollama",
-			Timeout: 30 * time.Second,
-			Ollama: &OllamaConfig{
-				Host:  "http://localhost:11434",
-				Model: "llama2",
-			},
+			Type:       "ollama",
+			Timeout:    30 * time.Second,
+			MaxRetries: 2,
+			OnFailure:  "abort",
+			Ollama:     defaultOllamaConfig(),
 		},
 		Commit: CommitConfig{
 			MaxLength:      72,
 			DiffTokenLimit: 16384,
+			Types:          DefaultCommitTypes(),
+			IncludeBody:    "auto",
+			TwoStage:       "auto",
+			BodyWidth:      72,
+			BodyStyle:      "none",
+			Mood:           "imperative",
+			Tone:           "terse",
+			MaxBodyLines:   30,
+			BannedPhrases:  []string{"various changes", "minor fixes", "this commit", "some changes", "misc changes"},
+			MaxAttempts:    3,
+			Candidates:     1,
+			Cleaning:       DefaultCleaningConfig(),
+			Stream:         true,
+		},
+		Diff:    DiffConfig{ContextLines: true, Format: "annotated", MinMoved: 6, MaxBytes: 4 << 20},
+		Cache:   CacheConfig{Enabled: true, Backend: "local"},
+		History: HistoryConfig{Enabled: true},
+		Privacy: PrivacyConfig{
+			RedactSecrets:     true,
+			DetectHighEntropy: true,
+			AllowRemote:       true,
+			ConfirmRemote:     true,
 		},
+		Prompt: PromptConfig{
+			Readme: ReadmeConfig{
+				Enabled:  true,
+				MaxLines: 40,
+				Sections: []string{"About", "Overview", "Architecture"},
+			},
+			ContextFileLines: 100,
+			Conventions:      true,
+			LearnScopes:      true,
+		},
+		Linear:  LinearConfig{MagicWord: "Refs"},
+		Standup: StandupConfig{Days: 1},
+		Watch:   WatchConfig{Settle: 3 * time.Second},
 	}
 
 	// Try to load config file
@@ -77,23 +430,201 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse YAML
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Parse YAML, rejecting keys that aren't options, which are most likely typos
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, explainParseError(err))
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err == nil {
+		cfg.Warnings = deprecationWarnings(&root, "")
+	}
+
+	// Profiles retry as the provider does unless they say otherwise, which may
+	// be max_retries: 0
+	for name, profile := range cfg.Profiles {
+		if !hasKey(&root, "profiles", name, "max_retries") {
+			profile.MaxRetries = cfg.Provider.MaxRetries
+			cfg.Profiles[name] = profile
+		}
+	}
+
+	// Ollama sections without a host use the same one as the ollama CLI
+	if cfg.Provider.Ollama != nil && cfg.Provider.Ollama.Host == "" {
+		cfg.Provider.Ollama.Host = defaultOllamaHost()
+	}
+	for _, profile := range cfg.Profiles {
+		if profile.Ollama != nil && profile.Ollama.Host == "" {
+			profile.Ollama.Host = defaultOllamaHost()
+		}
 	}
 
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	cfg.applyContextWindow()
 
 	return cfg, nil
 }
 
+func defaultOllamaConfig() *OllamaConfig {
+	return &OllamaConfig{
+		Host:  defaultOllamaHost(),
+		Model: "llama2",
+	}
+}
+
+// defaultOllamaHost returns the Ollama server the ollama CLI would use: the
+// one in $OLLAMA_HOST, or http://localhost:11434. As with the CLI, OLLAMA_HOST
+// may leave out the scheme (http) and the port (11434, or 80 or 443 with an
+// explicit http:// or https://), as in OLLAMA_HOST=gpu-box:11434.
+func defaultOllamaHost() string {
+	env := strings.Trim(strings.TrimSpace(os.Getenv("OLLAMA_HOST")), `"'`)
+	if env == "" {
+		return "http://localhost:11434"
+	}
+
+	port := "11434"
+	scheme, hostport, ok := strings.Cut(env, "://")
+	switch {
+	case !ok:
+		scheme, hostport = "http", env
+	case scheme == "http":
+		port = "80"
+	case scheme == "https":
+		port = "443"
+	}
+	hostport, path, _ := strings.Cut(hostport, "/")
+	host, p, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// No port; an IPv6 address may still be in brackets
+		host = strings.Trim(hostport, "[]")
+	} else if n, err := strconv.Atoi(p); err == nil && n > 0 && n <= 65535 {
+		port = p
+	}
+	u := url.URL{Scheme: scheme, Host: net.JoinHostPort(cmp.Or(host, "127.0.0.1"), port)}
+	if path != "" {
+		u.Path = "/" + path
+	}
+	return u.String()
+}
+
+// SelectProvider switches to the named profile, or to the named provider type
+func (c *Config) SelectProvider(name string) error {
+	if profile, ok := c.Profiles[name]; ok {
+		if profile.Timeout == 0 {
+			profile.Timeout = c.Provider.Timeout
+		}
+		if profile.OnFailure == "" {
+			profile.OnFailure = c.Provider.OnFailure
+		}
+		c.Provider = profile
+	} else {
+		switch name {
+		case "ollama":
+			if c.Provider.Ollama == nil {
+				c.Provider.Ollama = defaultOllamaConfig()
+			}
+		case "openai", "gateway":
+		default:
+			return fmt.Errorf("unknown provider or profile '%s'", name)
+		}
+		c.Provider.Type = name
+	}
+
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	c.applyContextWindow()
+	return nil
+}
+
+// applyContextWindow lowers diff_token_limit and large_diff_threshold to what
+// fits in the selected provider's context window. The configured values are
+// kept, so selecting another provider fits them to its window instead.
+func (c *Config) applyContextWindow() {
+	if c.diffTokenLimit == 0 {
+		c.diffTokenLimit, c.largeDiffThreshold = c.Commit.DiffTokenLimit, c.Commit.LargeDiffThreshold
+	}
+	c.Commit.DiffTokenLimit, c.Commit.LargeDiffThreshold = c.diffTokenLimit, c.largeDiffThreshold
+	if budget := c.Provider.DiffBudget(); budget > 0 && budget < c.Commit.DiffTokenLimit {
+		c.Commit.DiffTokenLimit = budget
+		c.Commit.LargeDiffThreshold = min(c.Commit.LargeDiffThreshold, budget)
+	}
+}
+
+// DefaultNumCtx is the num_ctx Ollama is asked for when the context window
+// isn't configured
+const DefaultNumCtx = 4096
+
+// DefaultMaxTokens is the most tokens a response may have, as long as the
+// context window has room for it
+const DefaultMaxTokens = 4096
+
+// NumCtx returns the context window to ask Ollama for
+func (c ProviderConfig) NumCtx() int {
+	return cmp.Or(c.ContextWindow, DefaultNumCtx)
+}
+
+// MaxResponseTokens returns the most tokens to let a response have
+func (c ProviderConfig) MaxResponseTokens() int {
+	if c.ContextWindow == 0 {
+		return DefaultMaxTokens
+	}
+	return min(DefaultMaxTokens, c.contextReserve())
+}
+
+// DiffBudget returns how many tokens of diff fit in the context window next to
+// the instructions, project context, and response, or 0 if the context window
+// isn't configured
+func (c ProviderConfig) DiffBudget() int {
+	if c.ContextWindow == 0 {
+		return 0
+	}
+	return c.ContextWindow - c.contextReserve()
+}
+
+// contextReserve is the part of the context window kept for everything but the
+// diff: a quarter of it, from 1024 to 8192 tokens
+func (c ProviderConfig) contextReserve() int {
+	return min(max(c.ContextWindow/4, 1024), 8192)
+}
+
+// Model returns the model used by the selected provider
+func (c *Config) Model() string {
+	switch c.Provider.Type {
+	case "ollama":
+		if c.Provider.Ollama != nil {
+			return c.Provider.Ollama.Model
+		}
+	case "openai":
+		if c.Provider.OpenAI != nil {
+			return c.Provider.OpenAI.Model
+		}
+	}
+	return ""
+}
+
+// SetModel overrides the model of the configured provider
+func (c *Config) SetModel(model string) {
+	switch c.Provider.Type {
+	case "ollama":
+		if c.Provider.Ollama != nil {
+			c.Provider.Ollama.Model = model
+		}
+	case "openai":
+		if c.Provider.OpenAI != nil {
+			c.Provider.OpenAI.Model = model
+		}
+	}
+}
+
 func (c *Config) Validate() error {
 	// Validate provider type
 	if c.Provider.Type == "" {
-		return fmt.Errorf("provider type is required (supported: ollama, openai)")
+		return fmt.Errorf("provider type is required (supported: %s)", strings.Join(ProviderTypes, ", "))
 	}
 
 	// Validate timeout
@@ -103,20 +634,113 @@ func (c *Config) Validate() error {
 	if c.Provider.Timeout > 10*time.Minute {
 		return fmt.Errorf("provider timeout is too large (got %v, maximum 10m)", c.Provider.Timeout)
 	}
+	if c.Provider.MaxRetries < 0 || c.Provider.MaxRetries > 10 {
+		return fmt.Errorf("provider.max_retries must be between 0 and 10 (got %d)", c.Provider.MaxRetries)
+	}
+	switch c.Provider.OnFailure {
+	case "abort", "edit", "template":
+	default:
+		return fmt.Errorf("provider.on_failure must be abort, edit, or template (got %q)", c.Provider.OnFailure)
+	}
+	if c.Provider.ContextWindow < 0 {
+		return fmt.Errorf("provider.context_window must not be negative (got %d)", c.Provider.ContextWindow)
+	}
+	if c.Provider.ContextWindow > 0 && c.Provider.ContextWindow < 2048 {
+		return fmt.Errorf("provider.context_window is too small (got %d, minimum 2048, or 0 if unknown)", c.Provider.ContextWindow)
+	}
 
 	// Validate commit config
 	if err := c.validateCommitConfig(); err != nil {
 		return fmt.Errorf("commit config validation failed: %w", err)
 	}
 
+	// Validate diff config
+	switch c.Diff.Format {
+	case "annotated", "unified":
+	default:
+		return fmt.Errorf("diff.format must be annotated or unified (got %q)", c.Diff.Format)
+	}
+	if c.Diff.MinMoved < 0 {
+		return fmt.Errorf("diff.min_moved_lines must not be negative (got %d)", c.Diff.MinMoved)
+	}
+	if c.Diff.MaxBytes < 0 {
+		return fmt.Errorf("diff.max_bytes must not be negative (got %d)", c.Diff.MaxBytes)
+	}
+
+	// Validate privacy config
+	for _, p := range c.Privacy.SecretPatterns {
+		if p.Name == "" {
+			return fmt.Errorf("secret_patterns entries must have a name")
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid secret pattern '%s': %w", p.Name, err)
+		}
+	}
+	for _, glob := range c.Privacy.RedactPaths {
+		if strings.TrimPrefix(glob, "/") == "" {
+			return fmt.Errorf("redact_paths must not contain empty entries")
+		}
+	}
+
+	// Validate prompt config
+	if c.Prompt.Readme.MaxLines < 0 {
+		return fmt.Errorf("prompt.readme.max_lines must not be negative (got %d)", c.Prompt.Readme.MaxLines)
+	}
+	if c.Prompt.ContextFileLines < 0 {
+		return fmt.Errorf("prompt.context_file_lines must not be negative (got %d)", c.Prompt.ContextFileLines)
+	}
+
+	// Validate cache config
+	switch c.Cache.Backend {
+	case "local":
+	case "redis":
+		if !strings.HasPrefix(c.Cache.Redis.URL, "redis://") && !strings.HasPrefix(c.Cache.Redis.URL, "rediss://") {
+			return fmt.Errorf("cache.redis.url must start with redis:// or rediss:// (got %q)", c.Cache.Redis.URL)
+		}
+		if c.Cache.Redis.TTL < 0 {
+			return fmt.Errorf("cache.redis.ttl must not be negative (got %v)", c.Cache.Redis.TTL)
+		}
+	case "s3":
+		if !strings.HasPrefix(c.Cache.S3.Endpoint, "http://") && !strings.HasPrefix(c.Cache.S3.Endpoint, "https://") {
+			return fmt.Errorf("cache.s3.endpoint must be a URL starting with http:// or https:// (got %q)", c.Cache.S3.Endpoint)
+		}
+		if c.Cache.S3.Bucket == "" {
+			return fmt.Errorf("cache.s3.bucket is required")
+		}
+	default:
+		return fmt.Errorf("cache.backend must be local, redis, or s3 (got %q)", c.Cache.Backend)
+	}
+
+	// Validate audit config
+	if c.Audit.Webhook != "" && !strings.HasPrefix(c.Audit.Webhook, "http://") && !strings.HasPrefix(c.Audit.Webhook, "https://") {
+		return fmt.Errorf("audit.webhook must be a URL starting with http:// or https:// (got %q)", c.Audit.Webhook)
+	}
+
+	// Validate standup config
+	if c.Standup.Days < 1 {
+		return fmt.Errorf("standup.days must be at least 1 (got %d)", c.Standup.Days)
+	}
+
+	// Validate watch config
+	if c.Watch.Settle <= 0 {
+		return fmt.Errorf("watch.settle must be positive (got %v)", c.Watch.Settle)
+	}
+
+	// Validate Linear config
+	if c.Linear.MagicWord != "none" && !slices.Contains(linearMagicWords, strings.ToLower(c.Linear.MagicWord)) {
+		return fmt.Errorf("linear.magic_word must be one Linear recognizes, such as Fixes, Closes, or Refs, or none (got %q)", c.Linear.MagicWord)
+	}
+
 	// Validate provider-specific config
 	switch c.Provider.Type {
 	case "ollama":
 		return c.validateOllamaConfig()
 	case "openai":
 		return c.validateOpenAIConfig()
+	case "gateway":
+		return c.validateGatewayConfig()
 	default:
-		return fmt.Errorf("unsupported provider type '%s' (supported: ollama, openai)", c.Provider.Type)
+		return fmt.Errorf("unsupported provider type '%s' (supported: %s)", c.Provider.Type, strings.Join(ProviderTypes, ", "))
 	}
 }
 
@@ -139,9 +763,107 @@ func (c *Config) validateCommitConfig() error {
 	if c.Commit.DiffTokenLimit > 100000 {
 		return fmt.Errorf("diff_token_limit is too large (got %d, maximum 100000)", c.Commit.DiffTokenLimit)
 	}
+	if c.Commit.LargeDiffThreshold < 0 {
+		return fmt.Errorf("large_diff_threshold must not be negative (got %d)", c.Commit.LargeDiffThreshold)
+	}
+	if c.Commit.LargeDiffThreshold > c.Commit.DiffTokenLimit {
+		return fmt.Errorf("large_diff_threshold must not exceed diff_token_limit (got %d, limit %d)", c.Commit.LargeDiffThreshold, c.Commit.DiffTokenLimit)
+	}
+	switch c.Commit.TwoStage {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("two_stage must be auto, always, or never (got %q)", c.Commit.TwoStage)
+	}
+	if c.Commit.BodyWidth < 0 {
+		return fmt.Errorf("body_width must not be negative (got %d)", c.Commit.BodyWidth)
+	}
+	if c.Commit.BodyWidth > 0 && c.Commit.BodyWidth < 40 {
+		return fmt.Errorf("body_width is too small (got %d, minimum 40, or 0 to disable wrapping)", c.Commit.BodyWidth)
+	}
+	if c.Commit.Candidates < 1 {
+		return fmt.Errorf("candidates must be at least 1 (got %d)", c.Commit.Candidates)
+	}
+	if c.Commit.Candidates > 9 {
+		return fmt.Errorf("candidates is too large (got %d, maximum 9)", c.Commit.Candidates)
+	}
+	if c.Commit.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1 (got %d)", c.Commit.MaxAttempts)
+	}
+	if c.Commit.MaxAttempts > 10 {
+		return fmt.Errorf("max_attempts is too large (got %d, maximum 10)", c.Commit.MaxAttempts)
+	}
+	for _, f := range c.Commit.Filters {
+		if strings.TrimSpace(f) == "" {
+			return fmt.Errorf("filters must not contain empty commands")
+		}
+	}
+	if len(c.Commit.Types) == 0 {
+		return fmt.Errorf("types must list at least one commit type")
+	}
+	for _, t := range c.Commit.Types {
+		if t.Name == "" || strings.Trim(t.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("commit type %q must consist of letters only", t.Name)
+		}
+	}
+	switch c.Commit.BodyStyle {
+	case "bullets", "paragraph", "none":
+	default:
+		return fmt.Errorf("body_style must be bullets, paragraph, or none (got %q)", c.Commit.BodyStyle)
+	}
+	if c.Commit.MaxBodyLines < 0 {
+		return fmt.Errorf("max_body_lines must not be negative (got %d)", c.Commit.MaxBodyLines)
+	}
+	if c.Commit.MaxTotalChars < 0 {
+		return fmt.Errorf("max_total_chars must not be negative (got %d)", c.Commit.MaxTotalChars)
+	}
+	if c.Commit.MaxTotalChars > 0 && c.Commit.MaxTotalChars < c.Commit.MaxLength {
+		return fmt.Errorf("max_total_chars must be at least max_length (got %d, max_length %d)", c.Commit.MaxTotalChars, c.Commit.MaxLength)
+	}
+	switch c.Commit.Mood {
+	case "imperative", "present", "past":
+	default:
+		return fmt.Errorf("mood must be imperative, present, or past (got %q)", c.Commit.Mood)
+	}
+	switch c.Commit.Tone {
+	case "terse", "descriptive":
+	default:
+		return fmt.Errorf("tone must be terse or descriptive (got %q)", c.Commit.Tone)
+	}
+	switch c.Commit.IncludeBody {
+	case "true", "false", "auto":
+	default:
+		return fmt.Errorf("include_body must be true, false, or auto (got %q)", c.Commit.IncludeBody)
+	}
+	for _, scope := range c.Commit.Scopes {
+		if scope == "" {
+			return fmt.Errorf("scopes must not contain empty entries")
+		}
+		if err := ValidateScope(scope); err != nil {
+			return err
+		}
+	}
+	if c.Commit.Scope != "" {
+		if err := c.Commit.CheckScope(c.Commit.Scope); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ValidateScope checks that scope can be used inside a conventional commit header
+func ValidateScope(scope string) error {
+	if strings.ContainsAny(scope, " \t():!") {
+		return fmt.Errorf("scope %q must not contain whitespace, parentheses, colons, or '!'", scope)
+	}
+	return nil
+}
+
+// isUnixSocketURL reports whether s names a unix socket by absolute path, as
+// in unix:///path/to/socket
+func isUnixSocketURL(s string) bool {
+	return strings.HasPrefix(s, "unix:///")
+}
+
 func (c *Config) validateOllamaConfig() error {
 	if c.Provider.Ollama == nil {
 		return fmt.Errorf("ollama config section is required when provider type is 'ollama'")
@@ -153,8 +875,8 @@ func (c *Config) validateOllamaConfig() error {
 	}
 
 	// Validate host URL format
-	if !strings.HasPrefix(cfg.Host, "http://") && !strings.HasPrefix(cfg.Host, "https://") {
-		return fmt.Errorf("ollama host must be a valid URL starting with http:// or https:// (got %q)", cfg.Host)
+	if !strings.HasPrefix(cfg.Host, "http://") && !strings.HasPrefix(cfg.Host, "https://") && !isUnixSocketURL(cfg.Host) {
+		return fmt.Errorf("ollama host must be a valid URL starting with http:// or https://, or unix:///path/to/socket (got %q)", cfg.Host)
 	}
 
 	if cfg.Model == "" {
@@ -164,6 +886,22 @@ func (c *Config) validateOllamaConfig() error {
 	return nil
 }
 
+func (c *Config) validateGatewayConfig() error {
+	if c.Provider.Gateway == nil {
+		return fmt.Errorf("gateway config section is required when provider type is 'gateway'")
+	}
+
+	cfg := c.Provider.Gateway
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return fmt.Errorf("gateway url must be a valid URL starting with http:// or https:// (got %q)", cfg.URL)
+	}
+	if cfg.Token == "" {
+		return fmt.Errorf("gateway token is required")
+	}
+
+	return nil
+}
+
 func (c *Config) validateOpenAIConfig() error {
 	if c.Provider.OpenAI == nil {
 		return fmt.Errorf("openai config section is required when provider type is 'openai'")
@@ -175,17 +913,33 @@ func (c *Config) validateOpenAIConfig() error {
 	}
 
 	// Validate base URL format
-	if !strings.HasPrefix(cfg.BaseURL, "http://") && !strings.HasPrefix(cfg.BaseURL, "https://") {
-		return fmt.Errorf("openai base_url must be a valid URL starting with http:// or https:// (got %q)", cfg.BaseURL)
+	if !strings.HasPrefix(cfg.BaseURL, "http://") && !strings.HasPrefix(cfg.BaseURL, "https://") && !isUnixSocketURL(cfg.BaseURL) {
+		return fmt.Errorf("openai base_url must be a valid URL starting with http:// or https://, or unix:///path/to/socket (got %q)", cfg.BaseURL)
 	}
 
-	if cfg.APIKey == "" {
-		return fmt.Errorf("openai api_key is required")
+	if cfg.CompletionsPath != "" && !strings.HasPrefix(cfg.CompletionsPath, "/") {
+		return fmt.Errorf("openai completions_path must start with / (got %q)", cfg.CompletionsPath)
 	}
 
-	// Basic API key format validation
-	if len(cfg.APIKey) < 10 {
-		return fmt.Errorf("openai api_key appears to be too short (got %d characters)", len(cfg.APIKey))
+	if cfg.OAuth2 != nil {
+		if !strings.HasPrefix(cfg.OAuth2.TokenURL, "http://") && !strings.HasPrefix(cfg.OAuth2.TokenURL, "https://") {
+			return fmt.Errorf("openai oauth2.token_url must be a URL starting with http:// or https:// (got %q)", cfg.OAuth2.TokenURL)
+		}
+		if cfg.OAuth2.ClientID == "" || cfg.OAuth2.ClientSecret == "" {
+			return fmt.Errorf("openai oauth2.client_id and oauth2.client_secret are required")
+		}
+		if cfg.APIKey != "" {
+			return fmt.Errorf("openai api_key and oauth2 can't both be set - remove api_key to use access tokens from oauth2.token_url")
+		}
+	} else {
+		if cfg.APIKey == "" {
+			return fmt.Errorf("openai api_key is required (or oauth2, to use access tokens)")
+		}
+
+		// Basic API key format validation
+		if len(cfg.APIKey) < 10 {
+			return fmt.Errorf("openai api_key appears to be too short (got %d characters)", len(cfg.APIKey))
+		}
 	}
 
 	if cfg.Model == "" {
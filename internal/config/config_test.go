@@ -0,0 +1,187 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadFile loads a config file with the given contents
+func loadFile(t *testing.T, contents string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	if contents != "" {
+		if err := os.MkdirAll(filepath.Join(dir, "git-ac"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "git-ac", "config.yaml"), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Load()
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := loadFile(t, "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("the defaults are invalid: %v", err)
+	}
+	if cfg.Commit.IncludeBody != "auto" || cfg.Commit.TwoStage != "auto" || !cfg.Diff.ContextLines || !cfg.Cache.Enabled {
+		t.Errorf("Load() defaults = %+v", cfg)
+	}
+	if !cfg.Commit.HasType("feat") || cfg.Commit.HasType("Feat") {
+		t.Errorf("Load() types = %v", cfg.Commit.TypeNames())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"unknown key", "commit:\n  max_lenght: 50\n", `line 2: unknown key "max_lenght" - did you mean "max_length"?`},
+		{"unknown section", "comit:\n  max_length: 50\n", `line 1: unknown key "comit"`},
+		{"max_length too small", "commit:\n  max_length: 10\n", "max_length is too small"},
+		{"bad include_body", "commit:\n  include_body: sometimes\n", "include_body must be true, false, or auto"},
+		{"bad mood", "commit:\n  mood: future\n", "mood must be imperative, present, or past"},
+		{"empty scope", "commit:\n  scopes: [api, \"\"]\n", "scopes must not contain empty entries"},
+		{"scope with a space", "commit:\n  scopes: [\"a b\"]\n", "must not contain whitespace"},
+		{"negative max_retries", "provider:\n  max_retries: -1\n", "provider.max_retries must be between 0 and 10"},
+		{"bad on_failure", "provider:\n  on_failure: retry\n", "provider.on_failure must be abort, edit, or template"},
+		{"small context window", "provider:\n  context_window: 1000\n", "provider.context_window is too small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadFile(t, tt.contents)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	commit := CommitConfig{Scopes: []string{"api", "cli"}}
+	tests := []struct {
+		scope string
+		want  string // Part of the expected error; "" if the scope is allowed
+	}{
+		{"api", ""},
+		{"CLI", ""},
+		{"ui", `scope "ui" is not in commit.scopes (allowed: api, cli)`},
+		{"a:b", "must not contain"},
+	}
+
+	for _, tt := range tests {
+		err := commit.CheckScope(tt.scope)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("CheckScope(%q) = %v, want %q", tt.scope, err, tt.want)
+		}
+	}
+
+	if err := (CommitConfig{}).CheckScope("anything"); err != nil {
+		t.Errorf("CheckScope() without scopes = %v, want nil", err)
+	}
+}
+
+func TestValidateForcedScope(t *testing.T) {
+	cfg, err := loadFile(t, "commit:\n  scopes: [api, cli]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Commit.Scope = "ui"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "not in commit.scopes") {
+		t.Errorf("Validate() with a scope outside commit.scopes = %v", err)
+	}
+}
+
+const profilesConfig = `provider:
+  type: ollama
+  max_retries: 4
+  context_window: 4096
+profiles:
+  fast:
+    type: ollama
+    max_retries: 0
+    ollama: {model: a}
+  precise:
+    type: ollama
+    ollama: {model: b}
+  large:
+    type: ollama
+    context_window: 32768
+    ollama: {model: c}
+`
+
+func TestSelectProvider(t *testing.T) {
+	cfg, err := loadFile(t, profilesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile        string
+		model          string
+		maxRetries     int
+		diffTokenLimit int
+	}{
+		{"fast", "a", 0, 16384},
+		{"precise", "b", 4, 16384},
+		{"large", "c", 4, 32768 - 8192},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			c := *cfg
+			if err := c.SelectProvider(tt.profile); err != nil {
+				t.Fatalf("SelectProvider() error = %v", err)
+			}
+			if c.Model() != tt.model {
+				t.Errorf("model = %q, want %q", c.Model(), tt.model)
+			}
+			if c.Provider.MaxRetries != tt.maxRetries {
+				t.Errorf("max_retries = %d, want %d", c.Provider.MaxRetries, tt.maxRetries)
+			}
+			if c.Commit.DiffTokenLimit != min(tt.diffTokenLimit, 16384) {
+				t.Errorf("diff_token_limit = %d, want %d", c.Commit.DiffTokenLimit, min(tt.diffTokenLimit, 16384))
+			}
+		})
+	}
+
+	// The provider's own window limits the diff until a profile is selected
+	if want := 4096 - 1024; cfg.Commit.DiffTokenLimit != want {
+		t.Errorf("diff_token_limit = %d, want %d", cfg.Commit.DiffTokenLimit, want)
+	}
+
+	c := *cfg
+	if err := c.SelectProvider("remote"); err == nil || !strings.Contains(err.Error(), "unknown provider or profile 'remote'") {
+		t.Errorf("SelectProvider() with an unknown name = %v", err)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		window, numCtx, maxTokens, budget int
+	}{
+		{0, DefaultNumCtx, DefaultMaxTokens, 0},
+		{2048, 2048, 1024, 1024},
+		{8192, 8192, 2048, 6144},
+		{131072, 131072, 4096, 131072 - 8192},
+	}
+
+	for _, tt := range tests {
+		c := ProviderConfig{ContextWindow: tt.window}
+		if c.NumCtx() != tt.numCtx || c.MaxResponseTokens() != tt.maxTokens || c.DiffBudget() != tt.budget {
+			t.Errorf("context_window %d: NumCtx() = %d, MaxResponseTokens() = %d, DiffBudget() = %d, want %d, %d, %d",
+				tt.window, c.NumCtx(), c.MaxResponseTokens(), c.DiffBudget(), tt.numCtx, tt.maxTokens, tt.budget)
+		}
+	}
+}
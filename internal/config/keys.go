@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedKeys maps config keys that have been replaced to what to use
+// instead. Keys are dotted paths, with "*" for a profile name. A deprecated key
+// keeps its field, so files using it still load, and Load applies it to its
+// replacement.
+var deprecatedKeys = map[string]string{}
+
+// deprecationWarnings returns a warning for each deprecated key in the config file
+func deprecationWarnings(node *yaml.Node, path string) []string {
+	if node.Kind == yaml.DocumentNode {
+		var warnings []string
+		for _, child := range node.Content {
+			warnings = append(warnings, deprecationWarnings(child, path)...)
+		}
+		return warnings
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var warnings []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if path == "profiles" {
+			key = "*"
+		}
+		if path != "" {
+			key = path + "." + key
+		}
+		if replacement, ok := deprecatedKeys[key]; ok {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s is deprecated - use %s instead", node.Content[i].Line, key, replacement))
+		}
+		warnings = append(warnings, deprecationWarnings(node.Content[i+1], key)...)
+	}
+	return warnings
+}
+
+// hasKey reports whether the config file sets the key at path
+func hasKey(node *yaml.Node, path ...string) bool {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if len(path) == 0 {
+		return true
+	}
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			return hasKey(node.Content[i+1], path[1:]...)
+		}
+	}
+	return false
+}
+
+// unknownFieldError matches the error yaml.v3 reports for a key with no field
+var unknownFieldError = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
+// explainParseError rewrites the errors for unknown keys to name the key and
+// the known key it is most likely a typo of, if any
+func explainParseError(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	messages := make([]string, len(typeErr.Errors))
+	for i, message := range typeErr.Errors {
+		m := unknownFieldError.FindStringSubmatch(message)
+		if m == nil {
+			messages[i] = message
+			continue
+		}
+		messages[i] = fmt.Sprintf("line %s: unknown key %q", m[1], m[2])
+		if suggestion := closestKey(m[2], keysByType()[m[3]]); suggestion != "" {
+			messages[i] += fmt.Sprintf(" - did you mean %q?", suggestion)
+		}
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// keysByType returns the keys of each struct in the config file, by Go type name
+func keysByType() map[string][]string {
+	keys := map[string][]string{}
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		if _, seen := keys[t.String()]; seen {
+			return
+		}
+		keys[t.String()] = []string{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			keys[t.String()] = append(keys[t.String()], name)
+			visit(f.Type)
+		}
+	}
+	visit(reflect.TypeOf(Config{}))
+	return keys
+}
+
+// closestKey returns the key within two edits of key, or ""
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(key), k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting a
+// swap of adjacent characters as one edit
+func editDistance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range cur {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,76 @@
+package debuglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger appends LLM requests and raw responses to a file, so bad output can be
+// reported and reproduced. A nil *Logger discards everything.
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens path for appending and writes a header for this invocation
+func Open(path string, header string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open debug log: %w", err)
+	}
+
+	l := &Logger{file: file}
+	l.write(fmt.Sprintf("===== %s %s =====\n", time.Now().Format(time.RFC3339), header))
+	return l, nil
+}
+
+// Request records a request body; non-string values are written as indented JSON
+func (l *Logger) Request(label string, request any) {
+	l.entry("REQUEST", label, request)
+}
+
+// Response records a raw response
+func (l *Logger) Response(label string, response any) {
+	l.entry("RESPONSE", label, response)
+}
+
+// Error records a failed request
+func (l *Logger) Error(label string, err error) {
+	l.entry("ERROR", label, err.Error())
+}
+
+// Close closes the log file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func (l *Logger) entry(kind, label string, v any) {
+	if l == nil {
+		return
+	}
+
+	text, ok := v.(string)
+	if !ok {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			text = fmt.Sprintf("%+v", v)
+		} else {
+			text = string(data)
+		}
+	}
+
+	l.write(fmt.Sprintf("--- %s %s (%s)\n%s\n\n", kind, label, time.Now().Format("15:04:05.000"), text))
+}
+
+func (l *Logger) write(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Debug logging must never break commit message generation
+	_, _ = l.file.WriteString(s)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package editor
+
+import "os/exec"
+
+// command returns the command that runs the editor name with args
+func command(name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
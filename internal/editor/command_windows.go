@@ -0,0 +1,40 @@
+//go:build windows
+
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// command returns the command that runs the editor name with args. Batch
+// files, such as the code.cmd that VS Code puts on the PATH, are run through
+// cmd.exe, which is what actually runs them, with each argument quoted so
+// paths with spaces survive.
+func command(name string, args ...string) *exec.Cmd {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return exec.Command(name, args...)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cmd", ".bat":
+	default:
+		return exec.Command(path, args...)
+	}
+
+	shell := os.Getenv("ComSpec")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	line := syscall.EscapeArg(path)
+	for _, arg := range args {
+		line += " " + syscall.EscapeArg(arg)
+	}
+	cmd := exec.Command(shell)
+	// /s keeps cmd.exe from mangling the quotes inside the outer pair
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: syscall.EscapeArg(shell) + ` /d /s /c "` + line + `"`}
+	return cmd
+}
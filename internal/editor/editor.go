@@ -4,43 +4,59 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"unicode"
+
+	"git-ac/internal/git"
+	"git-ac/internal/tempfile"
 )
 
-func Edit(initialContent string) (string, error) {
+// scissors marks where the diff added for commit.verbose starts. As in git,
+// everything from it on is ignored.
+const scissors = "------------------------ >8 ------------------------"
+
+// Edit opens initialContent in the user's editor and returns the edited message.
+// comment is appended as comment lines, starting with core.commentChar ('#' by
+// default), and with commit.verbose set, the staged diff follows. Like git,
+// comment lines and the diff are removed after editing. An empty result means
+// the user aborted, and is returned as "" with a nil error.
+func Edit(initialContent, comment string) (string, error) {
 	editor := getEditor()
 	if editor == "" {
-		return "", fmt.Errorf("no editor found - set $EDITOR environment variable")
+		return "", fmt.Errorf("no editor found - set core.editor in git config or the $EDITOR environment variable")
 	}
 
-	// Create temporary file with initial content
-	tmpFile, err := os.CreateTemp("", "git-ac-edit-*.txt")
+	path, cleanup, err := messageFile()
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary file: %w", err)
+		return "", err
 	}
-	defer func() {
-		_ = os.Remove(tmpFile.Name())
-	}()
+	defer cleanup()
 
-	// Write initial content to file
-	if _, err := tmpFile.WriteString(initialContent); err != nil {
-		_ = tmpFile.Close()
-		return "", fmt.Errorf("failed to write initial content: %w", err)
+	// Write initial content to file, followed by the comment block
+	char := commentChar(initialContent)
+	content := initialContent + "\n"
+	if comment != "" {
+		content += "\n" + commentLines(comment, char)
 	}
-
-	if err := tmpFile.Close(); err != nil {
-		return "", fmt.Errorf("failed to close temporary file: %w", err)
+	if diff := verboseDiff(); diff != "" {
+		content += char + " " + scissors + "\n"
+		content += commentLines("Do not modify or remove the line above.\nEverything below it will be ignored.", char)
+		content += diff
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write initial content: %w", err)
 	}
 
 	// Parse editor command and arguments
-	editorParts := strings.Fields(editor)
+	editorParts := splitCommand(editor)
 	if len(editorParts) == 0 {
 		return "", fmt.Errorf("empty editor command")
 	}
 
-	// Build command with arguments and add the temp file at the end
-	args := append(editorParts[1:], tmpFile.Name())
-	cmd := exec.Command(editorParts[0], args...)
+	// Build command with arguments and add the message file at the end
+	args := append(editorParts[1:], path)
+	cmd := command(editorParts[0], args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -50,30 +66,173 @@ func Edit(initialContent string) (string, error) {
 	}
 
 	// Read the edited content
-	editedContent, err := os.ReadFile(tmpFile.Name())
+	editedContent, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read edited content: %w", err)
 	}
 
-	result := strings.TrimSpace(string(editedContent))
-	if result == "" {
-		return "", fmt.Errorf("commit message cannot be empty")
+	// Editors on Windows may save with CRLF line endings and a byte order mark
+	edited := strings.TrimPrefix(strings.ReplaceAll(string(editedContent), "\r\n", "\n"), "\ufeff")
+	if i := strings.Index(edited, "\n"+char+" "+scissors+"\n"); i >= 0 {
+		edited = edited[:i]
 	}
+	return stripComments(edited, char), nil
+}
+
+// messageFile returns the file to edit the message in: the repository's
+// COMMIT_EDITMSG, as git commit uses, so editors recognize it as a commit
+// message and highlight it, show rulers, and warn about long lines. Outside a
+// repository, it is a temporary file with the same name, which cleanup removes.
+func messageFile() (string, func(), error) {
+	if path, err := (git.Repo{}).GetGitPath("COMMIT_EDITMSG"); err == nil {
+		return path, func() {}, nil
+	}
+
+	dir, err := tempfile.Dir("git-ac-edit-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return filepath.Join(dir, "COMMIT_EDITMSG"), func() { tempfile.Remove(dir) }, nil
+}
 
-	return result, nil
+// splitCommand splits an editor command into arguments on whitespace, keeping
+// text in single or double quotes together, so a path with spaces, such as
+// "C:\Program Files\Notepad++\notepad++.exe", can be quoted. Backslashes are
+// ordinary characters, since they separate directories on Windows.
+func splitCommand(command string) []string {
+	var parts []string
+	var part strings.Builder
+	inPart := false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				part.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inPart = true
+		case unicode.IsSpace(r):
+			if inPart {
+				parts = append(parts, part.String())
+				part.Reset()
+				inPart = false
+			}
+		default:
+			part.WriteRune(r)
+			inPart = true
+		}
+	}
+	if inPart {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// commentLines prefixes each line of text with char
+func commentLines(text, char string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") {
+			b.WriteString(char + line + "\n")
+		} else {
+			b.WriteString(char + " " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// autoCommentChars are the characters core.commentChar=auto chooses from
+const autoCommentChars = "#;@!$%^&|:"
+
+// commentChar returns what comment lines start with, from core.commentChar.
+// With "auto", it is the first character that no line of message starts with.
+func commentChar(message string) string {
+	char := git.Repo{}.GetConfig("core.commentChar")
+	switch char {
+	case "":
+		return "#"
+	case "auto":
+		for _, c := range strings.Split(autoCommentChars, "") {
+			if !startsLine(message, c) {
+				return c
+			}
+		}
+		return "#"
+	}
+	return char
 }
 
+// startsLine reports whether any line of text starts with prefix
+func startsLine(text, prefix string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// verboseDiff returns the staged diff to show below the message when
+// commit.verbose is set, as git commit does, or ""
+func verboseDiff() string {
+	switch strings.ToLower(git.Repo{}.GetConfig("commit.verbose")) {
+	case "", "false", "no", "off", "0":
+		return ""
+	}
+	diff, err := git.Repo{}.GetStagedDiff(git.DiffOptions{Unified: true})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// StripComments removes comment lines, starting with core.commentChar ('#'
+// by default), and surrounding whitespace, like git's default cleanup
+func StripComments(text string) string {
+	char := git.Repo{}.GetConfig("core.commentChar")
+	if char == "" || char == "auto" {
+		// The character git chose for auto isn't recorded, but '#' is its first choice
+		char = "#"
+	}
+	return stripComments(text, char)
+}
+
+// stripComments removes lines starting with char, and surrounding whitespace
+func stripComments(text, char string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, char) {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// getEditor resolves the editor the same way git does:
+// GIT_EDITOR, core.editor, VISUAL, then EDITOR
 func getEditor() string {
-	// Check EDITOR environment variable first
-	if editor := os.Getenv("EDITOR"); editor != "" {
+	if editor := os.Getenv("GIT_EDITOR"); editor != "" {
+		return editor
+	}
+
+	if editor := (git.Repo{}).GetConfig("core.editor"); editor != "" {
 		return editor
 	}
 
-	// Check VISUAL as fallback
-	if visual := os.Getenv("VISUAL"); visual != "" {
+	// Like git, only use VISUAL on terminals capable of full-screen editing
+	if visual := os.Getenv("VISUAL"); visual != "" && os.Getenv("TERM") != "dumb" {
 		return visual
 	}
 
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+
 	// Try common editors as last resort
 	editors := []string{"nano", "vim", "vi", "emacs"}
 	for _, editor := range editors {
@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommentLines(t *testing.T) {
+	got := commentLines("Please enter the commit message.\n\n\tmain.go\n", "#")
+	want := "# Please enter the commit message.\n#\n#\tmain.go\n"
+	if got != want {
+		t.Errorf("commentLines() = %q, want %q", got, want)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no comments", "feat: add token validation\n\nChecks the signature.", "feat: add token validation\n\nChecks the signature."},
+		{"comments removed", "feat: add token validation\n# Please enter the commit message.\n#\n", "feat: add token validation"},
+		{"surrounding whitespace removed", "\n\nfeat: add token validation  \n\n", "feat: add token validation"},
+		{"only comments", "# Please enter the commit message.\n", ""},
+		{"comment character within a line kept", "fix: handle #42\n", "fix: handle #42"},
+		{"CRLF line endings", "feat: add token validation\r\n\r\nChecks the signature.\r\n# Please enter the commit message.\r\n", "feat: add token validation\n\nChecks the signature."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripComments(tt.text, "#"); got != tt.want {
+				t.Errorf("stripComments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEditor(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		gitEditor, coreEditor, visual, editor string
+		term                                  string
+		want                                  string
+	}{
+		{"GIT_EDITOR first", "ge", "ce", "vis", "ed", "xterm", "ge"},
+		{"core.editor before VISUAL", "", "ce", "vis", "ed", "xterm", "ce"},
+		{"VISUAL before EDITOR", "", "", "vis", "ed", "xterm", "vis"},
+		{"VISUAL ignored on dumb terminals", "", "", "vis", "ed", "dumb", "ed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_EDITOR", tt.gitEditor)
+			t.Setenv("GIT_CONFIG_COUNT", "1")
+			t.Setenv("GIT_CONFIG_KEY_0", "core.editor")
+			t.Setenv("GIT_CONFIG_VALUE_0", tt.coreEditor)
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			t.Setenv("TERM", tt.term)
+			if got := getEditor(); got != tt.want {
+				t.Errorf("getEditor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentChar(t *testing.T) {
+	tests := []struct {
+		config  string
+		message string
+		want    string
+	}{
+		{"", "feat: add token validation", "#"},
+		{";", "feat: add token validation", ";"},
+		{"auto", "feat: add token validation", "#"},
+		{"auto", "feat: add token validation\n\n#42 is fixed", ";"},
+		{"auto", "feat: x\n#\n;\n@\n!\n$\n%\n^\n&\n|\n:", "#"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("GIT_CONFIG_COUNT", "1")
+		t.Setenv("GIT_CONFIG_KEY_0", "core.commentChar")
+		t.Setenv("GIT_CONFIG_VALUE_0", tt.config)
+		if got := commentChar(tt.message); got != tt.want {
+			t.Errorf("commentChar(%q) with core.commentChar=%q = %q, want %q", tt.message, tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestStripCommentsCommentChar(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "core.commentChar")
+	t.Setenv("GIT_CONFIG_VALUE_0", ";")
+	if got := StripComments("fix: handle input\n\n#42 is fixed\n; Please enter the commit message."); got != "fix: handle input\n\n#42 is fixed" {
+		t.Errorf("StripComments() = %q", got)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"vim", []string{"vim"}},
+		{"code --wait", []string{"code", "--wait"}},
+		{"  nano   -w  ", []string{"nano", "-w"}},
+		{`"C:\Program Files\Notepad++\notepad++.exe" -multiInst -nosession`, []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst", "-nosession"}},
+		{`'/Applications/Sublime Text.app/Contents/SharedSupport/bin/subl' -w`, []string{"/Applications/Sublime Text.app/Contents/SharedSupport/bin/subl", "-w"}},
+		{`emacs --eval "(setq x 1)"`, []string{"emacs", "--eval", "(setq x 1)"}},
+		{`edit ""`, []string{"edit", ""}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitCommand(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
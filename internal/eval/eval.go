@@ -0,0 +1,104 @@
+package eval
+
+import (
+	"strings"
+	"unicode"
+
+	"git-ac/internal/llm"
+)
+
+// Case is a past commit whose message was regenerated
+type Case struct {
+	Commit    string // Abbreviated hash
+	Actual    string // The commit's real message
+	Generated string // The generated message; "" if generation failed
+	Err       error  // Why generation failed
+}
+
+// Score compares a generated message with the real one
+type Score struct {
+	HasType    bool    // The real subject has a conventional commit type to compare against
+	TypeMatch  bool    // The generated type is the real one
+	Similarity float64 // Overlap of the subjects' words, from 0 to 1
+}
+
+// Compare scores a generated message against the real one. Similarity is the
+// Dice coefficient of the words in the subjects, not counting type and scope.
+func Compare(generated, actual string) Score {
+	genSubject, _, _ := llm.SplitMessage(generated)
+	actualSubject, _, _ := llm.SplitMessage(actual)
+
+	var score Score
+	genHeader, genOK := llm.ParseHeader(genSubject)
+	actualHeader, actualOK := llm.ParseHeader(actualSubject)
+	if genOK {
+		genSubject = genHeader.Subject
+	}
+	if actualOK {
+		actualSubject = actualHeader.Subject
+		score.HasType = true
+		score.TypeMatch = genOK && strings.EqualFold(genHeader.Type, actualHeader.Type)
+	}
+
+	score.Similarity = dice(words(genSubject), words(actualSubject))
+	return score
+}
+
+// words returns the lowercase words in s
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// dice returns the Dice coefficient of two multisets of words
+func dice(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	counts := make(map[string]int)
+	for _, w := range a {
+		counts[w]++
+	}
+	shared := 0
+	for _, w := range b {
+		if counts[w] > 0 {
+			counts[w]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+// Report summarizes the scores of a set of cases
+type Report struct {
+	Cases          int
+	Failed         int     // Cases where generation failed
+	Typed          int     // Successful cases whose real subject has a type
+	TypeMatches    int     // Typed cases whose generated type matched
+	MeanSimilarity float64 // Over successful cases
+}
+
+// Summarize scores each case and totals the results
+func Summarize(cases []Case) Report {
+	report := Report{Cases: len(cases)}
+	var totalSimilarity float64
+	for _, c := range cases {
+		if c.Err != nil {
+			report.Failed++
+			continue
+		}
+		score := Compare(c.Generated, c.Actual)
+		totalSimilarity += score.Similarity
+		if score.HasType {
+			report.Typed++
+			if score.TypeMatch {
+				report.TypeMatches++
+			}
+		}
+	}
+	if generated := report.Cases - report.Failed; generated > 0 {
+		report.MeanSimilarity = totalSimilarity / float64(generated)
+	}
+	return report
+}
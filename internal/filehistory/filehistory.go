@@ -0,0 +1,132 @@
+package filehistory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"git-ac/internal/cache"
+	"git-ac/internal/git"
+	"git-ac/internal/llm"
+)
+
+// cacheNamespace is the cache namespace for summaries of stretches of history
+const cacheNamespace = "history"
+
+// TextGenerator runs free-form prompts, as providers do
+type TextGenerator interface {
+	GenerateText(ctx context.Context, prompt llm.Prompt) (string, error)
+}
+
+// Options controls how a file's history is narrated
+type Options struct {
+	TokenLimit int          // Approximate limit on the history sent in one prompt
+	Model      string       // Part of the cache key, as summaries differ by model
+	Cache      *cache.Cache // Summaries of stretches of history; may be nil
+	Project    llm.ProjectContext
+
+	// Progress is called before each stretch of history that isn't cached is
+	// summarized; may be nil
+	Progress func(chunk, chunks int)
+}
+
+// Narrate tells how and why the file at path evolved over commits, which are
+// newest first as git log lists them. A history that doesn't fit in one
+// prompt is summarized in stretches first, like files in two-stage mode, and
+// the stretches' summaries are cached by their commits.
+func Narrate(ctx context.Context, gen TextGenerator, path string, commits []git.LogCommit, opts Options) (string, error) {
+	commits = slices.Clone(commits)
+	slices.Reverse(commits)
+
+	chunks := chunk(commits, opts.TokenLimit)
+	if len(chunks) == 1 {
+		return gen.GenerateText(ctx, llm.BuildHistoryNarrativePrompt(path, format(chunks[0], opts.TokenLimit), false, opts.Project))
+	}
+
+	var summaries strings.Builder
+	for i, c := range chunks {
+		summary, err := summarize(ctx, gen, path, c, i+1, len(chunks), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize commits %s to %s: %w", shortHash(c[0]), shortHash(c[len(c)-1]), err)
+		}
+		fmt.Fprintf(&summaries, "Commits %s (%s) to %s (%s):\n%s\n\n", shortHash(c[0]), c[0].Date, shortHash(c[len(c)-1]), c[len(c)-1].Date, summary)
+	}
+
+	return gen.GenerateText(ctx, llm.BuildHistoryNarrativePrompt(path, strings.TrimSpace(summaries.String()), true, opts.Project))
+}
+
+func summarize(ctx context.Context, gen TextGenerator, path string, commits []git.LogCommit, chunk, chunks int, opts Options) (string, error) {
+	key := ""
+	if opts.Cache != nil {
+		parts := []string{path, opts.Model}
+		for _, c := range commits {
+			parts = append(parts, c.Hash)
+		}
+		key = cache.Key(parts...)
+		if summary, ok := opts.Cache.Get(cacheNamespace, key); ok {
+			return summary, nil
+		}
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(chunk, chunks)
+	}
+	summary, err := gen.GenerateText(ctx, llm.BuildHistoryChunkPrompt(path, format(commits, opts.TokenLimit)))
+	if err != nil {
+		return "", err
+	}
+
+	if key != "" {
+		// A failed cache write only costs a re-summarization next time
+		_ = opts.Cache.Put(cacheNamespace, key, summary)
+	}
+	return summary, nil
+}
+
+// chunk splits commits into stretches that each fit within tokenLimit. A
+// commit too large to fit on its own gets a stretch to itself, and its patch
+// is cut down when formatted.
+func chunk(commits []git.LogCommit, tokenLimit int) [][]git.LogCommit {
+	var chunks [][]git.LogCommit
+	var current []git.LogCommit
+	tokens := 0
+	for _, c := range commits {
+		n := llm.EstimateTokens(formatCommit(c, 0))
+		if len(current) > 0 && tokenLimit > 0 && tokens+n > tokenLimit {
+			chunks = append(chunks, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, c)
+		tokens += n
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+func format(commits []git.LogCommit, tokenLimit int) string {
+	var b strings.Builder
+	for _, c := range commits {
+		b.WriteString(formatCommit(c, tokenLimit) + "\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// formatCommit formats a commit for the prompt, cutting its patch down to fit
+// tokenLimit; 0 means no limit
+func formatCommit(c git.LogCommit, tokenLimit int) string {
+	patch := c.Patch
+	if tokenLimit > 0 {
+		patch, _ = llm.FitDiff(patch, max(tokenLimit-llm.EstimateTokens(c.Message), tokenLimit/2))
+	}
+	return fmt.Sprintf("commit %s (%s, %s)\n%s\n\n%s", shortHash(c), c.Date, c.Author, c.Message, patch)
+}
+
+func shortHash(c git.LogCommit) string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
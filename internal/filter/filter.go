@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// timeout limits how long one filter may run
+const timeout = 30 * time.Second
+
+// Run passes message through each command in turn. A command gets the message
+// on stdin and prints the message to use, possibly changed, on stdout. The
+// commands run in dir and are split into arguments on whitespace.
+func Run(ctx context.Context, message string, commands []string, dir string) (string, error) {
+	for _, command := range commands {
+		var err error
+		if message, err = runOne(ctx, message, command, dir); err != nil {
+			return "", err
+		}
+	}
+	return message, nil
+}
+
+func runOne(ctx context.Context, message, command, dir string) (string, error) {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return "", fmt.Errorf("empty filter command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(message + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("filter %q failed: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("filter %q failed: %w", command, err)
+	}
+
+	filtered := strings.TrimSpace(stdout.String())
+	if filtered == "" {
+		return "", fmt.Errorf("filter %q produced an empty message", command)
+	}
+	return filtered, nil
+}
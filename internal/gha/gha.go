@@ -0,0 +1,139 @@
+package gha
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// zeroSHA is the "before" of a push that created a branch
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Event is the range of commits a GitHub Actions run should describe
+type Event struct {
+	Name        string // GITHUB_EVENT_NAME, e.g. push or pull_request
+	Base        string
+	Head        string
+	MergeBase   bool // Compare from where Head branched off Base
+	PullRequest bool // The run is for a pull request, so it gets a description
+}
+
+// eventPayload is the part of the GITHUB_EVENT_PATH payload that matters here
+type eventPayload struct {
+	Before      string `json:"before"`
+	After       string `json:"after"`
+	PullRequest *struct {
+		Base struct {
+			SHA string `json:"sha"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+// LoadEvent reads the run's event from the environment GitHub Actions sets up.
+// Pull requests are compared against their base branch, and pushes against the
+// previous head; any other event describes the commit that triggered it.
+func LoadEvent() (*Event, error) {
+	event := &Event{Name: os.Getenv("GITHUB_EVENT_NAME")}
+	if event.Name == "" {
+		return nil, fmt.Errorf("GITHUB_EVENT_NAME is not set - --gha only works in GitHub Actions")
+	}
+
+	var payload eventPayload
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event payload: %w", err)
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse event payload: %w", err)
+		}
+	}
+
+	switch {
+	case payload.PullRequest != nil:
+		event.Base = payload.PullRequest.Base.SHA
+		event.Head = payload.PullRequest.Head.SHA
+		event.MergeBase = true
+		event.PullRequest = true
+	case payload.After != "" && payload.Before != "" && payload.Before != zeroSHA:
+		event.Base = payload.Before
+		event.Head = payload.After
+	default:
+		// A new branch, or an event without a range: describe the last commit
+		head := payload.After
+		if head == "" {
+			head = os.Getenv("GITHUB_SHA")
+		}
+		if head == "" {
+			return nil, fmt.Errorf("cannot tell which commits to describe for a %s event", event.Name)
+		}
+		event.Base = head + "^"
+		event.Head = head
+	}
+
+	return event, nil
+}
+
+// SetOutput sets a step output, which may span several lines
+func SetOutput(name, value string) error {
+	delimiter, err := randomDelimiter()
+	if err != nil {
+		return err
+	}
+	return appendFile("GITHUB_OUTPUT", fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter))
+}
+
+// AppendSummary adds Markdown to the job summary
+func AppendSummary(markdown string) error {
+	return appendFile("GITHUB_STEP_SUMMARY", markdown+"\n")
+}
+
+// Error prints an error annotation
+func Error(err error) {
+	fmt.Printf("::error title=git-ac::%s\n", escape(err.Error()))
+}
+
+// Warning prints a warning annotation
+func Warning(message string) {
+	fmt.Printf("::warning title=git-ac::%s\n", escape(message))
+}
+
+// escape encodes the characters that end or corrupt a workflow command
+func escape(text string) string {
+	text = strings.ReplaceAll(text, "%", "%25")
+	text = strings.ReplaceAll(text, "\r", "%0D")
+	return strings.ReplaceAll(text, "\n", "%0A")
+}
+
+// appendFile appends text to the file named by the environment variable env
+func appendFile(env, text string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return fmt.Errorf("%s is not set - --gha only works in GitHub Actions", env)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", env, err)
+	}
+	if _, err := file.WriteString(text); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s: %w", env, err)
+	}
+	return file.Close()
+}
+
+// randomDelimiter returns a heredoc delimiter that won't appear in an output value
+func randomDelimiter() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate output delimiter: %w", err)
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
+}
@@ -1,14 +1,35 @@
 package git
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"git-ac/internal/tempfile"
 )
 
-func ValidateRepository() error {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
+// Repo runs git commands in the repository containing Dir. The zero value uses
+// the repository containing the current directory.
+type Repo struct {
+	Dir string
+}
+
+// command returns a git command that runs in the repository
+func (r Repo) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	return cmd
+}
+
+func (r Repo) ValidateRepository() error {
+	cmd := r.command("rev-parse", "--git-dir")
 	cmd.Stderr = nil
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("not a git repository")
@@ -16,18 +37,173 @@ func ValidateRepository() error {
 	return nil
 }
 
-func GetStagedDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--cached")
-	output, err := cmd.Output()
+// ErrDiffTooLarge is returned for a diff larger than DiffOptions.MaxBytes
+var ErrDiffTooLarge = errors.New("diff is too large")
+
+// DiffOptions controls how the staged diff is produced
+type DiffOptions struct {
+	NoContext bool // Omit unchanged lines around each change (git diff -U0)
+	Unified   bool // Keep git's +/- line markers rather than rewriting them as ADDED:/REMOVED:
+	MaxBytes  int  // Stop reading and fail with ErrDiffTooLarge beyond this many bytes; 0 means no limit
+}
+
+func (r Repo) GetStagedDiff(opts DiffOptions) (string, error) {
+	diff, err := r.diff(opts, "--cached")
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged diff: %w", err)
 	}
+	return diff, nil
+}
+
+// GetRangeDiff returns the changes between two revisions. With mergeBase, the
+// changes are taken from where head branched off base, as in base...head.
+func (r Repo) GetRangeDiff(base, head string, mergeBase bool, opts DiffOptions) (string, error) {
+	rangeSpec := base + ".." + head
+	if mergeBase {
+		rangeSpec = base + "..." + head
+	}
+	diff, err := r.diff(opts, rangeSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff of %s: %w", rangeSpec, err)
+	}
+	return diff, nil
+}
+
+// diff runs git diff with extra arguments, in the format set by opts
+func (r Repo) diff(opts DiffOptions, extra ...string) (string, error) {
+	// Full blob IDs let per-file results be cached by content, and rename
+	// detection keeps moved files from showing up as a deletion and an addition
+	args := []string{"diff", "--full-index", "-M"}
+	if opts.NoContext {
+		args = append(args, "-U0")
+	}
+	args = append(args, extra...)
+
+	cmd := r.command(args...)
+	output, err := outputLimited(cmd, opts.MaxBytes)
+	if err != nil {
+		return "", err
+	}
 
-	// Transform diff format for better LLM readability
 	diff := string(output)
+	if opts.Unified {
+		return diff, nil
+	}
+
+	// Transform diff format for better LLM readability
 	return transformDiffForLLM(diff), nil
 }
 
+// outputLimited runs cmd and returns its output, stopping it with
+// ErrDiffTooLarge once the output is longer than max bytes. 0 means no limit.
+func outputLimited(cmd *exec.Cmd, max int) ([]byte, error) {
+	if max <= 0 {
+		return cmd.Output()
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	output, err := io.ReadAll(io.LimitReader(stdout, int64(max)+1))
+	if err == nil && len(output) > max {
+		err = ErrDiffTooLarge
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// GetStagedDiffSummary returns the diffstat of the staged changes, followed by
+// a line per file saying how it changed and how many lines were added and
+// removed, for changes too large to send in full
+func (r Repo) GetStagedDiffSummary() (string, error) {
+	stat, err := r.GetStagedDiffStat()
+	if err != nil {
+		return "", err
+	}
+	files, err := r.GetStagedFiles()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := r.command("diff", "--cached", "-M", "--numstat", "-z")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged line counts: %w", err)
+	}
+	// With -z, each record is "added<TAB>removed<TAB>path" followed by NUL, or
+	// "added<TAB>removed<TAB>" then NUL-separated old and new paths for renames
+	counts := make(map[string]string)
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		added, rest, _ := strings.Cut(fields[i], "\t")
+		removed, path, _ := strings.Cut(rest, "\t")
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		if added == "-" {
+			counts[path] = "binary"
+		} else {
+			counts[path] = fmt.Sprintf("+%s -%s", added, removed)
+		}
+	}
+
+	var summary strings.Builder
+	summary.WriteString(stat + "\n\n")
+	for _, f := range files {
+		summary.WriteString(f.Description())
+		if count, ok := counts[f.Path]; ok {
+			summary.WriteString(" (" + count + ")")
+		}
+		summary.WriteString("\n")
+	}
+	return summary.String(), nil
+}
+
+// GetStagedLineCounts returns the number of files the staged changes touch,
+// and the lines they add and remove. Binary files count as changed files
+// without lines.
+func (r Repo) GetStagedLineCounts() (files, added, removed int, err error) {
+	cmd := r.command("diff", "--cached", "-M", "--numstat")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get staged line counts: %w", err)
+	}
+	for line := range strings.Lines(string(output)) {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		files++
+		a, _ := strconv.Atoi(fields[0])
+		d, _ := strconv.Atoi(fields[1])
+		added += a
+		removed += d
+	}
+	return files, added, removed, nil
+}
+
+// GetStagedDiffStat returns the diffstat summary of the staged changes
+func (r Repo) GetStagedDiffStat() (string, error) {
+	cmd := r.command("diff", "--cached", "--stat")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged diffstat: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
 func transformDiffForLLM(diff string) string {
 	lines := strings.Split(diff, "\n")
 	var transformedLines []string
@@ -51,27 +227,113 @@ func transformDiffForLLM(diff string) string {
 	return strings.Join(transformedLines, "\n")
 }
 
-func GetReadmeContent() string {
-	readmeFiles := []string{"README.md", "readme.md", "Readme.md", "README", "readme"}
+// StagedFile describes one file in the staged changes
+type StagedFile struct {
+	Status  string // Status letter from git diff --name-status: A, M, D, R, C, or T
+	Path    string
+	OldPath string // Original path for renames and copies
+}
+
+// Description returns a git-status-style description of the change
+func (f StagedFile) Description() string {
+	switch f.Status {
+	case "A":
+		return "new file:   " + f.Path
+	case "D":
+		return "deleted:    " + f.Path
+	case "R":
+		return "renamed:    " + f.OldPath + " -> " + f.Path
+	case "C":
+		return "copied:     " + f.OldPath + " -> " + f.Path
+	case "T":
+		return "typechange: " + f.Path
+	default:
+		return "modified:   " + f.Path
+	}
+}
+
+func (r Repo) GetStagedFiles() ([]StagedFile, error) {
+	cmd := r.command("diff", "--cached", "--name-status", "-z")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	// With -z, fields are NUL-separated: status, path (and a second path for renames/copies)
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	var files []StagedFile
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "" {
+			continue
+		}
+		status := fields[i][:1]
+		file := StagedFile{Status: status}
+		if (status == "R" || status == "C") && i+2 < len(fields) {
+			file.OldPath = fields[i+1]
+			file.Path = fields[i+2]
+			i += 2
+		} else if i+1 < len(fields) {
+			file.Path = fields[i+1]
+			i++
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// readmeNames are the README files looked for, in order of preference
+var readmeNames = []string{
+	"README.md", "README.markdown", "README.rst", "README.adoc", "README.asciidoc", "README.txt", "README",
+}
 
-	for _, filename := range readmeFiles {
-		if content, err := os.ReadFile(filename); err == nil {
-			return string(content)
+// GetReadme returns the name and content of the README at the root of the
+// repository, so it is found from subdirectories too. Names are matched
+// regardless of case, as in readme.md or Readme.rst. The name is "" if there is
+// no README.
+func (r Repo) GetReadme() (string, string) {
+	root, err := r.GetRepositoryRoot()
+	if err != nil {
+		root = cmp.Or(r.Dir, ".")
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", ""
+	}
+
+	for _, name := range readmeNames {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(entry.Name(), name) {
+				continue
+			}
+			if content, err := os.ReadFile(filepath.Join(root, entry.Name())); err == nil {
+				return entry.Name(), string(content)
+			}
 		}
 	}
+	return "", ""
+}
+
+func (r Repo) Commit(message string) error {
+	return r.commit(message)
+}
 
-	return ""
+// AmendMessage replaces HEAD's message, leaving its changes as they are even
+// if other changes are staged
+func (r Repo) AmendMessage(message string) error {
+	return r.commit(message, "--amend", "--only")
 }
 
-func Commit(message string) error {
-	// Write commit message to temporary file to handle multiline messages properly
-	tmpFile, err := os.CreateTemp("", "git-ac-commit-*.txt")
+// commit runs git commit with the message and any extra arguments
+func (r Repo) commit(message string, extra ...string) error {
+	// Write commit message to temporary file to handle multiline messages
+	// properly. In the git directory, it can never be staged by mistake.
+	gitDir, _ := r.GetGitDir()
+	tmpFile, err := tempfile.Create(gitDir, "git-ac-commit-*.txt")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	defer func() {
-		_ = os.Remove(tmpFile.Name())
-	}()
+	defer tempfile.Remove(tmpFile.Name())
 	defer func() {
 		_ = tmpFile.Close()
 	}()
@@ -84,9 +346,21 @@ func Commit(message string) error {
 		return fmt.Errorf("failed to close temporary file: %w", err)
 	}
 
-	cmd := exec.Command("git", "commit", "-F", tmpFile.Name())
+	// git commit signs commits by itself with commit.gpgsign, but only
+	// git format-patch reads format.signoff, which users set to sign off
+	// everything they contribute
+	args := append([]string{"commit", "-F", tmpFile.Name()}, extra...)
+	if signoff, _ := r.GetConfigBool("format.signoff"); signoff {
+		args = append(args, "--signoff")
+	}
+
+	cmd := r.command(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if sign, _ := r.GetConfigBool("commit.gpgsign"); sign {
+		// Signing may need to ask for a passphrase
+		cmd.Stdin = os.Stdin
+	}
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("git commit failed: %w", err)
@@ -95,8 +369,25 @@ func Commit(message string) error {
 	return nil
 }
 
-func StageAllChanges() error {
-	cmd := exec.Command("git", "add", "-u")
+// ShowStagedDiff shows the staged diff to the user, through git's pager when
+// standard output is a terminal
+func (r Repo) ShowStagedDiff(colored bool) error {
+	colorArg := "--color=never"
+	if colored {
+		colorArg = "--color=always"
+	}
+	cmd := r.command("diff", "--cached", "--stat", "--patch", colorArg)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to show staged diff: %w", err)
+	}
+	return nil
+}
+
+func (r Repo) StageAllChanges() error {
+	cmd := r.command("add", "-u")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -107,11 +398,343 @@ func StageAllChanges() error {
 	return nil
 }
 
-func GetRepositoryRoot() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+func (r Repo) GetRepositoryRoot() (string, error) {
+	cmd := r.command("rev-parse", "--show-toplevel")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get repository root: %w", err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// GetGitPath returns the path of a file in the repository's git directory,
+// such as "index", allowing for worktrees
+func (r Repo) GetGitPath(name string) (string, error) {
+	cmd := r.command("rev-parse", "--path-format=absolute", "--git-path", name)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s in the git directory: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Operation is a git operation that has stopped partway, waiting for the
+// user to continue it
+type Operation string
+
+const (
+	OperationNone       Operation = ""
+	OperationMerge      Operation = "merge"
+	OperationRebase     Operation = "rebase"
+	OperationCherryPick Operation = "cherry-pick"
+	OperationRevert     Operation = "revert"
+)
+
+// GetGitDir returns the absolute path of the repository's git directory
+func (r Repo) GetGitDir() (string, error) {
+	cmd := r.command("rev-parse", "--absolute-git-dir")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git directory: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetOperation returns the operation in progress in the repository, if any
+func (r Repo) GetOperation() Operation {
+	gitDir, err := r.GetGitDir()
+	if err != nil {
+		return OperationNone
+	}
+
+	// A rebase stopped on a commit that was being cherry-picked is still a rebase
+	markers := []struct {
+		name string
+		op   Operation
+	}{
+		{"rebase-merge", OperationRebase},
+		{"rebase-apply", OperationRebase},
+		{"MERGE_HEAD", OperationMerge},
+		{"CHERRY_PICK_HEAD", OperationCherryPick},
+		{"REVERT_HEAD", OperationRevert},
+	}
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(gitDir, m.name)); err == nil {
+			return m.op
+		}
+	}
+	return OperationNone
+}
+
+// GetMergeSubject returns the subject line git prepared for the merge in
+// progress, such as "Merge branch 'feature'"
+func (r Repo) GetMergeSubject() (string, error) {
+	path, err := r.GetGitPath("MERGE_MSG")
+	if err != nil {
+		return "", err
+	}
+	message, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the merge message: %w", err)
+	}
+	for _, line := range strings.Split(string(message), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("the merge message is empty")
+}
+
+// GetRecentCommits returns the hashes of up to n of the latest commits on HEAD,
+// newest first, leaving out merges and root commits
+func (r Repo) GetRecentCommits(n int) ([]string, error) {
+	cmd := r.command("rev-list", "--min-parents=1", "--max-parents=1", fmt.Sprintf("--max-count=%d", n), "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// GetRecentSubjects returns the subjects of up to n of the latest commits on
+// HEAD, newest first, leaving out merges
+func (r Repo) GetRecentSubjects(n int) ([]string, error) {
+	cmd := r.command("log", "--no-merges", "--format=%s", fmt.Sprintf("--max-count=%d", n), "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commit subjects: %w", err)
+	}
+	return strings.Split(strings.TrimRight(string(output), "\n"), "\n"), nil
+}
+
+// GetCommitMessage returns the message of a commit
+func (r Repo) GetCommitMessage(rev string) (string, error) {
+	cmd := r.command("log", "-1", "--format=%B", rev)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit message: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetMergedTags returns the tags reachable from HEAD
+func (r Repo) GetMergedTags() ([]string, error) {
+	cmd := r.command("tag", "--merged", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// GetCommitMessagesSince returns the messages of the commits on HEAD since rev,
+// newest first, or of all commits on HEAD if rev is ""
+func (r Repo) GetCommitMessagesSince(rev string) ([]string, error) {
+	rangeSpec := "HEAD"
+	if rev != "" {
+		rangeSpec = rev + "..HEAD"
+	}
+	cmd := r.command("log", "-z", "--format=%B", rangeSpec)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits in %s: %w", rangeSpec, err)
+	}
+
+	var messages []string
+	for _, message := range strings.Split(string(output), "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// CreateTag creates an annotated tag on HEAD
+func (r Repo) CreateTag(name, message string) error {
+	cmd := r.command("tag", "-a", name, "-m", message)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %s", name, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// LogCommit is a commit as git log lists it, with its changes
+type LogCommit struct {
+	Hash    string
+	Author  string
+	Date    string // YYYY-MM-DD
+	Message string
+	Patch   string // The changes as a patch, or as a diffstat
+}
+
+// GetFileHistory returns up to n of the latest commits that changed path,
+// newest first, following it across renames
+func (r Repo) GetFileHistory(path string, n int) ([]LogCommit, error) {
+	commits, err := r.log("--follow", "-p", "--full-index", fmt.Sprintf("--max-count=%d", n), "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of %s: %w", path, err)
+	}
+	return commits, nil
+}
+
+// GetAuthoredCommits returns the commits by author since the given time on
+// the given branches (all local branches if none), newest first, with their
+// diffstats. Merges are left out.
+func (r Repo) GetAuthoredCommits(author string, since time.Time, branches []string) ([]LogCommit, error) {
+	args := []string{"--no-merges", "--stat", "--author=" + author, "--since=" + since.Format(time.RFC3339)}
+	if len(branches) == 0 {
+		args = append(args, "--branches")
+	} else {
+		args = append(args, branches...)
+	}
+	commits, err := r.log(append(args, "--")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits by %s: %w", author, err)
+	}
+	return commits, nil
+}
+
+// GetRangeCommits returns the commits in base..head, newest first, with their
+// diffstats. Merges are left out.
+func (r Repo) GetRangeCommits(base, head string) ([]LogCommit, error) {
+	commits, err := r.log("--no-merges", "--stat", base+".."+head, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits in %s..%s: %w", base, head, err)
+	}
+	return commits, nil
+}
+
+// log runs git log with extra arguments and parses the commits it lists
+func (r Repo) log(extra ...string) ([]LogCommit, error) {
+	// NUL bytes can't appear in messages or patches, so they delimit the headers
+	args := append([]string{"log", "--format=%x00%H%n%an%n%as%n%B%x00"}, extra...)
+	output, err := r.command(args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// Output alternates between headers and changes, after an empty first part
+	parts := strings.Split(string(output), "\x00")
+	var commits []LogCommit
+	for i := 1; i+1 < len(parts); i += 2 {
+		fields := strings.SplitN(parts[i], "\n", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		commits = append(commits, LogCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Message: strings.TrimSpace(fields[3]),
+			Patch:   strings.Trim(parts[i+1], "\n"),
+		})
+	}
+	return commits, nil
+}
+
+// ResolveCommit returns the hash of the commit that rev names
+func (r Repo) ResolveCommit(rev string) (string, error) {
+	cmd := r.command("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a commit", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetParent returns the hash of a commit's first parent, or "" for a root commit
+func (r Repo) GetParent(hash string) string {
+	cmd := r.command("rev-parse", "--verify", "--quiet", hash+"^")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// GetCommitStat returns the diffstat summary of a commit's changes, against
+// its first parent
+func (r Repo) GetCommitStat(rev string) (string, error) {
+	parent := r.GetParent(rev)
+	if parent == "" {
+		var err error
+		if parent, err = r.GetEmptyTree(); err != nil {
+			return "", err
+		}
+	}
+	cmd := r.command("diff", "--stat", parent, rev)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diffstat of %s: %w", rev, err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// GetEmptyTree returns the ID of the empty tree, to diff a root commit against
+func (r Repo) GetEmptyTree() (string, error) {
+	cmd := r.command("hash-object", "-t", "tree", "--stdin")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get empty tree: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetHead returns the hash of the commit HEAD points to
+func (r Repo) GetHead() (string, error) {
+	cmd := r.command("rev-parse", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetCurrentBranch returns the name of the checked-out branch, or "" when HEAD is detached
+func (r Repo) GetCurrentBranch() string {
+	cmd := r.command("symbolic-ref", "--quiet", "--short", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// GetConfig returns the value of a git config key, or "" if it isn't set
+func (r Repo) GetConfig(key string) string {
+	cmd := r.command("config", "--get", key)
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// GetConfigBool returns the value of a boolean git config key, and whether it is set
+func (r Repo) GetConfigBool(key string) (bool, bool) {
+	cmd := r.command("config", "--type=bool", "--get", key)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, false
+	}
+	return strings.TrimSpace(string(output)) == "true", true
+}
+
+// GetConfigAll returns all values of a multi-valued git config key
+func (r Repo) GetConfigAll(key string) []string {
+	cmd := r.command("config", "--get-all", key)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n")
+}
+
+// AddLocalConfig adds a value to a git config key in the repository's own config
+func (r Repo) AddLocalConfig(key, value string) error {
+	cmd := r.command("config", "--local", "--add", key, value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git config failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
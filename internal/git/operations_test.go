@@ -0,0 +1,165 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRepo creates an empty repository, kept apart from the user's git config
+func testRepo(t *testing.T) Repo {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, role := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+role+"_NAME", "Ada")
+		t.Setenv("GIT_"+role+"_EMAIL", "ada@example.com")
+	}
+	r := Repo{Dir: t.TempDir()}
+	run(t, r, "init", "-q", "-b", "main")
+	return r
+}
+
+// run runs git in r, failing the test if it fails
+func run(t *testing.T, r Repo, args ...string) string {
+	t.Helper()
+	output, err := r.command(args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes a file and commits it with message
+func commitFile(t *testing.T, r Repo, path, contents, message string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(r.Dir, path), []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, r, "add", "--", path)
+	run(t, r, "commit", "-q", "-m", message)
+	return run(t, r, "rev-parse", "HEAD")
+}
+
+func TestGetFileHistory(t *testing.T) {
+	r := testRepo(t)
+	first := commitFile(t, r, "old.go", "package a\n", "feat: add a\n\nWith a body.\n\nIn paragraphs.")
+	commitFile(t, r, "other.go", "package a\n", "chore: unrelated")
+	run(t, r, "mv", "old.go", "new.go")
+	run(t, r, "commit", "-q", "-m", "refactor: rename a")
+	last := commitFile(t, r, "new.go", "package a\n\nvar x = 1\n", "fix: set x")
+
+	commits, err := r.GetFileHistory("new.go", 10)
+	if err != nil {
+		t.Fatalf("GetFileHistory() error = %v", err)
+	}
+	var messages []string
+	for _, c := range commits {
+		messages = append(messages, c.Message)
+	}
+	want := []string{"fix: set x", "refactor: rename a", "feat: add a\n\nWith a body.\n\nIn paragraphs."}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Fatalf("GetFileHistory() messages = %q, want %q", messages, want)
+	}
+	if commits[0].Hash != last || commits[2].Hash != first || commits[0].Author != "Ada" {
+		t.Errorf("GetFileHistory() = %+v", commits)
+	}
+	if !strings.Contains(commits[0].Patch, "+var x = 1") {
+		t.Errorf("GetFileHistory() patch = %q, want the change", commits[0].Patch)
+	}
+
+	if commits, err := r.GetFileHistory("new.go", 1); err != nil || len(commits) != 1 {
+		t.Errorf("GetFileHistory() with n = 1 returned %d commits, %v", len(commits), err)
+	}
+}
+
+func TestGetAuthoredCommits(t *testing.T) {
+	r := testRepo(t)
+	written := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	t.Setenv("GIT_AUTHOR_DATE", written.Format(time.RFC3339))
+	t.Setenv("GIT_COMMITTER_DATE", written.Format(time.RFC3339))
+	commitFile(t, r, "a.go", "package a\n", "feat: add a")
+	t.Setenv("GIT_AUTHOR_EMAIL", "grace@example.com")
+	commitFile(t, r, "b.go", "package b\n", "feat: add b")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	// A message that looks like git log's own output mustn't be mistaken for it
+	commitFile(t, r, "c.go", "package c\n", "fix: handle c\n\ncommit 0123456789abcdef\nAuthor: Grace\n\n 1 file changed")
+
+	commits, err := r.GetAuthoredCommits("ada@example.com", written.Add(-time.Hour), nil)
+	if err != nil {
+		t.Fatalf("GetAuthoredCommits() error = %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("GetAuthoredCommits() = %+v, want 2 commits", commits)
+	}
+	if commits[0].Message != "fix: handle c\n\ncommit 0123456789abcdef\nAuthor: Grace\n\n 1 file changed" || commits[1].Message != "feat: add a" {
+		t.Errorf("GetAuthoredCommits() messages = %q, %q", commits[0].Message, commits[1].Message)
+	}
+	if !strings.Contains(commits[0].Patch, "c.go | 1 +") || commits[0].Date != "2026-03-02" {
+		t.Errorf("GetAuthoredCommits() = %+v, want the diffstat and date", commits[0])
+	}
+
+	if commits, err := r.GetAuthoredCommits("ada@example.com", written.Add(time.Hour), nil); err != nil || len(commits) != 0 {
+		t.Errorf("GetAuthoredCommits() since a later time = %+v, %v", commits, err)
+	}
+}
+
+func TestGetRangeCommits(t *testing.T) {
+	r := testRepo(t)
+	commitFile(t, r, "a.go", "package a\n", "feat: add a")
+	run(t, r, "tag", "v1.0.0")
+	commitFile(t, r, "b.go", "package b\n", "feat: add b")
+	commitFile(t, r, "c.go", "package c\n", "fix: handle c")
+
+	commits, err := r.GetRangeCommits("v1.0.0", "HEAD")
+	if err != nil {
+		t.Fatalf("GetRangeCommits() error = %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "fix: handle c" || commits[1].Message != "feat: add b" {
+		t.Errorf("GetRangeCommits() = %+v", commits)
+	}
+
+	if _, err := r.GetRangeCommits("v9.9.9", "HEAD"); err == nil {
+		t.Errorf("GetRangeCommits() with an unknown base succeeded")
+	}
+}
+
+func TestGetStagedDiffSummary(t *testing.T) {
+	r := testRepo(t)
+	commitFile(t, r, "old.go", "package a\n\nfunc a() {}\n\nfunc b() {}\n", "feat: add a")
+	commitFile(t, r, "gone.txt", "bye\n", "docs: add gone")
+
+	run(t, r, "mv", "old.go", "new.go")
+	files := map[string]string{
+		"new.go":     "package a\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n",
+		"with space": "one\ntwo\n",
+		"ünïcode.md": "text\n",
+		"image.bin":  "\x00\x01\x02",
+	}
+	for path, contents := range files {
+		if err := os.WriteFile(filepath.Join(r.Dir, path), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run(t, r, "rm", "-q", "gone.txt")
+	run(t, r, "add", "-A")
+
+	summary, err := r.GetStagedDiffSummary()
+	if err != nil {
+		t.Fatalf("GetStagedDiffSummary() error = %v", err)
+	}
+	for _, want := range []string{
+		"renamed:    old.go -> new.go (+2 -0)",
+		"new file:   with space (+2 -0)",
+		"new file:   ünïcode.md (+1 -0)",
+		"new file:   image.bin (binary)",
+		"deleted:    gone.txt (+0 -1)",
+		"5 files changed",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("GetStagedDiffSummary() doesn't contain %q:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"git-ac/internal/xdg"
+)
+
+// Outcomes of a generated message
+const (
+	OutcomeCommitted = "committed" // Committed, possibly after editing
+	OutcomeCopied    = "copied"    // Copied to the clipboard
+	OutcomeAborted   = "aborted"   // Rejected by the user
+)
+
+// Entry records a generated message and what became of it
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Outcome          string    `json:"outcome"`
+	Generated        string    `json:"generated"`       // The message as generated (the chosen candidate)
+	Final            string    `json:"final,omitempty"` // The message as committed or copied
+	LatencyMS        int64     `json:"latency_ms"`      // Time taken to generate the candidates
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+}
+
+// DefaultPath returns the location of the history log,
+// $XDG_STATE_HOME/git-ac/history.jsonl (~/.local/state/git-ac/history.jsonl by default)
+func DefaultPath() (string, error) {
+	stateHome, err := xdg.StateHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateHome, "git-ac", "history.jsonl"), nil
+}
+
+// Append adds an entry to the log at path, creating it if needed
+func Append(path string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history log: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// One write per entry, so concurrent runs don't interleave lines
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history log: %w", err)
+	}
+	return nil
+}
+
+// Read returns the entries in the log at path, oldest first. A missing log has
+// no entries, and lines that can't be parsed are skipped.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history log: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,97 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// ModelStats summarizes the history of one provider and model
+type ModelStats struct {
+	Provider         string
+	Model            string
+	Generations      int
+	Accepted         int           // Committed or copied without changes
+	Used             int           // Committed or copied, with or without changes
+	EditDistance     float64       // Mean characters changed between generated and used messages
+	Latency          time.Duration // Mean time to generate
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// AcceptanceRate returns the fraction of generated messages used unchanged
+func (s ModelStats) AcceptanceRate() float64 {
+	if s.Generations == 0 {
+		return 0
+	}
+	return float64(s.Accepted) / float64(s.Generations)
+}
+
+// Aggregate summarizes entries per provider and model, most used first
+func Aggregate(entries []Entry) []ModelStats {
+	type key struct{ provider, model string }
+	byModel := make(map[key]*ModelStats)
+	var order []key
+	totalDistance := make(map[key]int)
+	totalLatency := make(map[key]time.Duration)
+
+	for _, e := range entries {
+		k := key{e.Provider, e.Model}
+		s, ok := byModel[k]
+		if !ok {
+			s = &ModelStats{Provider: e.Provider, Model: e.Model}
+			byModel[k] = s
+			order = append(order, k)
+		}
+
+		s.Generations++
+		s.PromptTokens += e.PromptTokens
+		s.CompletionTokens += e.CompletionTokens
+		totalLatency[k] += time.Duration(e.LatencyMS) * time.Millisecond
+
+		if e.Outcome == OutcomeCommitted || e.Outcome == OutcomeCopied {
+			s.Used++
+			distance := editDistance(e.Generated, e.Final)
+			totalDistance[k] += distance
+			if distance == 0 {
+				s.Accepted++
+			}
+		}
+	}
+
+	stats := make([]ModelStats, 0, len(order))
+	for _, k := range order {
+		s := byModel[k]
+		s.Latency = totalLatency[k] / time.Duration(s.Generations)
+		if s.Used > 0 {
+			s.EditDistance = float64(totalDistance[k]) / float64(s.Used)
+		}
+		stats = append(stats, *s)
+	}
+	slices.SortStableFunc(stats, func(a, b ModelStats) int {
+		return cmp.Compare(b.Generations, a.Generations)
+	})
+	return stats
+}
+
+// editDistance returns the Levenshtein distance between a and b, in characters
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,130 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiURL is Linear's GraphQL endpoint
+const apiURL = "https://api.linear.app/graphql"
+
+// requestTimeout limits how long an issue lookup may delay generation
+const requestTimeout = 10 * time.Second
+
+// issueIDPattern matches an issue ID at the start of a branch name segment, as
+// in Linear's suggested branch names (e.g. "alice/eng-123-fix-login")
+var issueIDPattern = regexp.MustCompile(`(?:^|/)([A-Za-z][A-Za-z0-9]{0,6})-([0-9]+)(?:[-_/]|$)`)
+
+// Issue is a Linear issue
+type Issue struct {
+	ID    string // Identifier, e.g. "ENG-123"
+	Title string // "" if the issue wasn't looked up
+}
+
+// String describes the issue for the prompt
+func (i Issue) String() string {
+	if i.Title == "" {
+		return i.ID
+	}
+	return i.ID + ": " + i.Title
+}
+
+// IssueID returns the first issue ID in a branch name, or "" if there is none.
+// With teams, only IDs of those teams are recognized.
+func IssueID(branch string, teams []string) string {
+	for _, match := range issueIDPattern.FindAllStringSubmatch(branch, -1) {
+		team := strings.ToUpper(match[1])
+		if len(teams) > 0 && !slices.ContainsFunc(teams, func(t string) bool { return strings.EqualFold(t, team) }) {
+			continue
+		}
+		return team + "-" + match[2]
+	}
+	return ""
+}
+
+// Client looks up issues with Linear's API
+type Client struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewClient creates a client that authenticates with a personal API key
+func NewClient(apiKey string) *Client {
+	return &Client{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type issueResponse struct {
+	Data struct {
+		Issue *struct {
+			Identifier string `json:"identifier"`
+			Title      string `json:"title"`
+		} `json:"issue"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Issue looks up the issue with the given ID
+func (c *Client) Issue(ctx context.Context, id string) (*Issue, error) {
+	jsonData, err := json.Marshal(graphQLRequest{
+		Query:     `query($id: String!) { issue(id: $id) { identifier title } }`,
+		Variables: map[string]any{"id": id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Linear: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("authentication failed (401) - check linear.api_key")
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("request to Linear failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// GraphQL errors, such as an unknown issue, come with status 200 or 400
+	var issueResp issueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&issueResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(issueResp.Errors) > 0 {
+		return nil, fmt.Errorf("%s", issueResp.Errors[0].Message)
+	}
+	if issueResp.Data.Issue == nil {
+		return nil, fmt.Errorf("issue %s not found", id)
+	}
+
+	return &Issue{ID: issueResp.Data.Issue.Identifier, Title: issueResp.Data.Issue.Title}, nil
+}
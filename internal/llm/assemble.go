@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// MessageParts is a commit message as structured fields, for providers that
+// can return JSON matching PartsSchema
+type MessageParts struct {
+	Type     string   `json:"type"`
+	Scope    string   `json:"scope,omitempty"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body,omitempty"`
+	Breaking bool     `json:"breaking,omitempty"`
+	Footers  []string `json:"footers,omitempty"`
+}
+
+// PartsSchema is the JSON schema describing MessageParts
+var PartsSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "type": {"type": "string"},
+    "scope": {"type": "string"},
+    "subject": {"type": "string"},
+    "body": {"type": "string"},
+    "breaking": {"type": "boolean"},
+    "footers": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["type", "subject"]
+}`)
+
+// ParseParts decodes a model response containing a MessageParts JSON object
+func ParseParts(text string) (MessageParts, error) {
+	// Tolerate code fences or stray text around the object
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return MessageParts{}, fmt.Errorf("response does not contain a JSON object")
+	}
+
+	var parts MessageParts
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parts); err != nil {
+		return MessageParts{}, fmt.Errorf("failed to decode message parts: %w", err)
+	}
+	if strings.TrimSpace(parts.Subject) == "" {
+		return MessageParts{}, fmt.Errorf("message parts have an empty subject")
+	}
+	return parts, nil
+}
+
+// AssembleMessage formats structured parts into a commit message, enforcing the
+// configured type, scope, and subject length locally
+func AssembleMessage(parts MessageParts, commitConfig config.CommitConfig) string {
+	h := Header{
+		Type:     strings.TrimSpace(parts.Type),
+		Scope:    strings.TrimSpace(parts.Scope),
+		Breaking: parts.Breaking,
+		Subject:  strings.TrimSpace(parts.Subject),
+	}
+	if h.Type == "" {
+		h.Type = fallbackType(commitConfig.Types)
+	}
+	h = enforceHeaderFields(h, commitConfig)
+
+	var footers []string
+	for _, footer := range parts.Footers {
+		footer = strings.TrimSpace(footer)
+		if footer == "" {
+			continue
+		}
+		// A breaking change footer implies "!" in the header
+		if strings.HasPrefix(footer, breakingFooter) || strings.HasPrefix(footer, "BREAKING-CHANGE: ") {
+			h.Breaking = true
+		}
+		footers = append(footers, footer)
+	}
+
+	// Conventional subjects don't end with a period
+	h.Subject = strings.TrimRight(h.Subject, ". ")
+
+	h = shortenHeader(h, commitConfig.MaxLength)
+
+	message := h.String()
+
+	if body := strings.TrimSpace(parts.Body); body != "" && commitConfig.IncludeBody != "false" {
+		message += "\n\n" + wrapText(styleText(body, commitConfig.BodyStyle), commitConfig.BodyWidth)
+	}
+
+	if len(footers) > 0 {
+		message += "\n\n" + strings.Join(footers, "\n")
+	}
+
+	return message
+}
+
+// shortenHeader shortens the subject at a word boundary so the header fits
+// within maxLength characters, rather than splitting it across lines
+func shortenHeader(h Header, maxLength int) Header {
+	if overflow := textLength(h.String()) - maxLength; maxLength > 0 && overflow > 0 {
+		h.Subject = shortenAtWord(h.Subject, textLength(h.Subject)-overflow)
+	}
+	return h
+}
+
+// shortenAtWord cuts text to at most limit characters, at the last word boundary that fits
+func shortenAtWord(text string, limit int) string {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text
+	}
+	if idx := strings.LastIndex(string(runes[:limit+1]), " "); idx > 0 {
+		return strings.TrimRight(text[:idx], " ,;:-")
+	}
+	return string(runes[:limit])
+}
+
+// fallbackType is the type given to a message the model left untyped: chore,
+// or the first configured type if the team doesn't use chore
+func fallbackType(types []config.CommitType) string {
+	for _, t := range types {
+		if strings.EqualFold(t.Name, "chore") {
+			return t.Name
+		}
+	}
+	if len(types) > 0 {
+		return types[0].Name
+	}
+	return "chore"
+}
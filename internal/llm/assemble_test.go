@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"testing"
+
+	"git-ac/internal/config"
+)
+
+func TestAssembleMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		parts  MessageParts
+		adjust func(*config.CommitConfig)
+		want   string
+	}{
+		{
+			name:  "header and body",
+			parts: MessageParts{Type: "feat", Scope: "api", Subject: "add token validation.", Body: "Checks the signature."},
+			want:  "feat(api): add token validation\n\nChecks the signature.",
+		},
+		{
+			name:  "breaking change footer marks the header",
+			parts: MessageParts{Type: "feat", Subject: "drop v1 tokens", Footers: []string{"BREAKING CHANGE: v1 tokens are rejected", " "}},
+			want:  "feat!: drop v1 tokens\n\nBREAKING CHANGE: v1 tokens are rejected",
+		},
+		{
+			name:  "long subject shortened at a word",
+			parts: MessageParts{Type: "feat", Subject: "add validation of tokens"},
+			adjust: func(c *config.CommitConfig) {
+				c.MaxLength = 25
+			},
+			want: "feat: add validation of",
+		},
+		{
+			name:   "body left out",
+			parts:  MessageParts{Type: "fix", Subject: "handle empty input", Body: "More."},
+			adjust: func(c *config.CommitConfig) { c.IncludeBody = "false" },
+			want:   "fix: handle empty input",
+		},
+		{
+			name:  "missing type is chore",
+			parts: MessageParts{Subject: "bump linter version"},
+			want:  "chore: bump linter version",
+		},
+		{
+			name:  "missing type without chore is the first configured type",
+			parts: MessageParts{Subject: "bump linter version"},
+			adjust: func(c *config.CommitConfig) {
+				c.Types = []config.CommitType{{Name: "maint"}, {Name: "feat"}}
+			},
+			want: "maint: bump linter version",
+		},
+		{
+			name:   "forced type and scope",
+			parts:  MessageParts{Type: "feat", Scope: "api", Subject: "handle empty input"},
+			adjust: func(c *config.CommitConfig) { c.Type, c.Scope = "fix", "cli" },
+			want:   "fix(cli): handle empty input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			if tt.adjust != nil {
+				tt.adjust(&commitConfig)
+			}
+			if got := AssembleMessage(tt.parts, commitConfig); got != tt.want {
+				t.Errorf("AssembleMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseParts(t *testing.T) {
+	parts, err := ParseParts("```json\n{\"type\": \"fix\", \"subject\": \"handle empty input\"}\n```")
+	if err != nil {
+		t.Fatalf("ParseParts() error = %v", err)
+	}
+	if parts.Type != "fix" || parts.Subject != "handle empty input" {
+		t.Errorf("ParseParts() = %+v", parts)
+	}
+
+	for _, text := range []string{"fix: handle empty input", `{"type": "fix", "subject": " "}`, `{"type": `} {
+		if _, err := ParseParts(text); err == nil {
+			t.Errorf("ParseParts(%q) succeeded, want an error", text)
+		}
+	}
+}
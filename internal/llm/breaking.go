@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// breakingFooter is the conventional commits footer token for breaking changes
+const breakingFooter = "BREAKING CHANGE: "
+
+// declarationPatterns match exported API declarations; the last submatch is the name
+var declarationPatterns = []*regexp.Regexp{
+	// Go: exported funcs, methods, and types
+	regexp.MustCompile(`^\s*func\s+(?:\([^)]*\)\s*)?([A-Z]\w*)`),
+	regexp.MustCompile(`^\s*type\s+([A-Z]\w*)`),
+	// JavaScript/TypeScript exports
+	regexp.MustCompile(`^\s*export\s+(?:default\s+)?(?:async\s+)?(?:function\*?|class|const|let|var|interface|type|enum)\s+(\w+)`),
+	// Rust public items
+	regexp.MustCompile(`^\s*pub\s+(?:async\s+)?(?:fn|struct|enum|trait|type|const|mod)\s+(\w+)`),
+}
+
+// DetectBreakingChanges looks for exported declarations that a diff removes or changes,
+// returning a description of each. It is a heuristic used to prompt the model.
+func DetectBreakingChanges(diff string) []string {
+	_, files := parseDiff(diff)
+
+	var findings []string
+	for _, f := range files {
+		if fileCategory(f.path) != categorySource || isInternalPath(f.path) {
+			continue
+		}
+
+		removed := map[string]string{}
+		added := map[string]string{}
+		var order []string
+		for _, h := range f.hunks {
+			for _, line := range h.lines {
+				content, isAdded, ok := changedContent(line)
+				if !ok {
+					continue
+				}
+				name := declarationName(content)
+				if name == "" {
+					continue
+				}
+				if isAdded {
+					added[name] = strings.TrimSpace(content)
+				} else {
+					if _, seen := removed[name]; !seen {
+						order = append(order, name)
+					}
+					removed[name] = strings.TrimSpace(content)
+				}
+			}
+		}
+
+		for _, name := range order {
+			newDecl, stillExists := added[name]
+			switch {
+			case !stillExists:
+				findings = append(findings, fmt.Sprintf("removed %s (%s)", name, f.path))
+			case newDecl != removed[name]:
+				findings = append(findings, fmt.Sprintf("changed the signature of %s (%s)", name, f.path))
+			}
+		}
+	}
+
+	return findings
+}
+
+// changedContent returns the content of an added or removed diff line
+func changedContent(line string) (string, bool, bool) {
+	switch {
+	case strings.HasPrefix(line, "ADDED: "):
+		return strings.TrimPrefix(line, "ADDED: "), true, true
+	case strings.HasPrefix(line, "REMOVED: "):
+		return strings.TrimPrefix(line, "REMOVED: "), false, true
+	case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+		return line[1:], true, true
+	case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+		return line[1:], false, true
+	}
+	return "", false, false
+}
+
+func declarationName(line string) string {
+	for _, pattern := range declarationPatterns {
+		if m := pattern.FindStringSubmatch(line); m != nil {
+			return m[len(m)-1]
+		}
+	}
+	return ""
+}
+
+// isInternalPath reports whether the path is in a Go internal package, which isn't public API
+func isInternalPath(p string) bool {
+	return strings.HasPrefix(p, "internal/") || strings.Contains(p, "/internal/")
+}
+
+// ensureBreakingMarkers keeps the header's "!" and the BREAKING CHANGE footer consistent:
+// a footer implies "!", and "!" gets a footer listing the detected breaking changes
+func ensureBreakingMarkers(message string, breaking []string) string {
+	lines := strings.Split(message, "\n")
+	h, ok := ParseHeader(lines[0])
+	if !ok {
+		return message
+	}
+
+	hasFooter := false
+	for i, line := range lines[1:] {
+		// Normalize the BREAKING-CHANGE synonym the spec allows
+		if strings.HasPrefix(line, "BREAKING-CHANGE: ") {
+			lines[i+1] = breakingFooter + strings.TrimPrefix(line, "BREAKING-CHANGE: ")
+		}
+		if strings.HasPrefix(lines[i+1], breakingFooter) {
+			hasFooter = true
+		}
+	}
+
+	if hasFooter && !h.Breaking {
+		h.Breaking = true
+		lines[0] = h.String()
+	}
+
+	message = strings.Join(lines, "\n")
+	if h.Breaking && !hasFooter && len(breaking) > 0 {
+		message += "\n\n" + breakingFooter + strings.Join(breaking, "; ")
+	}
+	return message
+}
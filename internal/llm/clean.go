@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"git-ac/internal/config"
+)
+
+// StripThinking removes the reasoning that thinking models emit in tags like <think>
+func StripThinking(message string, tags []string) string {
+	cleaned := strings.TrimSpace(message)
+
+	for _, tag := range tags {
+		open, close := "<"+tag+">", "</"+tag+">"
+
+		// For thinking models, look for the actual answer after the closing tag
+		if strings.Contains(cleaned, close) {
+			parts := strings.Split(cleaned, close)
+			if len(parts) > 1 {
+				// Take everything after the last closing tag
+				cleaned = strings.TrimSpace(parts[len(parts)-1])
+			}
+		}
+
+		// Remove thinking patterns
+		for strings.Contains(cleaned, open) && strings.Contains(cleaned, close) {
+			start := strings.Index(cleaned, open)
+			end := strings.Index(cleaned, close) + len(close)
+			if start >= 0 && end > start {
+				cleaned = cleaned[:start] + cleaned[end:]
+			} else {
+				break
+			}
+		}
+
+		// Remove remaining thinking tags
+		cleaned = strings.ReplaceAll(cleaned, open, "")
+		cleaned = strings.ReplaceAll(cleaned, close, "")
+	}
+
+	return strings.TrimSpace(cleaned)
+}
+
+// stripCodeFence removes a Markdown code fence wrapped around the whole message
+func stripCodeFence(message string) string {
+	if !strings.HasPrefix(message, "```") {
+		return message
+	}
+	lines := strings.Split(message, "\n")
+	lines = lines[1:] // Opening fence, possibly with a language tag
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "```" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// stripPrefixes repeatedly removes any of the given preambles from the start of the message
+func stripPrefixes(message string, prefixes []string) string {
+	for {
+		stripped := false
+		for _, prefix := range prefixes {
+			if prefix != "" && len(message) >= len(prefix) && strings.EqualFold(message[:len(prefix)], prefix) {
+				message = strings.TrimSpace(message[len(prefix):])
+				stripped = true
+			}
+		}
+		if !stripped {
+			return message
+		}
+	}
+}
+
+// cutStopPhrases drops everything from the first stop phrase on, below the subject line
+func cutStopPhrases(message string, phrases []string) string {
+	subject, rest, found := strings.Cut(message, "\n")
+	if !found {
+		return message
+	}
+
+	lowerRest := strings.ToLower(rest)
+	cut := len(rest)
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		if idx := strings.Index(lowerRest, strings.ToLower(phrase)); idx >= 0 && idx < cut {
+			cut = idx
+		}
+	}
+
+	return strings.TrimSpace(subject + "\n" + rest[:cut])
+}
+
+// CleanCommitMessage applies the configured cleaning rules and handles message formatting
+func CleanCommitMessage(message string, commitConfig config.CommitConfig) string {
+	cleaned := StripThinking(message, commitConfig.Cleaning.ThinkTags)
+	cleaned = stripCodeFence(cleaned)
+	cleaned = stripPrefixes(cleaned, commitConfig.Cleaning.StripPrefixes)
+	cleaned = cutStopPhrases(cleaned, commitConfig.Cleaning.StopPhrases)
+
+	// Handle multi-line commits based on config
+	lines := strings.Split(cleaned, "\n")
+	if len(lines) > 0 {
+		lines[0] = enforceHeader(lines[0], commitConfig)
+
+		// Subject-only commits drop whatever body the model produced anyway,
+		// keeping only a BREAKING CHANGE footer
+		if commitConfig.IncludeBody == "false" {
+			// A new slice, so appending doesn't overwrite the lines being read
+			kept := []string{lines[0]}
+			for _, line := range lines[1:] {
+				if !strings.HasPrefix(line, breakingFooter) {
+					continue
+				}
+				if len(kept) == 1 {
+					kept = append(kept, "")
+				}
+				kept = append(kept, line)
+			}
+			lines = kept
+		}
+
+		// Shorten an overlong subject at a word boundary, as AssembleMessage
+		// does. Lengths are counted in runes so multi-byte characters are never split.
+		if subject := strings.TrimSpace(lines[0]); commitConfig.MaxLength > 0 && textLength(subject) > commitConfig.MaxLength {
+			if h, ok := ParseHeader(subject); ok {
+				lines[0] = shortenHeader(h, commitConfig.MaxLength).String()
+			} else {
+				lines[0] = shortenAtWord(subject, commitConfig.MaxLength)
+			}
+		}
+
+		// Always allow multi-line commits - let the LLM decide
+		cleaned = strings.Join(lines, "\n")
+	}
+
+	return cleaned
+}
+
+// withSubject cleans up the response like CleanCommitMessage, then puts the
+// user's subject line above the body the model wrote. The model is asked to
+// repeat the subject, and whatever first line it wrote instead is replaced.
+func withSubject(response string, commitConfig config.CommitConfig) string {
+	cleaned := StripThinking(response, commitConfig.Cleaning.ThinkTags)
+	cleaned = stripCodeFence(cleaned)
+	cleaned = stripPrefixes(cleaned, commitConfig.Cleaning.StripPrefixes)
+	cleaned = cutStopPhrases(cleaned, commitConfig.Cleaning.StopPhrases)
+
+	first, rest, _ := strings.Cut(cleaned, "\n")
+	first = strings.TrimSpace(first)
+	if _, ok := ParseHeader(first); ok || first == "" || strings.EqualFold(first, strings.TrimSpace(commitConfig.Subject)) {
+		cleaned = rest
+	}
+	if body := strings.TrimSpace(cleaned); body != "" {
+		return commitConfig.Subject + "\n\n" + body
+	}
+	return commitConfig.Subject
+}
+
+// textLength returns the length of text in characters (runes) rather than bytes
+func textLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// FinishMessage turns a raw model response into the final commit message, assembling
+// it from structured parts when structured output is enabled and the response has them
+func FinishMessage(response string, input PromptInput, commitConfig config.CommitConfig) (string, error) {
+	commitConfig = input.CommitConfig(commitConfig)
+	if commitConfig.StructuredOutput {
+		if parts, err := ParseParts(StripThinking(response, commitConfig.Cleaning.ThinkTags)); err == nil {
+			message := AssembleMessage(parts, commitConfig)
+			if commitConfig.Subject != "" {
+				header, _, _ := strings.Cut(message, "\n")
+				message = commitConfig.Subject + strings.TrimPrefix(message, header)
+			}
+			message = ensureBreakingMarkers(message, input.BreakingChanges)
+			return LimitBody(message, commitConfig.MaxBodyLines, commitConfig.MaxTotalChars), nil
+		}
+		// Fall back to treating the response as plain text
+	}
+
+	var cleaned string
+	if commitConfig.Subject != "" {
+		cleaned = withSubject(response, commitConfig)
+	} else {
+		cleaned = CleanCommitMessage(response, commitConfig)
+	}
+	if cleaned == "" {
+		return "", &ValidationError{Reason: "the message was empty after removing commentary", Message: response, Unusable: true}
+	}
+	message := ensureBreakingMarkers(WrapBody(StyleBody(cleaned, commitConfig.BodyStyle), commitConfig.BodyWidth), input.BreakingChanges)
+	return LimitBody(message, commitConfig.MaxBodyLines, commitConfig.MaxTotalChars), nil
+}
+
+// LimitBody drops lines from the end of the body until it has at most maxLines
+// lines and the whole message has at most maxChars characters. The subject and
+// trailers are always kept; 0 means no limit.
+func LimitBody(message string, maxLines, maxChars int) string {
+	subject, body, trailers := SplitMessage(message)
+	if body == "" {
+		return message
+	}
+
+	lines := strings.Split(body, "\n")
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+
+	join := func(lines []string) string {
+		parts := []string{subject}
+		if b := strings.TrimSpace(strings.Join(lines, "\n")); b != "" {
+			parts = append(parts, b)
+		}
+		if trailers != "" {
+			parts = append(parts, trailers)
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	limited := join(lines)
+	for maxChars > 0 && len(lines) > 0 && textLength(limited) > maxChars {
+		lines = lines[:len(lines)-1]
+		limited = join(lines)
+	}
+	return limited
+}
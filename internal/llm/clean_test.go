@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"testing"
+
+	"git-ac/internal/config"
+)
+
+// testCommitConfig returns the default commit settings
+func testCommitConfig() config.CommitConfig {
+	return config.CommitConfig{
+		MaxLength:   72,
+		Types:       config.DefaultCommitTypes(),
+		IncludeBody: "auto",
+		TwoStage:    "auto",
+		Mood:        "imperative",
+		MaxAttempts: 3,
+		Candidates:  1,
+		Cleaning:    config.DefaultCleaningConfig(),
+	}
+}
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		adjust   func(*config.CommitConfig)
+		want     string
+	}{
+		{
+			name:     "plain message",
+			response: "feat: add token validation\n\nChecks the signature.",
+			want:     "feat: add token validation\n\nChecks the signature.",
+		},
+		{
+			name:     "thinking removed",
+			response: "<think>It adds validation.</think>\nfeat: add token validation",
+			want:     "feat: add token validation",
+		},
+		{
+			name:     "configured thinking tag removed",
+			response: "<reasoning>It adds validation.</reasoning>\nfeat: add token validation",
+			adjust:   func(c *config.CommitConfig) { c.Cleaning.ThinkTags = []string{"reasoning"} },
+			want:     "feat: add token validation",
+		},
+		{
+			name:     "code fence and prefix removed",
+			response: "```\nCommit message: fix: handle empty input\n```",
+			want:     "fix: handle empty input",
+		},
+		{
+			name:     "stop phrase cuts commentary",
+			response: "fix: handle empty input\n\nExplanation: the input was empty.",
+			want:     "fix: handle empty input",
+		},
+		{
+			name:     "commentary kept without stop phrases",
+			response: "fix: handle empty input\n\nExplanation: the input was empty.",
+			adjust:   func(c *config.CommitConfig) { c.Cleaning.StopPhrases = nil },
+			want:     "fix: handle empty input\n\nExplanation: the input was empty.",
+		},
+		{
+			name:     "forced type replaces the model's",
+			response: "feat: handle empty input",
+			adjust:   func(c *config.CommitConfig) { c.Type = "fix" },
+			want:     "fix: handle empty input",
+		},
+		{
+			name:     "forced type added to an untyped subject",
+			response: "handle empty input",
+			adjust:   func(c *config.CommitConfig) { c.Type = "fix" },
+			want:     "fix: handle empty input",
+		},
+		{
+			name:     "forced scope",
+			response: "fix(api): handle empty input",
+			adjust:   func(c *config.CommitConfig) { c.Scope = "cli" },
+			want:     "fix(cli): handle empty input",
+		},
+		{
+			name:     "allowed scope respelled",
+			response: "fix(API): handle empty input",
+			adjust:   func(c *config.CommitConfig) { c.Scopes = []string{"api"} },
+			want:     "fix(api): handle empty input",
+		},
+		{
+			name:     "scope outside the list left for validation",
+			response: "fix(ui): handle empty input",
+			adjust:   func(c *config.CommitConfig) { c.Scopes = []string{"api"} },
+			want:     "fix(ui): handle empty input",
+		},
+		{
+			name:     "long subject shortened at a word",
+			response: "feat: add validation of tokens\n\nChecks the signature.",
+			adjust:   func(c *config.CommitConfig) { c.MaxLength = 20 },
+			want:     "feat: add validation\n\nChecks the signature.",
+		},
+		{
+			name:     "long subject without spaces cut",
+			response: "abcdefghijkl",
+			adjust:   func(c *config.CommitConfig) { c.MaxLength = 6 },
+			want:     "abcdef",
+		},
+		{
+			name:     "multi-byte characters counted once",
+			response: "fix: ééé ééé",
+			adjust:   func(c *config.CommitConfig) { c.MaxLength = 12 },
+			want:     "fix: ééé ééé",
+		},
+		{
+			name:     "multi-byte characters not split",
+			response: "fix: éééééé",
+			adjust:   func(c *config.CommitConfig) { c.MaxLength = 8 },
+			want:     "fix: ééé",
+		},
+		{
+			name:     "body dropped without include_body",
+			response: "feat: add token validation\n\nChecks the signature.",
+			adjust:   func(c *config.CommitConfig) { c.IncludeBody = "false" },
+			want:     "feat: add token validation",
+		},
+		{
+			name:     "every breaking change footer kept without include_body",
+			response: "feat!: drop v1 tokens\n\nRemoves old code.\n\nBREAKING CHANGE: v1 tokens are rejected\nBREAKING CHANGE: the /v1 route is gone",
+			adjust:   func(c *config.CommitConfig) { c.IncludeBody = "false" },
+			want:     "feat!: drop v1 tokens\n\nBREAKING CHANGE: v1 tokens are rejected\nBREAKING CHANGE: the /v1 route is gone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			if tt.adjust != nil {
+				tt.adjust(&commitConfig)
+			}
+			if got := CleanCommitMessage(tt.response, commitConfig); got != tt.want {
+				t.Errorf("CleanCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	message := "feat: add token validation\n\n- one\n- two\n- three"
+	tests := []struct {
+		name     string
+		maxLines int
+		maxChars int
+		want     string
+	}{
+		{"no limits", 0, 0, message},
+		{"line limit", 2, 0, "feat: add token validation\n\n- one\n- two"},
+		{"character limit", 0, 40, "feat: add token validation\n\n- one\n- two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LimitBody(message, tt.maxLines, tt.maxChars); got != tt.want {
+				t.Errorf("LimitBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"subject repeated", "fix(api): handle empty input\n\nChecks for nil.", "Handle nil input\n\nChecks for nil."},
+		{"subject left out", "Checks for nil.", "Handle nil input\n\nChecks for nil."},
+		{"no body", "fix: handle empty input", "Handle nil input"},
+		{"subject in another convention repeated", "<think>Hmm.</think>\n\nHandle nil input\n\nChecks for nil.", "Handle nil input\n\nChecks for nil."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			commitConfig.Subject = "Handle nil input"
+			if got := withSubject(tt.response, commitConfig); got != tt.want {
+				t.Errorf("withSubject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FindConflictMarkers returns the places, as path:line, where the diff adds
+// the <<<<<<< or >>>>>>> lines git writes around an unresolved conflict
+func FindConflictMarkers(diff string) []string {
+	_, files := parseDiff(diff)
+
+	var found []string
+	for _, f := range files {
+		for _, h := range f.hunks {
+			line := hunkNewStart(h.header)
+			for _, l := range h.lines {
+				content, added, changed := changedContent(l)
+				if !changed {
+					line++
+					continue
+				}
+				if !added {
+					continue
+				}
+				if isConflictMarker(content) {
+					found = append(found, fmt.Sprintf("%s:%d", f.path, line))
+				}
+				line++
+			}
+		}
+	}
+	return found
+}
+
+// isConflictMarker reports whether line starts or ends a conflict. The =======
+// between the sides is left out, since it's also a Markdown and reST underline.
+func isConflictMarker(line string) bool {
+	for _, marker := range []string{"<<<<<<<", ">>>>>>>"} {
+		if rest, ok := strings.CutPrefix(line, marker); ok && (rest == "" || rest[0] == ' ') {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkNewStart returns the first line number in the new file that a hunk
+// header such as "@@ -10,4 +12,5 @@" covers
+func hunkNewStart(header string) int {
+	_, rest, ok := strings.Cut(header, " +")
+	if !ok {
+		return 1
+	}
+	rest, _, _ = strings.Cut(rest, " ")
+	rest, _, _ = strings.Cut(rest, ",")
+	start, err := strconv.Atoi(rest)
+	if err != nil {
+		return 1
+	}
+	return start
+}
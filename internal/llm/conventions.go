@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// ConventionFiles are the files, at the root of a repository, in which a team
+// writes down its commit conventions, in the order they are looked for
+var ConventionFiles = []string{".gitmessage", "COMMIT_CONVENTION.md", "CONVENTIONS.md"}
+
+// conventionType matches a line of a conventions file defining a commit type,
+// such as "- feat: a new feature", "* **fix**: a bug fix", or "# docs - documentation"
+var conventionType = regexp.MustCompile("^\\s*(?:[-*+]|#+)\\s+(?:\\*\\*|`)?([a-z]+)(?::(?:\\*\\*|`)|(?:\\*\\*|`)?\\s*[:–—-])\\s+(\\S.*)$")
+
+// ConventionTypes returns the commit types a conventions file lists, with
+// their descriptions. It returns nil unless the file lists at least three
+// types including fix, as a list of conventional commit types would, so
+// lists of other things aren't mistaken for one.
+func ConventionTypes(conventions string) []config.CommitType {
+	var types []config.CommitType
+	seen := map[string]bool{}
+	for _, line := range strings.Split(conventions, "\n") {
+		m := conventionType.FindStringSubmatch(line)
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		types = append(types, config.CommitType{Name: m[1], Description: strings.TrimSpace(m[2])})
+	}
+	if len(types) < 3 || !seen["fix"] {
+		return nil
+	}
+	return types
+}
+
+// CommitConfig returns commitConfig with the commit types from the
+// repository's conventions file, if it lists any, and the scopes learned from
+// its history, if commitConfig has none
+func (p ProjectContext) CommitConfig(commitConfig config.CommitConfig) config.CommitConfig {
+	if len(p.Types) > 0 {
+		commitConfig.Types = p.Types
+	}
+	if len(p.Scopes) > 0 && len(commitConfig.Scopes) == 0 {
+		commitConfig.Scopes = p.Scopes
+	}
+	return commitConfig
+}
@@ -0,0 +1,31 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildPRDescriptionPrompt creates the prompt for a pull request title and
+// description of the changes in diff
+func BuildPRDescriptionPrompt(diff string, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	system.WriteString("Write a pull request title and description for the changes in the following diff.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- The first line is the title: a short summary of the whole change, with no Markdown formatting\n")
+	system.WriteString("- After a blank line, write the description in Markdown: one or two sentences on what the change does and why, then a bulleted list of the notable changes\n")
+	system.WriteString("- Mention anything reviewers should pay special attention to, such as breaking changes or migrations\n")
+	system.WriteString("- Do not invent motivation or testing that the diff doesn't show\n")
+	system.WriteString("- Output ONLY the title and description, with no preamble or commentary")
+	if extra := strings.TrimSpace(project.ExtraInstructions); extra != "" {
+		system.WriteString("\n\nADDITIONAL INSTRUCTIONS:\n" + extra)
+	}
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	user.WriteString("DIFF:\n")
+	user.WriteString(diff)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1252 maps the bytes 0x80-0x9F of Windows-1252, which Latin-1 text from
+// Windows usually is, to the characters they stand for. The zero entries are
+// undefined.
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// ToUTF8 makes a diff valid UTF-8, so the model sees text rather than mojibake
+// and lengths are measured in characters. Lines that aren't UTF-8 are decoded
+// as Windows-1252 (a superset of Latin-1) when they look like it. Other lines,
+// such as Shift-JIS, whose characters take several bytes, can't be decoded
+// without knowing the encoding, so their invalid bytes become U+FFFD. Control
+// characters other than tabs and carriage returns, as in binary-ish files,
+// become U+FFFD too. It returns the diff and the number of lines changed.
+func ToUTF8(diff string) (string, int) {
+	if utf8.ValidString(diff) && !hasControlChars(diff) {
+		return diff, 0
+	}
+
+	lines := strings.Split(diff, "\n")
+	changed := 0
+	for i, line := range lines {
+		fixed := line
+		if !utf8.ValidString(fixed) {
+			if decoded, ok := decodeCP1252(fixed); ok {
+				fixed = decoded
+			} else {
+				fixed = strings.ToValidUTF8(fixed, "�")
+			}
+		}
+		fixed = strings.Map(replaceControl, fixed)
+		if fixed != line {
+			lines[i] = fixed
+			changed++
+		}
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// decodeCP1252 decodes line as Windows-1252, if it looks like it: every byte
+// outside ASCII stands alone, as in accented Latin text. Runs of them are more
+// likely a multibyte encoding.
+func decodeCP1252(line string) (string, bool) {
+	var b strings.Builder
+	previousHigh := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c < 0x80 {
+			b.WriteByte(c)
+			previousHigh = false
+			continue
+		}
+		if previousHigh {
+			return "", false
+		}
+		previousHigh = true
+
+		r := rune(c)
+		if c < 0xA0 {
+			if r = cp1252[c-0x80]; r == 0 {
+				return "", false
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), true
+}
+
+// hasControlChars reports whether s has control characters other than tabs,
+// carriage returns, and newlines
+func hasControlChars(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return replaceControl(r) != r && r != '\n' }) >= 0
+}
+
+// replaceControl replaces control characters other than tabs and carriage
+// returns with U+FFFD
+func replaceControl(r rune) rune {
+	if (r < 0x20 && r != '\t' && r != '\r') || r == 0x7F {
+		return '�'
+	}
+	return r
+}
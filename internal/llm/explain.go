@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildExplainPrompt creates the prompt for a plain-language explanation of
+// an existing commit, from its message and diff
+func BuildExplainPrompt(message, diff string, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	system.WriteString("Explain what the following commit does and why, for a developer reading the project's history.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- Start with one or two sentences summarizing what the commit changes and what effect that has\n")
+	system.WriteString("- Then walk through the notable changes, naming the files, functions, or behavior involved\n")
+	system.WriteString("- Explain why the change was made if the commit message or the diff says or clearly implies it; otherwise say the reason isn't stated\n")
+	system.WriteString("- Point out anything surprising, such as behavior changes that the commit message doesn't mention\n")
+	system.WriteString("- Use plain language and plain text, without Markdown headings\n")
+	system.WriteString("- Output ONLY the explanation, with no preamble or commentary")
+	if extra := strings.TrimSpace(project.ExtraInstructions); extra != "" {
+		system.WriteString("\n\nADDITIONAL INSTRUCTIONS:\n" + extra)
+	}
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	user.WriteString("COMMIT MESSAGE:\n")
+	user.WriteString(strings.TrimSpace(message) + "\n\n")
+	user.WriteString("DIFF:\n")
+	user.WriteString(diff)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildHistoryChunkPrompt creates the prompt summarizing a stretch of a file's
+// history, given as commits with their messages and patches, oldest first
+func BuildHistoryChunkPrompt(path, commits string) Prompt {
+	var system strings.Builder
+	system.WriteString("Summarize how the file " + path + " changed over the following commits, which are listed oldest first.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- For each notable change, say what changed in the file and why, citing the commit's short hash and date\n")
+	system.WriteString("- Take the reasons from the commit messages; don't invent motivation they don't give\n")
+	system.WriteString("- Group related commits, and pass over trivial ones such as formatting or typo fixes in a few words\n")
+	system.WriteString("- Output ONLY the summary, with no preamble or commentary")
+
+	return Prompt{System: system.String(), User: "COMMITS:\n" + commits}
+}
+
+// BuildHistoryNarrativePrompt creates the prompt for a narrative of how and
+// why a file evolved, from its history: either commits with patches, or
+// summaries of stretches of them, oldest first
+func BuildHistoryNarrativePrompt(path, history string, summarized bool, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	if summarized {
+		system.WriteString("Below are summaries of the history of the file " + path + ", oldest first. ")
+	} else {
+		system.WriteString("Below are the commits that changed the file " + path + ", oldest first. ")
+	}
+	system.WriteString("Tell the story of how and why the file came to be the way it is, for a developer about to change it.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- Start with one or two sentences on what the file is for and the overall direction of its changes\n")
+	system.WriteString("- Then describe the important stages in order, with their reasons, citing short commit hashes\n")
+	system.WriteString("- End with anything a developer changing the file now should keep in mind, such as past bugs, reverted approaches, or constraints the history reveals\n")
+	system.WriteString("- Take the reasons from the history; when it doesn't give one, say so rather than guessing\n")
+	system.WriteString("- Use plain language and plain text, without Markdown headings\n")
+	system.WriteString("- Output ONLY the narrative, with no preamble or commentary")
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	if summarized {
+		user.WriteString("HISTORY SUMMARIES:\n")
+	} else {
+		user.WriteString("COMMITS:\n")
+	}
+	user.WriteString(history)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// headerPattern matches a conventional commit header: type(scope)!: subject
+var headerPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+// Header is the parsed first line of a conventional commit message
+type Header struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+// ParseHeader parses a conventional commit header line, reporting whether it matched
+func ParseHeader(line string) (Header, bool) {
+	m := headerPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Header{}, false
+	}
+	return Header{
+		Type:     m[1],
+		Scope:    strings.TrimSpace(m[2]),
+		Breaking: m[3] == "!",
+		Subject:  strings.TrimSpace(m[4]),
+	}, true
+}
+
+// String formats the header back into a single line
+func (h Header) String() string {
+	var b strings.Builder
+	b.WriteString(h.Type)
+	if h.Scope != "" {
+		b.WriteString("(" + h.Scope + ")")
+	}
+	if h.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": ")
+	b.WriteString(h.Subject)
+	return b.String()
+}
+
+// enforceHeader rewrites the header to honor the forced type and scope
+func enforceHeader(line string, commitConfig config.CommitConfig) string {
+
+	h, ok := ParseHeader(line)
+	if !ok {
+		if commitConfig.Type == "" {
+			return line
+		}
+		// The model skipped the type entirely; treat the whole line as the subject
+		h = Header{Subject: strings.TrimSpace(line)}
+	}
+
+	return enforceHeaderFields(h, commitConfig).String()
+}
+
+// enforceHeaderFields applies the forced type and scope to h, and the
+// configured spelling of its type and scope
+func enforceHeaderFields(h Header, commitConfig config.CommitConfig) Header {
+	if commitConfig.Type != "" {
+		h.Type = commitConfig.Type
+	} else {
+		// Models sometimes capitalize the type; use the configured spelling
+		for _, t := range commitConfig.Types {
+			if strings.EqualFold(t.Name, h.Type) {
+				h.Type = t.Name
+				break
+			}
+		}
+	}
+
+	if commitConfig.Scope != "" {
+		h.Scope = commitConfig.Scope
+	} else if scope := allowedScope(h.Scope, commitConfig.Scopes); scope != "" {
+		// Scopes outside the list are left for validation to reject
+		h.Scope = scope
+	}
+
+	return h
+}
+
+// allowedScope returns the allowed spelling of scope, or "" if it isn't allowed
+func allowedScope(scope string, allowed []string) string {
+	for _, a := range allowed {
+		if strings.EqualFold(a, scope) {
+			return a
+		}
+	}
+	return ""
+}
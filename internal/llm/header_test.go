@@ -0,0 +1,26 @@
+package llm
+
+import "testing"
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want Header
+		ok   bool
+	}{
+		{"feat: add token validation", Header{Type: "feat", Subject: "add token validation"}, true},
+		{"fix(api)!: reject v1 tokens", Header{Type: "fix", Scope: "api", Breaking: true, Subject: "reject v1 tokens"}, true},
+		{"  docs( readme ):update  ", Header{Type: "docs", Scope: "readme", Subject: "update"}, true},
+		{"add token validation", Header{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseHeader(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseHeader(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+		if again, _ := ParseHeader(got.String()); ok && again != got {
+			t.Errorf("ParseHeader(%q) = %+v, want %+v", got.String(), again, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lfsPointer holds what a Git LFS pointer file's lines in a diff say about one
+// side of the change
+type lfsPointer struct {
+	oid  string
+	size int64 // -1 if not in the diff
+}
+
+// DescribeLFSPointers replaces the changes to Git LFS pointer files with a
+// one-line note naming the asset and its size, since the pointers' hashes tell
+// the model nothing. It returns the diff and the number of assets described.
+func DescribeLFSPointers(diff string) (string, int) {
+	preamble, files := parseDiff(diff)
+
+	described := 0
+	for _, f := range files {
+		note, ok := lfsNote(f)
+		if !ok {
+			continue
+		}
+		f.hunks = []*diffHunk{{file: f, header: note}}
+		described++
+	}
+
+	if described == 0 {
+		return diff, 0
+	}
+	return renderDiff(preamble, files), described
+}
+
+// lfsNote describes the change to f if every changed line in it is a line of
+// an LFS pointer
+func lfsNote(f *diffFile) (string, bool) {
+	oldPointer := lfsPointer{size: -1}
+	newPointer := lfsPointer{size: -1}
+
+	for _, h := range f.hunks {
+		for _, line := range h.lines {
+			content, added, changed := changedContent(line)
+			if !changed {
+				// The size is only a context line when it didn't change
+				content = strings.TrimSpace(strings.TrimPrefix(line, "UNCHANGED:"))
+				if size, ok := lfsSize(content); ok {
+					oldPointer.size, newPointer.size = size, size
+				}
+				continue
+			}
+
+			pointer := &oldPointer
+			if added {
+				pointer = &newPointer
+			}
+			content = strings.TrimSpace(content)
+			switch {
+			case strings.HasPrefix(content, "version https://git-lfs.github.com/spec/"),
+				strings.HasPrefix(content, "ext-"):
+			case strings.HasPrefix(content, "oid sha256:"):
+				pointer.oid = strings.TrimPrefix(content, "oid sha256:")
+			default:
+				size, ok := lfsSize(content)
+				if !ok {
+					return "", false
+				}
+				pointer.size = size
+			}
+		}
+	}
+
+	switch {
+	case oldPointer.oid == "" && newPointer.oid == "":
+		return "", false
+	case oldPointer.oid == "":
+		return fmt.Sprintf("[Git LFS asset %s added (%s)]", f.path, formatSize(newPointer.size)), true
+	case newPointer.oid == "":
+		return fmt.Sprintf("[Git LFS asset %s deleted (was %s)]", f.path, formatSize(oldPointer.size)), true
+	default:
+		return fmt.Sprintf("[Git LFS asset %s updated (%s -> %s)]", f.path, formatSize(oldPointer.size), formatSize(newPointer.size)), true
+	}
+}
+
+func lfsSize(line string) (int64, bool) {
+	rest, ok := strings.CutPrefix(line, "size ")
+	if !ok {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+// formatSize formats a size in bytes for people, e.g. "1.5 MB"
+func formatSize(size int64) string {
+	switch {
+	case size < 0:
+		return "size unknown"
+	case size < 1000:
+		return fmt.Sprintf("%d bytes", size)
+	}
+
+	value := float64(size)
+	for _, unit := range []string{"KB", "MB", "GB"} {
+		value /= 1000
+		if value < 1000 {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return fmt.Sprintf("%.1f TB", value/1000)
+}
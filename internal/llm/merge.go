@@ -0,0 +1,31 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildMergePrompt creates the prompt for the body of a merge commit, from the
+// subject git prepared for it and the commits the merge brings in
+func BuildMergePrompt(subject, commits string, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	system.WriteString("Write the body of a merge commit whose subject line is \"" + subject + "\", summarizing what the merge brings in from the commits below.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- Summarize the merged changes as a short bulleted list, most significant first, grouping related commits\n")
+	system.WriteString("- Describe changes in plain language rather than repeating commit subjects\n")
+	system.WriteString("- Don't repeat the subject line, and don't invent changes the commits don't show\n")
+	system.WriteString("- Wrap lines at 72 characters\n")
+	system.WriteString("- Output ONLY the body, with no preamble or commentary")
+	if extra := strings.TrimSpace(project.ExtraInstructions); extra != "" {
+		system.WriteString("\n\nADDITIONAL INSTRUCTIONS:\n" + extra)
+	}
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	user.WriteString("MERGED COMMITS:\n")
+	user.WriteString(commits)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// trailerPattern matches git trailer lines like "Signed-off-by: ..." and "BREAKING CHANGE: ..."
+var trailerPattern = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*): .+|^[A-Za-z][A-Za-z0-9-]* #.+`)
+
+// SplitMessage splits a commit message into its subject line, body, and trailer block
+func SplitMessage(message string) (subject, body, trailers string) {
+	subject, rest, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return subject, "", ""
+	}
+
+	// Trailers are the final paragraph, when every line in it looks like a trailer
+	paragraphs := strings.Split(rest, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	for _, line := range strings.Split(last, "\n") {
+		if !trailerPattern.MatchString(line) {
+			return subject, rest, ""
+		}
+	}
+
+	body = strings.TrimSpace(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n"))
+	return subject, body, last
+}
+
+// InsertBeforeTrailers adds a paragraph to the end of a commit message's body,
+// above any trailers
+func InsertBeforeTrailers(message, paragraph string) string {
+	subject, body, trailers := SplitMessage(message)
+	parts := []string{subject}
+	for _, part := range []string{body, paragraph, trailers} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"strings"
+)
+
+// commitVerbs are verbs that commonly start commit subjects, used to check the
+// subject's mood. Each entry is base form, third person, past tense.
+var commitVerbs = [][3]string{
+	{"add", "adds", "added"},
+	{"allow", "allows", "allowed"},
+	{"avoid", "avoids", "avoided"},
+	{"bump", "bumps", "bumped"},
+	{"change", "changes", "changed"},
+	{"clean", "cleans", "cleaned"},
+	{"convert", "converts", "converted"},
+	{"create", "creates", "created"},
+	{"delete", "deletes", "deleted"},
+	{"deprecate", "deprecates", "deprecated"},
+	{"disable", "disables", "disabled"},
+	{"document", "documents", "documented"},
+	{"drop", "drops", "dropped"},
+	{"enable", "enables", "enabled"},
+	{"ensure", "ensures", "ensured"},
+	{"extract", "extracts", "extracted"},
+	{"fix", "fixes", "fixed"},
+	{"handle", "handles", "handled"},
+	{"implement", "implements", "implemented"},
+	{"improve", "improves", "improved"},
+	{"introduce", "introduces", "introduced"},
+	{"make", "makes", "made"},
+	{"merge", "merges", "merged"},
+	{"move", "moves", "moved"},
+	{"prevent", "prevents", "prevented"},
+	{"refactor", "refactors", "refactored"},
+	{"remove", "removes", "removed"},
+	{"rename", "renames", "renamed"},
+	{"replace", "replaces", "replaced"},
+	{"restore", "restores", "restored"},
+	{"revert", "reverts", "reverted"},
+	{"rewrite", "rewrites", "rewrote"},
+	{"simplify", "simplifies", "simplified"},
+	{"split", "splits", "split"},
+	{"support", "supports", "supported"},
+	{"switch", "switches", "switched"},
+	{"update", "updates", "updated"},
+	{"upgrade", "upgrades", "upgraded"},
+	{"use", "uses", "used"},
+	{"validate", "validates", "validated"},
+}
+
+// moodForms maps each mood to the index of its form in commitVerbs
+var moodForms = map[string]int{
+	"imperative": 0,
+	"present":    1,
+	"past":       2,
+}
+
+// moodExamples describes each mood for the prompt and validation feedback
+var moodExamples = map[string]string{
+	"imperative": "imperative mood (add, not added or adds)",
+	"present":    "present tense, third person (adds, not add or added)",
+	"past":       "past tense (added, not add or adds)",
+}
+
+// inMood returns subject with its first word, a base-form verb, in mood. A
+// subject starting with an unknown verb is returned unchanged.
+func inMood(subject, mood string) string {
+	want, ok := moodForms[mood]
+	if !ok {
+		return subject
+	}
+	verb, rest, _ := strings.Cut(subject, " ")
+	for _, forms := range commitVerbs {
+		if forms[0] == verb {
+			return strings.TrimSpace(forms[want] + " " + rest)
+		}
+	}
+	return subject
+}
+
+// checkMood reports whether the subject's first word, if it is a known verb,
+// is in the configured mood
+func checkMood(subject, mood string) bool {
+	want, ok := moodForms[mood]
+	if !ok {
+		return true
+	}
+
+	fields := strings.Fields(strings.ToLower(subject))
+	if len(fields) == 0 {
+		return true
+	}
+	word := fields[0]
+
+	for _, forms := range commitVerbs {
+		matched := false
+		for _, form := range forms {
+			if word == form {
+				matched = true
+			}
+		}
+		// Some verbs share forms (e.g. split), so any matching form in the right mood passes
+		if matched {
+			return word == forms[want]
+		}
+	}
+	return true
+}
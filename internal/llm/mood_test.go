@@ -0,0 +1,46 @@
+package llm
+
+import "testing"
+
+func TestCheckMood(t *testing.T) {
+	tests := []struct {
+		subject string
+		mood    string
+		want    bool
+	}{
+		{"add token validation", "imperative", true},
+		{"adds token validation", "imperative", false},
+		{"adds token validation", "present", true},
+		{"added token validation", "past", true},
+		{"add token validation", "past", false},
+		{"split the parser", "past", true}, // Same form in the base and past tense
+		{"tidy the parser", "past", true},  // Unknown verbs pass
+		{"", "imperative", true},
+	}
+
+	for _, tt := range tests {
+		if got := checkMood(tt.subject, tt.mood); got != tt.want {
+			t.Errorf("checkMood(%q, %q) = %v, want %v", tt.subject, tt.mood, got, tt.want)
+		}
+	}
+}
+
+func TestInMood(t *testing.T) {
+	tests := []struct {
+		subject string
+		mood    string
+		want    string
+	}{
+		{"add token validation", "imperative", "add token validation"},
+		{"add token validation", "present", "adds token validation"},
+		{"rewrite the parser", "past", "rewrote the parser"},
+		{"tidy the parser", "past", "tidy the parser"},
+		{"add", "past", "added"},
+	}
+
+	for _, tt := range tests {
+		if got := inMood(tt.subject, tt.mood); got != tt.want {
+			t.Errorf("inMood(%q, %q) = %q, want %q", tt.subject, tt.mood, got, tt.want)
+		}
+	}
+}
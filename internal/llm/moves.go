@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// changeBlock is a run of consecutive added or removed lines within a hunk
+type changeBlock struct {
+	hunk  *diffHunk
+	start int // Index of the first line in hunk.lines
+	lines []string
+	added bool
+	used  bool
+}
+
+// CollapseMoves replaces blocks of at least minLines lines that were removed in one
+// place and added unchanged (apart from indentation) in another with a one-line
+// note, so refactors that move code don't fill the prompt with it twice. It returns
+// the diff and the number of blocks collapsed; minLines of 0 disables collapsing.
+func CollapseMoves(diff string, minLines int) (string, int) {
+	if minLines <= 0 {
+		return diff, 0
+	}
+
+	preamble, files := parseDiff(diff)
+
+	var removed, added []*changeBlock
+	for _, f := range files {
+		for _, h := range f.hunks {
+			for _, b := range changeBlocks(h) {
+				if b.added {
+					added = append(added, b)
+				} else {
+					removed = append(removed, b)
+				}
+			}
+		}
+	}
+
+	// Lines to replace, by hunk and line index; "" removes the line
+	edits := make(map[*diffHunk]map[int]string)
+	edit := func(h *diffHunk, index int, line string) {
+		if edits[h] == nil {
+			edits[h] = make(map[int]string)
+		}
+		edits[h][index] = line
+	}
+
+	collapsed := 0
+	for _, from := range removed {
+		for _, to := range added {
+			if from.used || to.used {
+				continue
+			}
+
+			// The moved code may be only part of either block
+			n, fromOffset, toOffset := longestCommonRun(from.lines, to.lines)
+			if n < minLines {
+				continue
+			}
+
+			for i := 0; i < n; i++ {
+				edit(from.hunk, from.start+fromOffset+i, "")
+				edit(to.hunk, to.start+toOffset+i, "")
+			}
+			edit(to.hunk, to.start+toOffset, moveNote(n, from.hunk.file.path, to.hunk.file.path))
+			from.used, to.used = n == len(from.lines), n == len(to.lines)
+			collapsed++
+		}
+	}
+
+	if collapsed == 0 {
+		return diff, 0
+	}
+
+	for h, hunkEdits := range edits {
+		var lines []string
+		for i, line := range h.lines {
+			replacement, ok := hunkEdits[i]
+			switch {
+			case !ok:
+				lines = append(lines, line)
+			case replacement != "":
+				lines = append(lines, replacement)
+			}
+		}
+		h.lines = lines
+	}
+
+	return renderDiff(preamble, files), collapsed
+}
+
+// changeBlocks splits a hunk into runs of consecutive added or removed lines
+func changeBlocks(h *diffHunk) []*changeBlock {
+	var blocks []*changeBlock
+	var current *changeBlock
+
+	for i, line := range h.lines {
+		content, added, ok := changedContent(line)
+		if !ok {
+			current = nil
+			continue
+		}
+		if current == nil || current.added != added {
+			current = &changeBlock{hunk: h, start: i, added: added}
+			blocks = append(blocks, current)
+		}
+		current.lines = append(current.lines, strings.TrimSpace(content))
+	}
+
+	return blocks
+}
+
+// longestCommonRun finds the longest run of identical, non-blank lines in a and b,
+// returning its length and its offsets in each
+func longestCommonRun(a, b []string) (int, int, int) {
+	best, bestA, bestB := 0, 0, 0
+	for i := range a {
+		for j := range b {
+			n := 0
+			for i+n < len(a) && j+n < len(b) && a[i+n] == b[j+n] {
+				n++
+			}
+			if n > best && hasContent(a[i:i+n]) {
+				best, bestA, bestB = n, i, j
+			}
+		}
+	}
+	return best, bestA, bestB
+}
+
+func hasContent(lines []string) bool {
+	for _, line := range lines {
+		if line != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func moveNote(n int, from, to string) string {
+	if from == to {
+		return fmt.Sprintf("[moved %d lines within %s]", n, to)
+	}
+	return fmt.Sprintf("[moved %d lines from %s to %s]", n, from, to)
+}
@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// Prompt is a model prompt split into instructions and the content they apply to
+type Prompt struct {
+	System string `json:"system"` // Instructions
+	User   string `json:"user"`   // Project context and changes
+}
+
+// String combines the prompt into a single text, for providers without separate system messages
+func (p Prompt) String() string {
+	return p.System + "\n\n" + p.User
+}
+
+// BuildSummarizePrompt creates the prompt for file change summarization
+func BuildSummarizePrompt(diff string) Prompt {
+	return Prompt{
+		System: "Summarize the changes in the following diff in several sentences. Pay attention to detail. The result should be a summary that is meaningful to a human knowledgeable about the codebase.",
+		User:   fmt.Sprintf("DIFF:\n%s\n\nOUTPUT:", diff),
+	}
+}
+
+// ProjectContext is background about the project, and the team's standing rules,
+// that help the model describe changes
+type ProjectContext struct {
+	Readme            string        `json:"readme,omitempty"`             // Relevant parts of the project README, if any
+	Files             []ContextFile `json:"files,omitempty"`              // Additional files from prompt.context_files
+	SystemPrefix      string        `json:"system_prefix,omitempty"`      // Text placed before the built-in instructions
+	ExtraInstructions string        `json:"extra_instructions,omitempty"` // Rules added after the built-in requirements
+	PreviousMessage   string        `json:"previous_message,omitempty"`   // A generated message the user asked to have revised
+	Feedback          string        `json:"feedback,omitempty"`           // The user's feedback on PreviousMessage
+	Issue             string        `json:"issue,omitempty"`              // The issue the changes are for, e.g. "ENG-123: Fix login redirect"
+
+	Conventions *ContextFile        `json:"conventions,omitempty"` // The repository's commit conventions file, if it has one
+	Types       []config.CommitType `json:"types,omitempty"`       // Commit types the conventions file lists, used instead of the default types
+	Scopes      []string            `json:"scopes,omitempty"`      // Scopes learned from the repository's history, used when commit.scopes isn't set
+}
+
+// ContextFile is a file included in the prompt as project context
+type ContextFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// PromptInput is what the commit message is generated from
+type PromptInput struct {
+	Content         string           // Staged diff, or per-file summaries of it
+	IsFileSummary   bool             // Whether Content holds summaries rather than the diff
+	Project         ProjectContext   // Project background
+	BreakingChanges []string         // Possible breaking changes detected in the diff
+	Release         string           // Version the changes release, if they only bump it, such as "v1.2.3"
+	Rejected        *ValidationError // Previous attempt and why it was rejected, when retrying
+	Attempt         int              // Generation attempt, starting at 1
+}
+
+// CommitConfig returns commitConfig as the project's conventions and history
+// adjust it, with a release commit's header if the changes are one
+func (input PromptInput) CommitConfig(commitConfig config.CommitConfig) config.CommitConfig {
+	return releaseCommitConfig(input.Release, input.Project.CommitConfig(commitConfig))
+}
+
+// BuildCommitPrompt creates the commit message generation prompt
+func BuildCommitPrompt(input PromptInput, commitConfig config.CommitConfig) Prompt {
+	commitConfig = input.CommitConfig(commitConfig)
+	var prompt strings.Builder
+
+	if prefix := strings.TrimSpace(input.Project.SystemPrefix); prefix != "" {
+		prompt.WriteString(prefix + "\n\n")
+	}
+
+	prompt.WriteString("You are a Git commit message generator. " +
+		"Analyze the following changes and output ONLY a conventional commit message. Your commit message must summarize the most important and significant changes present. " +
+		"Be as specific as possible within the given constraints; saying 'change maximum character limit to 72' is better than 'update commit message rules'. ")
+	switch commitConfig.IncludeBody {
+	case "true":
+		prompt.WriteString("You MUST include an extended description of the changes after the summary line. ")
+	case "false":
+		prompt.WriteString("Output ONLY the summary line; do not include an extended description. ")
+	default:
+		prompt.WriteString("You may optionally include an extended description of the changes, ONLY if the changes are large or complex. ")
+	}
+	if commitConfig.IncludeBody != "false" {
+		switch commitConfig.BodyStyle {
+		case "bullets":
+			prompt.WriteString("Write the extended description as a bulleted list, with one change per '- ' item. ")
+		case "paragraph":
+			prompt.WriteString("Write the extended description as short prose paragraphs, not as a list. ")
+		}
+	}
+	prompt.WriteString("Focus on the changes themselves; do not explain why you chose the type you did.\n\n")
+
+	header := "type"
+	if commitConfig.Type != "" {
+		header = commitConfig.Type
+	}
+	if commitConfig.Scope != "" {
+		header += "(" + commitConfig.Scope + ")"
+	} else if len(commitConfig.Scopes) > 0 {
+		header += "(scope)"
+	}
+	switch {
+	case commitConfig.StructuredOutput:
+		prompt.WriteString("REQUIRED FORMAT:\nA JSON object with these fields:\n")
+		prompt.WriteString("- type: the commit type\n")
+		prompt.WriteString("- scope: the commit scope, or an empty string\n")
+		prompt.WriteString("- subject: the summary line, without the type or scope prefix\n")
+		if commitConfig.IncludeBody != "false" {
+			prompt.WriteString("- body: the extended description, or an empty string\n")
+		}
+		prompt.WriteString("- breaking: true only if the changes break backward compatibility\n")
+		prompt.WriteString("- footers: a list of commit footers such as 'BREAKING CHANGE: ...', usually empty\n\n")
+	case commitConfig.IncludeBody == "true":
+		prompt.WriteString(fmt.Sprintf("REQUIRED FORMAT:\n%s: summary line\n\ndescription\n\n", header))
+	case commitConfig.IncludeBody == "false":
+		prompt.WriteString(fmt.Sprintf("REQUIRED FORMAT:\n%s: summary line\n\n", header))
+	default:
+		prompt.WriteString(fmt.Sprintf("REQUIRED FORMAT:\n%s: summary line\n\noptional description\n\n", header))
+	}
+
+	if commitConfig.Type != "" {
+		prompt.WriteString(fmt.Sprintf("COMMIT TYPE:\nThe commit type has already been chosen: '%s'. Always use it; do not choose a different type.\n\n", commitConfig.Type))
+	} else {
+		prompt.WriteString("VALID TYPES:\n")
+		for _, t := range commitConfig.Types {
+			if t.Description != "" {
+				prompt.WriteString(fmt.Sprintf("%s - %s\n", t.Name, t.Description))
+			} else {
+				prompt.WriteString(t.Name + "\n")
+			}
+		}
+		prompt.WriteString("\n")
+	}
+
+	if examples := firstLineExamples(commitConfig); len(examples) > 0 {
+		prompt.WriteString("GOOD FIRST-LINE EXAMPLES:\n")
+		prompt.WriteString(strings.Join(examples, "\n") + "\n\n")
+	}
+
+	if commitConfig.Scope != "" {
+		prompt.WriteString(fmt.Sprintf("COMMIT SCOPE:\nThe commit scope has already been chosen: '%s'. Always use it; do not choose a different scope.\n\n", commitConfig.Scope))
+	} else if len(commitConfig.Scopes) > 0 {
+		prompt.WriteString("ALLOWED SCOPES:\n")
+		prompt.WriteString(strings.Join(commitConfig.Scopes, ", "))
+		prompt.WriteString("\nUse exactly one of these scopes when it fits the change. If none fits, omit the scope entirely (type: summary line). Never invent a scope that is not in this list.\n\n")
+	}
+
+	if commitConfig.Subject != "" {
+		prompt.WriteString(fmt.Sprintf("SUMMARY LINE:\nThe summary line has already been written: '%s'. Use it exactly as written as the first line, and write the extended description to explain it from the changes.\n\n", commitConfig.Subject))
+	}
+	if input.Release != "" {
+		prompt.WriteString(fmt.Sprintf("RELEASE:\nThese changes only prepare release %s: they bump the version number, and may update the changelog. Do not describe the version number edits themselves. If there is an extended description, summarize the changelog entries for this release, or leave it out if there are none.\n\n", input.Release))
+	}
+
+	prompt.WriteString("REQUIREMENTS:\n")
+	prompt.WriteString(fmt.Sprintf("- First line of the commit message MUST be concise and under %d characters\n", commitConfig.MaxLength))
+	prompt.WriteString(fmt.Sprintf("- Use the %s\n", moodExamples[commitConfig.Mood]))
+	prompt.WriteString("- No explanations, reasoning, or headings\n")
+	if len(commitConfig.BannedPhrases) > 0 {
+		prompt.WriteString(fmt.Sprintf("- Never use vague phrases such as '%s'\n", strings.Join(commitConfig.BannedPhrases, "', '")))
+	}
+	if commitConfig.StructuredOutput {
+		prompt.WriteString("- Output ONLY the JSON object\n")
+	} else {
+		prompt.WriteString("- Output ONLY the commit message\n")
+	}
+	if commitConfig.Tone == "descriptive" {
+		prompt.WriteString("- Focus on the most important changes present rather than inconsequential details. Keep the first line concise, but describe what changed and why in the extended description.\n")
+	} else {
+		prompt.WriteString("- Focus on the most important changes present rather than inconsequential details. Be extremely concise.\n")
+	}
+	if !commitConfig.StructuredOutput {
+		prompt.WriteString("- Start immediately with 'type:'\n")
+	}
+	if commitConfig.IncludeBody == "false" {
+		prompt.WriteString("- Output a single line only, except for a BREAKING CHANGE footer. DO NOT write 'No extended description'.\n")
+	} else {
+		prompt.WriteString("- If you include an extended description, it must be specific and concise. Do not include excess verbiage like 'note:' or 'these changes relate to...'. Do not prefix it with 'extended description'.\n")
+		prompt.WriteString("- If you do not include an extended description, no additional output is required. DO NOT write 'No extended description'. Your output should only include words that are meaningful to describe the diff itself.\n")
+	}
+
+	prompt.WriteString("- If the changes break backward compatibility (e.g. removing or changing a public API), add '!' after the type/scope and end the message with a footer line 'BREAKING CHANGE: <what breaks and how to migrate>'. Otherwise do neither.\n\n")
+
+	if extra := strings.TrimSpace(input.Project.ExtraInstructions); extra != "" {
+		prompt.WriteString("ADDITIONAL INSTRUCTIONS:\n")
+		prompt.WriteString(extra)
+		prompt.WriteString("\n\n")
+	}
+
+	if conventions := input.Project.Conventions; conventions != nil {
+		prompt.WriteString(fmt.Sprintf("REPOSITORY CONVENTIONS (from %s; follow any rules it gives for commit messages):\n", conventions.Path))
+		prompt.WriteString(conventions.Content)
+		prompt.WriteString("\n\n")
+	}
+
+	// Everything above is instructions; the project context and changes follow as user content
+	system := strings.TrimSpace(prompt.String())
+	prompt.Reset()
+
+	writeProjectContext(&prompt, input.Project)
+
+	if len(input.BreakingChanges) > 0 {
+		prompt.WriteString("POSSIBLE BREAKING CHANGES (detected automatically; confirm against the changes):\n")
+		for _, change := range input.BreakingChanges {
+			prompt.WriteString("- " + change + "\n")
+		}
+		prompt.WriteString("\n")
+	}
+
+	if input.IsFileSummary {
+		prompt.WriteString("FILE CHANGES SUMMARIZED:\n")
+	} else {
+		prompt.WriteString("STAGED DIFF:\n")
+	}
+	prompt.WriteString(input.Content)
+
+	if input.Project.Feedback != "" {
+		if input.Project.PreviousMessage != "" {
+			prompt.WriteString("\n\nPREVIOUS MESSAGE:\n")
+			prompt.WriteString(input.Project.PreviousMessage)
+		}
+		prompt.WriteString(fmt.Sprintf("\n\nThe user asked for a new commit message, with this feedback: %s", input.Project.Feedback))
+	}
+
+	switch {
+	case input.Rejected != nil && input.Rejected.Unusable:
+		// Nothing worth showing back; insist on the format instead
+		prompt.WriteString(fmt.Sprintf("\n\nIMPORTANT: Your previous response was unusable because %s. ", input.Rejected.Reason))
+		if commitConfig.StructuredOutput {
+			prompt.WriteString("Respond with ONLY the JSON object described above, and nothing else.")
+		} else {
+			prompt.WriteString("Respond with ONLY the commit message, starting immediately with 'type:'. Do not include any thinking, explanation, or other text.")
+		}
+	case input.Rejected != nil:
+		prompt.WriteString("\n\nPREVIOUS ATTEMPT (rejected):\n")
+		prompt.WriteString(input.Rejected.Message)
+		prompt.WriteString(fmt.Sprintf("\n\nThe previous attempt was rejected because %s. Write a new commit message that fixes this and follows the required format.", input.Rejected.Reason))
+	}
+
+	return Prompt{System: system, User: prompt.String()}
+}
+
+// exampleSubjects are example summary lines for common commit types, in the
+// imperative mood. Each starts with a verb from commitVerbs, so it can be put
+// in the configured mood.
+var exampleSubjects = map[string]string{
+	"feat":     "add JWT token validation",
+	"fix":      "handle empty input strings",
+	"refactor": "simplify YAML loading",
+	"docs":     "update installation guide",
+	"style":    "use consistent import grouping",
+	"test":     "add cases for expired tokens",
+	"chore":    "bump linter version",
+	"perf":     "avoid copying request bodies",
+	"build":    "upgrade Go toolchain to 1.22",
+	"ci":       "enable module download caching",
+}
+
+// maxExamples is the most first-line examples shown in the prompt
+const maxExamples = 4
+
+// firstLineExamples returns example first lines in the configured mood, using
+// the types the model may choose from, or the chosen type. Types without an
+// example are left out, so the list may be empty.
+func firstLineExamples(commitConfig config.CommitConfig) []string {
+	types := commitConfig.TypeNames()
+	if commitConfig.Type != "" {
+		types = []string{commitConfig.Type}
+	}
+
+	var examples []string
+	for _, name := range types {
+		subject, ok := exampleSubjects[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		examples = append(examples, name+": "+inMood(subject, commitConfig.Mood))
+		if len(examples) == maxExamples {
+			break
+		}
+	}
+	return examples
+}
+
+// writeProjectContext writes the README and context files to a prompt
+func writeProjectContext(prompt *strings.Builder, project ProjectContext) {
+	if project.Issue != "" {
+		prompt.WriteString("ISSUE (what these changes are for; don't mention its ID, a reference is added automatically):\n")
+		prompt.WriteString(project.Issue)
+		prompt.WriteString("\n\n")
+	}
+
+	if project.Readme != "" {
+		prompt.WriteString("PROJECT README:\n")
+		prompt.WriteString(project.Readme)
+		prompt.WriteString("\n\n")
+	}
+
+	for _, file := range project.Files {
+		prompt.WriteString(fmt.Sprintf("PROJECT CONTEXT (%s):\n", file.Path))
+		prompt.WriteString(file.Content)
+		prompt.WriteString("\n\n")
+	}
+}
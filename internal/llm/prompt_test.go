@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"git-ac/internal/config"
+)
+
+func TestFirstLineExamples(t *testing.T) {
+	tests := []struct {
+		name   string
+		adjust func(*config.CommitConfig)
+		want   []string
+	}{
+		{
+			name: "default types",
+			want: []string{
+				"feat: add JWT token validation",
+				"fix: handle empty input strings",
+				"refactor: simplify YAML loading",
+				"docs: update installation guide",
+			},
+		},
+		{
+			name: "configured types only",
+			adjust: func(c *config.CommitConfig) {
+				c.Types = []config.CommitType{{Name: "perf"}, {Name: "security"}, {Name: "Fix"}}
+			},
+			want: []string{"perf: avoid copying request bodies", "Fix: handle empty input strings"},
+		},
+		{
+			name:   "types without examples",
+			adjust: func(c *config.CommitConfig) { c.Types = []config.CommitType{{Name: "security"}} },
+		},
+		{
+			name:   "forced type",
+			adjust: func(c *config.CommitConfig) { c.Type = "docs" },
+			want:   []string{"docs: update installation guide"},
+		},
+		{
+			name:   "past mood",
+			adjust: func(c *config.CommitConfig) { c.Types = c.Types[:2]; c.Mood = "past" },
+			want:   []string{"feat: added JWT token validation", "fix: handled empty input strings"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			if tt.adjust != nil {
+				tt.adjust(&commitConfig)
+			}
+			if got := firstLineExamples(commitConfig); !slices.Equal(got, tt.want) {
+				t.Errorf("firstLineExamples() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCommitPromptExamples(t *testing.T) {
+	commitConfig := testCommitConfig()
+	prompt := BuildCommitPrompt(PromptInput{Content: "diff"}, commitConfig).String()
+	if !strings.Contains(prompt, "GOOD FIRST-LINE EXAMPLES:\nfeat: add JWT token validation\n") {
+		t.Errorf("BuildCommitPrompt() doesn't show the examples:\n%s", prompt)
+	}
+
+	commitConfig.Types = []config.CommitType{{Name: "security"}}
+	prompt = BuildCommitPrompt(PromptInput{Content: "diff"}, commitConfig).String()
+	if strings.Contains(prompt, "EXAMPLES") {
+		t.Errorf("BuildCommitPrompt() shows examples for types that have none:\n%s", prompt)
+	}
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// Candidate is a generated commit message with its local quality score
+type Candidate struct {
+	Message string  `json:"message"`
+	Score   float64 `json:"score"`
+}
+
+// RankCandidates scores messages with local heuristics and returns them best first.
+// Messages that pass validation rank above those that don't; among those, messages
+// that mention more of the changed files, have a subject of reasonable length, and
+// avoid banned phrases in the body score higher.
+func RankCandidates(messages []string, diff string, commitConfig config.CommitConfig) []Candidate {
+	stems := changedFileStems(diff)
+
+	candidates := make([]Candidate, 0, len(messages))
+	for _, message := range messages {
+		candidates = append(candidates, Candidate{
+			Message: message,
+			Score:   scoreMessage(message, stems, commitConfig),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+func scoreMessage(message string, stems []string, commitConfig config.CommitConfig) float64 {
+	score := 0.0
+	if ValidateMessage(message, commitConfig) == nil {
+		score += 100
+	}
+
+	// Coverage: the share of changed files the message refers to
+	lower := strings.ToLower(message)
+	if len(stems) > 0 {
+		covered := 0
+		for _, stem := range stems {
+			if strings.Contains(lower, stem) {
+				covered++
+			}
+		}
+		score += 20 * float64(covered) / float64(len(stems))
+	}
+
+	// Length: very short subjects are rarely specific enough
+	subject, body, _ := SplitMessage(message)
+	if h, ok := ParseHeader(subject); ok {
+		words := len(strings.Fields(h.Subject))
+		switch {
+		case words >= 4:
+			score += 10
+		case words >= 2:
+			score += 5
+		}
+	}
+
+	// Vague phrases in the body count against it, like in the subject
+	lowerBody := strings.ToLower(body)
+	for _, phrase := range commitConfig.BannedPhrases {
+		if phrase != "" && strings.Contains(lowerBody, strings.ToLower(phrase)) {
+			score -= 10
+		}
+	}
+
+	return score
+}
+
+// changedFileStems returns the lowercased base names, without extensions, of the
+// files in diff, as the words a message covering them would likely use
+func changedFileStems(diff string) []string {
+	seen := make(map[string]bool)
+	var stems []string
+	for _, file := range SplitDiff(diff) {
+		base := path.Base(file.Path)
+		stem := strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))
+		// Names this short or generic say little about coverage
+		if len(stem) < 3 || seen[stem] {
+			continue
+		}
+		seen[stem] = true
+		stems = append(stems, stem)
+	}
+	return stems
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// markdownHeading matches an ATX heading and captures its level and text
+var markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// asciidocHeading matches an AsciiDoc section title and captures its level and text
+var asciidocHeading = regexp.MustCompile(`^(={1,6})\s+(\S.*)$`)
+
+// asciidocAttribute matches an AsciiDoc attribute entry, such as ":toc:"
+var asciidocAttribute = regexp.MustCompile(`^:[\w-]+:`)
+
+// ExtractReadme picks the parts of a README that describe the project: its first
+// paragraph and any sections with the configured headings, limited to max_lines.
+// name is the README's file name, whose extension tells Markdown from
+// reStructuredText (.rst) and AsciiDoc (.adoc); anything else is read as Markdown.
+func ExtractReadme(name, readme string, readmeConfig config.ReadmeConfig) string {
+	if !readmeConfig.Enabled || strings.TrimSpace(readme) == "" {
+		return ""
+	}
+
+	lines := strings.Split(strings.ReplaceAll(readme, "\r\n", "\n"), "\n")
+	switch strings.ToLower(path.Ext(name)) {
+	case ".rst":
+		lines = fromRST(lines)
+	case ".adoc", ".asciidoc":
+		lines = fromAsciiDoc(lines)
+	}
+
+	var parts []string
+	if paragraph := firstParagraph(lines); paragraph != "" {
+		parts = append(parts, paragraph)
+	}
+	for _, heading := range readmeConfig.Sections {
+		if section := readmeSection(lines, heading); section != "" {
+			parts = append(parts, section)
+		}
+	}
+
+	extracted := strings.Join(parts, "\n\n")
+	if extracted == "" {
+		// Not structured like a Markdown README; use it from the top
+		extracted = strings.TrimSpace(readme)
+	}
+
+	return LimitLines(extracted, readmeConfig.MaxLines)
+}
+
+// fromAsciiDoc rewrites AsciiDoc section titles as Markdown headings, and drops
+// attribute entries, block attributes, and images, which aren't prose
+func fromAsciiDoc(lines []string) []string {
+	var converted []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if asciidocAttribute.MatchString(trimmed) || strings.HasPrefix(trimmed, "image:") ||
+			(strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]")) {
+			continue
+		}
+		if m := asciidocHeading.FindStringSubmatch(trimmed); m != nil {
+			line = strings.Repeat("#", len(m[1])) + " " + m[2]
+		}
+		converted = append(converted, line)
+	}
+	return converted
+}
+
+// fromRST rewrites reStructuredText section titles as Markdown headings, and
+// drops directives, such as badge images, and comments. As in RST, a title's
+// level is given by the order in which its adornment style first appears.
+func fromRST(lines []string) []string {
+	var styles []string
+	level := func(style string) int {
+		i := slices.Index(styles, style)
+		if i < 0 {
+			styles = append(styles, style)
+			i = len(styles) - 1
+		}
+		return min(i+1, 6)
+	}
+
+	var converted []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		switch {
+		case line == ".." || strings.HasPrefix(line, ".. "):
+			// Skip the directive and its indented options and content
+			for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || strings.TrimLeft(lines[i+1], " \t") != lines[i+1]) {
+				i++
+			}
+			converted = append(converted, "")
+		case isRSTAdornment(line) && i+2 < len(lines) && strings.TrimRight(lines[i+2], " \t") == line && strings.TrimSpace(lines[i+1]) != "":
+			// Title with an overline and an underline
+			converted = append(converted, strings.Repeat("#", level("over"+line[:1]))+" "+strings.TrimSpace(lines[i+1]))
+			i += 2
+		case line != "" && !isRSTAdornment(line) && i+1 < len(lines) && isRSTAdornment(strings.TrimRight(lines[i+1], " \t")) &&
+			len(strings.TrimRight(lines[i+1], " \t")) >= len(strings.TrimSpace(line)):
+			// Title with an underline
+			converted = append(converted, strings.Repeat("#", level(lines[i+1][:1]))+" "+strings.TrimSpace(line))
+			i++
+		default:
+			converted = append(converted, line)
+		}
+	}
+	return converted
+}
+
+// rstAdornmentChars are the characters RST title underlines and overlines may be made of
+const rstAdornmentChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// isRSTAdornment reports whether line is an RST title's underline or overline:
+// one of rstAdornmentChars repeated at least twice
+func isRSTAdornment(line string) bool {
+	if len(line) < 2 || !strings.ContainsRune(rstAdornmentChars, rune(line[0])) {
+		return false
+	}
+	return strings.Trim(line, line[:1]) == ""
+}
+
+// firstParagraph returns the first paragraph of prose, skipping the title, badges, and HTML
+func firstParagraph(lines []string) string {
+	var paragraph []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(paragraph) > 0 {
+			if trimmed == "" || markdownHeading.MatchString(trimmed) {
+				break
+			}
+			paragraph = append(paragraph, trimmed)
+			continue
+		}
+
+		if trimmed == "" || markdownHeading.MatchString(trimmed) || isReadmeDecoration(trimmed) {
+			continue
+		}
+		paragraph = append(paragraph, trimmed)
+	}
+	return strings.Join(paragraph, "\n")
+}
+
+// isReadmeDecoration reports whether line is a badge, image, HTML, or heading underline
+func isReadmeDecoration(line string) bool {
+	return strings.HasPrefix(line, "[![") || strings.HasPrefix(line, "![") ||
+		strings.HasPrefix(line, "<") || strings.Trim(line, "=-") == ""
+}
+
+// readmeSection returns the section with the given heading, including any subsections
+func readmeSection(lines []string, heading string) string {
+	var section []string
+	level := 0
+	for _, line := range lines {
+		m := markdownHeading.FindStringSubmatch(strings.TrimSpace(line))
+		if level > 0 {
+			if m != nil && len(m[1]) <= level {
+				break
+			}
+			section = append(section, line)
+			continue
+		}
+		if m != nil && strings.EqualFold(m[2], heading) {
+			level = len(m[1])
+			section = append(section, strings.TrimSpace(line))
+		}
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
+
+// LimitLines cuts text down to maxLines lines; 0 means no limit
+func LimitLines(text string, maxLines int) string {
+	lines := strings.Split(text, "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return text
+	}
+	return strings.Join(lines[:maxLines], "\n") + "\n... (truncated)"
+}
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildReleasePRPrompt creates the prompt for the Markdown body of a release
+// pull request, from the commits in the release and any exported declarations
+// their changes remove or change
+func BuildReleasePRPrompt(commits string, breaking []string, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	system.WriteString("Write the body of a pull request that releases the following commits, for the people who will review and ship the release and the users who will upgrade.\n\n")
+	system.WriteString("FORMAT (Markdown):\n")
+	system.WriteString("## Highlights\n")
+	system.WriteString("The most important user-facing changes as a bulleted list, most significant first. Group related commits into one bullet and leave out internal changes such as refactoring, CI, and dependency bumps unless they matter to users.\n\n")
+	system.WriteString("## Breaking changes\n")
+	system.WriteString("A bulleted list of changes that can break existing users: commits marked with ! or a BREAKING CHANGE footer, and changed or removed APIs. Write \"None.\" if there are none.\n\n")
+	system.WriteString("## Upgrade notes\n")
+	system.WriteString("What users must do to upgrade, such as configuration changes, migrations, or replacements for removed APIs, as a bulleted list. Write \"None.\" if nothing is needed.\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- Use exactly these three sections, in this order, with no title above them\n")
+	system.WriteString("- Describe changes in plain language, not as a list of commit subjects; cite short commit hashes in parentheses where useful\n")
+	system.WriteString("- Don't invent changes, motivation, or upgrade steps that the commits don't show\n")
+	system.WriteString("- Output ONLY the pull request body, with no preamble or commentary")
+	if extra := strings.TrimSpace(project.ExtraInstructions); extra != "" {
+		system.WriteString("\n\nADDITIONAL INSTRUCTIONS:\n" + extra)
+	}
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	if len(breaking) > 0 {
+		user.WriteString("CHANGED OR REMOVED EXPORTED DECLARATIONS:\n")
+		for _, b := range breaking {
+			user.WriteString("- " + b + "\n")
+		}
+		user.WriteString("\n")
+	}
+	user.WriteString("COMMITS:\n")
+	user.WriteString(commits)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
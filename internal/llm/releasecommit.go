@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// versionField matches a line setting a version, as in `"version": "1.2.3"`,
+// `version = "1.2.3"`, `const Version = "v1.2.3"`, or `<version>1.2.3</version>`
+var versionField = regexp.MustCompile(`(?i)version[^0-9\n]{0,20}?\bv?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)\b`)
+
+// bareVersion matches a line that is only a version, as in a VERSION file
+var bareVersion = regexp.MustCompile(`^v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)$`)
+
+// changelogNames are the prefixes of changelog file names, in upper case
+var changelogNames = []string{"CHANGELOG", "CHANGES", "HISTORY", "NEWS", "RELEASES", "RELEASE-NOTES", "RELEASE_NOTES"}
+
+// lockfileNames are lockfiles, which record the project's own version as well
+// as its dependencies'
+var lockfileNames = []string{"package-lock.json", "Cargo.lock", "yarn.lock", "pnpm-lock.yaml",
+	"poetry.lock", "uv.lock", "Gemfile.lock", "composer.lock"}
+
+// DetectRelease returns the version a release commit's changes bump the
+// project to, as in "v1.2.3", or "" if the changes do anything else. A release
+// commit only changes version numbers, in version files and manifests such as
+// package.json, and may update the changelog and lockfiles alongside them.
+func DetectRelease(diff string) string {
+	_, files := parseDiff(diff)
+
+	version := ""
+	for _, f := range files {
+		base := path.Base(f.path)
+		if isChangelog(base) || containsFold(lockfileNames, base) {
+			continue
+		}
+		for _, h := range f.hunks {
+			for _, line := range h.lines {
+				content, added, ok := changedContent(line)
+				content = strings.TrimSpace(content)
+				if !ok || content == "" {
+					continue
+				}
+				m := bareVersion.FindStringSubmatch(content)
+				if m == nil {
+					m = versionField.FindStringSubmatch(content)
+				}
+				if m == nil {
+					// Something other than a version changed
+					return ""
+				}
+				if !added {
+					continue
+				}
+				if version != "" && version != m[1] {
+					// Several versions, such as a dependency's; not a clear release
+					return ""
+				}
+				version = m[1]
+			}
+		}
+	}
+	if version == "" {
+		return ""
+	}
+	return "v" + version
+}
+
+// releaseCommitConfig returns commitConfig with the header of a release
+// commit for version, unless the user chose the type, scope, or subject
+func releaseCommitConfig(version string, commitConfig config.CommitConfig) config.CommitConfig {
+	if version == "" || commitConfig.Type != "" || commitConfig.Scope != "" || commitConfig.Subject != "" {
+		return commitConfig
+	}
+	commitConfig.Type, commitConfig.Scope = "chore", "release"
+	commitConfig.Subject = Header{Type: "chore", Scope: "release", Subject: version}.String()
+	return commitConfig
+}
+
+// isChangelog reports whether a file name is a changelog's, such as CHANGELOG.md
+func isChangelog(base string) bool {
+	upper := strings.ToUpper(base)
+	for _, name := range changelogNames {
+		if strings.HasPrefix(upper, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
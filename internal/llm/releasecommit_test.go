@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"testing"
+)
+
+func TestDetectRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "package.json",
+			diff: "diff --git a/package.json b/package.json\n@@ -1,3 +1,3 @@\n {\n-  \"version\": \"1.1.0\",\n+  \"version\": \"1.2.0\",",
+			want: "v1.2.0",
+		},
+		{
+			name: "VERSION file and changelog",
+			diff: "diff --git a/VERSION b/VERSION\n@@ -1 +1 @@\n-v1.1.0\n+v1.2.0\n" +
+				"diff --git a/CHANGELOG.md b/CHANGELOG.md\n@@ -1,2 +1,5 @@\n # Changelog\n+\n+## 1.2.0\n+- Faster parsing",
+			want: "v1.2.0",
+		},
+		{
+			name: "annotated diff",
+			diff: "diff --git a/Cargo.toml b/Cargo.toml\n@@ -1,3 +1,3 @@\nREMOVED: version = \"0.3.1\"\nADDED: version = \"0.4.0-rc.1\"",
+			want: "v0.4.0-rc.1",
+		},
+		{
+			name: "other changes",
+			diff: "diff --git a/package.json b/package.json\n@@ -1,3 +1,4 @@\n-  \"version\": \"1.1.0\",\n+  \"version\": \"1.2.0\",\n+  \"private\": true,",
+		},
+		{
+			name: "dependency bump",
+			diff: "diff --git a/package.json b/package.json\n@@ -1,3 +1,3 @@\n-    \"left-pad\": \"1.1.0\",\n+    \"left-pad\": \"1.2.0\",",
+		},
+		{
+			name: "several versions",
+			diff: "diff --git a/a/VERSION b/a/VERSION\n@@ -1 +1 @@\n-1.0.0\n+1.1.0\n" +
+				"diff --git a/b/VERSION b/b/VERSION\n@@ -1 +1 @@\n-2.0.0\n+2.1.0",
+		},
+		{
+			name: "changelog only",
+			diff: "diff --git a/CHANGELOG.md b/CHANGELOG.md\n@@ -1 +1,2 @@\n # Changelog\n+- Faster parsing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectRelease(tt.diff); got != tt.want {
+				t.Errorf("DetectRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseCommitConfig(t *testing.T) {
+	commitConfig := releaseCommitConfig("v1.2.0", testCommitConfig())
+	if commitConfig.Subject != "chore(release): v1.2.0" {
+		t.Errorf("releaseCommitConfig() subject = %q, want %q", commitConfig.Subject, "chore(release): v1.2.0")
+	}
+
+	chosen := testCommitConfig()
+	chosen.Type = "build"
+	if got := releaseCommitConfig("v1.2.0", chosen); got.Subject != "" || got.Type != "build" {
+		t.Errorf("releaseCommitConfig() overrode the chosen type: %+v", got)
+	}
+	if got := releaseCommitConfig("", testCommitConfig()); got.Subject != "" {
+		t.Errorf("releaseCommitConfig() without a release set the subject %q", got.Subject)
+	}
+}
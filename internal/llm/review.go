@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"strings"
+)
+
+// NoIssues is the review a model gives when it finds nothing to flag
+const NoIssues = "No issues found."
+
+// BuildReviewPrompt creates the prompt for a short review of staged changes
+// before they are committed
+func BuildReviewPrompt(diff string, project ProjectContext) Prompt {
+	var system strings.Builder
+	if prefix := strings.TrimSpace(project.SystemPrefix); prefix != "" {
+		system.WriteString(prefix + "\n\n")
+	}
+	system.WriteString("Review the following diff of changes that are about to be committed, and flag problems the author would want to fix first.\n\n")
+	system.WriteString("LOOK FOR:\n")
+	system.WriteString("- Obvious bugs, such as inverted conditions, off-by-one errors, unhandled errors, and nil or null dereferences\n")
+	system.WriteString("- Leftover debugging code, such as print statements, commented-out code, and hardcoded test values\n")
+	system.WriteString("- TODO and FIXME comments the changes add\n")
+	system.WriteString("- Changed behavior without matching test changes, where the project has tests\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- List each problem on its own line as: path:line: description\n")
+	system.WriteString("- Take line numbers from the hunk headers (@@ -old +new @@), counting lines in the new version of the file\n")
+	system.WriteString("- Only flag real problems visible in the diff; don't comment on style, and don't praise the changes\n")
+	system.WriteString("- Most important problems first, at most 10\n")
+	system.WriteString("- If there is nothing to flag, output exactly: " + NoIssues + "\n")
+	system.WriteString("- Output ONLY the list, with no preamble or commentary")
+	if extra := strings.TrimSpace(project.ExtraInstructions); extra != "" {
+		system.WriteString("\n\nADDITIONAL INSTRUCTIONS:\n" + extra)
+	}
+
+	var user strings.Builder
+	writeProjectContext(&user, project)
+	user.WriteString("DIFF:\n")
+	user.WriteString(diff)
+
+	return Prompt{System: system.String(), User: user.String()}
+}
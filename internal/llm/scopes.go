@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// maxLearnedScopes limits how many scopes learned from history are offered,
+// keeping the most used
+const maxLearnedScopes = 30
+
+// LearnScopes returns the scopes the conventional commit subjects use, most
+// used first. Scopes used only once are left out as likely typos or one-offs,
+// and it returns nil unless at least three scopes are established, so a
+// history that rarely uses scopes doesn't restrict them.
+func LearnScopes(subjects []string) []string {
+	counts := map[string]int{}
+	spelling := map[string]string{} // The first spelling seen of each scope, by its lowercase form
+	for _, subject := range subjects {
+		h, ok := ParseHeader(subject)
+		if !ok || h.Scope == "" || config.ValidateScope(h.Scope) != nil {
+			continue
+		}
+		key := strings.ToLower(h.Scope)
+		if _, ok := spelling[key]; !ok {
+			spelling[key] = h.Scope
+		}
+		counts[key]++
+	}
+
+	var scopes []string
+	for key, n := range counts {
+		if n >= 2 {
+			scopes = append(scopes, key)
+		}
+	}
+	if len(scopes) < 3 {
+		return nil
+	}
+	slices.SortFunc(scopes, func(a, b string) int {
+		return cmp.Or(counts[b]-counts[a], strings.Compare(a, b))
+	})
+	scopes = scopes[:min(len(scopes), maxLearnedScopes)]
+	for i, key := range scopes {
+		scopes[i] = spelling[key]
+	}
+	return scopes
+}
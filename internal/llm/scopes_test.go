@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLearnScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		subjects []string
+		want     []string
+	}{
+		{
+			name: "most used first",
+			subjects: []string{
+				"feat(cli): add flag", "fix(api): check input", "fix(CLI): parse flag",
+				"docs(readme): fix typo", "feat(api): add route", "fix(api): close body",
+				"docs(readme): add example", "chore(typo): once", "fix: no scope", "Merge branch 'main'",
+			},
+			want: []string{"api", "cli", "readme"},
+		},
+		{
+			name: "too few established scopes",
+			subjects: []string{
+				"feat(cli): add flag", "fix(cli): parse flag", "fix(api): check input", "feat(api): add route",
+			},
+		},
+		{
+			name: "invalid scopes ignored",
+			subjects: []string{
+				"feat(a b): x", "feat(a b): y", "feat(a b): z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LearnScopes(tt.subjects); !slices.Equal(got, tt.want) {
+				t.Errorf("LearnScopes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
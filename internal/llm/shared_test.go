@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseTwoStage(t *testing.T) {
+	small := "+word"
+	large := strings.Repeat("+word ", 2000)
+
+	tests := []struct {
+		name     string
+		diff     string
+		twoStage string
+		want     bool
+	}{
+		{"auto with a small diff", small, "auto", false},
+		{"auto with a large diff", large, "auto", true},
+		{"always", small, "always", true},
+		{"never", large, "never", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			commitConfig.DiffTokenLimit = 2000
+			commitConfig.TwoStage = tt.twoStage
+			if got := UseTwoStage(tt.diff, commitConfig); got != tt.want {
+				t.Errorf("UseTwoStage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDiffTooLargeThreshold(t *testing.T) {
+	commitConfig := testCommitConfig()
+	commitConfig.DiffTokenLimit = 16384
+	diff := strings.Repeat("+word ", 1000)
+
+	if IsDiffTooLarge(diff, commitConfig) {
+		t.Errorf("IsDiffTooLarge() = true with the default threshold, half of diff_token_limit")
+	}
+	commitConfig.LargeDiffThreshold = 500
+	if !IsDiffTooLarge(diff, commitConfig) {
+		t.Errorf("IsDiffTooLarge() = false with large_diff_threshold below the diff's size")
+	}
+}
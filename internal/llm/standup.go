@@ -0,0 +1,21 @@
+package llm
+
+import (
+	"strings"
+)
+
+// BuildStandupPrompt creates the prompt for a standup update from the
+// commits someone made over period (e.g. "since Friday")
+func BuildStandupPrompt(commits, period string) Prompt {
+	var system strings.Builder
+	system.WriteString("Write a short standup update from the following commits, which the author made " + period + ".\n\n")
+	system.WriteString("REQUIREMENTS:\n")
+	system.WriteString("- A bulleted list of what was done, starting each bullet with \"- \"\n")
+	system.WriteString("- One bullet per piece of work, not per commit: combine commits that belong together\n")
+	system.WriteString("- Describe outcomes in plain language a teammate would follow, not file names or commit types\n")
+	system.WriteString("- At most 6 bullets, most significant work first\n")
+	system.WriteString("- Don't invent plans, blockers, or work the commits don't show\n")
+	system.WriteString("- Output ONLY the list, with no heading, preamble, or commentary")
+
+	return Prompt{System: system.String(), User: "COMMITS:\n" + commits}
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sentenceEnd matches the end of a sentence followed by the start of the next
+var sentenceEnd = regexp.MustCompile(`([.!?])\s+([A-Z])`)
+
+// StyleBody rewrites the body paragraphs of a commit message in the given
+// body_style: "bullets" turns prose into a list, "paragraph" turns lists into
+// prose, and "none" leaves the body as it is
+func StyleBody(message, style string) string {
+	if style != "bullets" && style != "paragraph" {
+		return message
+	}
+	return mapBodyParagraphs(message, func(paragraph string) string {
+		return styleParagraph(paragraph, style)
+	})
+}
+
+// styleText applies StyleBody's rewriting to every paragraph of text
+func styleText(text, style string) string {
+	if style != "bullets" && style != "paragraph" {
+		return text
+	}
+	paragraphs := strings.Split(text, "\n\n")
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = styleParagraph(paragraph, style)
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func styleParagraph(paragraph, style string) string {
+	lines := strings.Split(strings.Trim(paragraph, "\n"), "\n")
+
+	if listIndent(lines[0]) == "" {
+		// Prose; anything indented is likely code, which is left alone
+		for _, line := range lines {
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				return paragraph
+			}
+		}
+		if style != "bullets" {
+			return paragraph
+		}
+
+		var bullets []string
+		prose := strings.Join(lines, " ")
+		for _, s := range strings.Split(sentenceEnd.ReplaceAllString(prose, "$1\n$2"), "\n") {
+			bullets = append(bullets, "- "+strings.TrimSpace(s))
+		}
+		return strings.Join(bullets, "\n")
+	}
+
+	// A list; lines without a marker continue the previous item
+	var items []string
+	for _, line := range lines {
+		if listIndent(line) != "" {
+			items = append(items, stripListMarker(line))
+		} else {
+			items[len(items)-1] += " " + strings.TrimSpace(line)
+		}
+	}
+
+	if style == "paragraph" {
+		for i, item := range items {
+			items[i] = sentence(item)
+		}
+		return strings.Join(items, " ")
+	}
+
+	// Normalize the list marker
+	for i, item := range items {
+		items[i] = "- " + item
+	}
+	return strings.Join(items, "\n")
+}
+
+// stripListMarker removes a leading list marker ("- ", "* ", "+ ", "1. ") from line
+func stripListMarker(line string) string {
+	return strings.TrimSpace(line[len(listIndent(line)):])
+}
+
+// sentence capitalizes text and ends it with a period, unless it already ends in punctuation
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return text
+	}
+	text = strings.ToUpper(text[:1]) + text[1:]
+	if !strings.ContainsAny(text[len(text)-1:], ".!?:") {
+		text += "."
+	}
+	return text
+}
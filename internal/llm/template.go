@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// templateFile is what the template says about one changed file
+type templateFile struct {
+	path           string
+	status         string // "new", "deleted", "renamed", or "" for a modified file
+	added, removed int
+	category       int
+	build, ci      bool // Build files or dependencies, or CI configuration
+}
+
+// TemplateMessage builds a commit message from the diff without a model, for
+// when the provider can't be reached: a header with a guessed type and scope
+// and a generic subject, and a body listing the changed files. It's a starting
+// point for the user to edit, not a finished message. A release commit, or a
+// message with a subject from --subject, gets that subject instead.
+func TemplateMessage(diff string, commitConfig config.CommitConfig) string {
+	commitConfig = releaseCommitConfig(DetectRelease(diff), commitConfig)
+	_, parsed := parseDiff(diff)
+	if len(parsed) == 0 {
+		return ""
+	}
+
+	files := make([]templateFile, 0, len(parsed))
+	for _, f := range parsed {
+		files = append(files, summarizeTemplateFile(f))
+	}
+
+	header := commitConfig.Subject
+	if header == "" {
+		header = enforceHeaderFields(Header{
+			Type:    templateType(files, commitConfig.Types),
+			Scope:   templateScope(files, commitConfig.Scopes),
+			Subject: templateSubject(files),
+		}, commitConfig).String()
+	}
+
+	var b strings.Builder
+	b.WriteString(header + "\n\n")
+	for _, f := range files {
+		b.WriteString("- " + f.path)
+		var notes []string
+		if f.status != "" {
+			notes = append(notes, f.status)
+		}
+		if f.added > 0 {
+			notes = append(notes, fmt.Sprintf("+%d", f.added))
+		}
+		if f.removed > 0 {
+			notes = append(notes, fmt.Sprintf("-%d", f.removed))
+		}
+		if len(notes) > 0 {
+			b.WriteString(" (" + strings.Join(notes, ", ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// summarizeTemplateFile counts a file's changed lines and classifies it
+func summarizeTemplateFile(f *diffFile) templateFile {
+	t := templateFile{path: f.path, category: fileCategory(f.path)}
+	for _, line := range f.header {
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			t.status = "new"
+		case strings.HasPrefix(line, "deleted file mode"):
+			t.status = "deleted"
+		case strings.HasPrefix(line, "rename from"):
+			t.status = "renamed"
+		}
+	}
+	for _, h := range f.hunks {
+		for _, line := range h.lines {
+			switch {
+			case strings.HasPrefix(line, "ADDED:"), strings.HasPrefix(line, "+"):
+				t.added++
+			case strings.HasPrefix(line, "REMOVED:"), strings.HasPrefix(line, "-"):
+				t.removed++
+			}
+		}
+	}
+
+	base := path.Base(f.path)
+	switch base {
+	case "go.mod", "go.sum", "package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+		"Cargo.toml", "Cargo.lock", "pyproject.toml", "poetry.lock", "uv.lock", "Gemfile", "Gemfile.lock",
+		"Makefile", "Dockerfile", "CMakeLists.txt", "build.gradle", "pom.xml":
+		t.build = true
+	}
+	t.ci = strings.HasPrefix(f.path, ".github/workflows/") || strings.HasPrefix(f.path, ".circleci/") ||
+		base == ".gitlab-ci.yml" || base == ".travis.yml" || base == "Jenkinsfile"
+	return t
+}
+
+// templateType guesses the commit type from which kinds of files changed,
+// using only types the team allows
+func templateType(files []templateFile, types []config.CommitType) string {
+	all := func(match func(templateFile) bool) bool {
+		return !slices.ContainsFunc(files, func(f templateFile) bool { return !match(f) })
+	}
+
+	var guesses []string
+	switch {
+	case all(func(f templateFile) bool { return f.ci }):
+		guesses = []string{"ci", "build", "chore"}
+	case all(func(f templateFile) bool { return f.build }):
+		guesses = []string{"build", "chore"}
+	case all(func(f templateFile) bool { return f.category == categoryDocs }):
+		guesses = []string{"docs", "chore"}
+	case all(func(f templateFile) bool { return f.category == categoryTests }):
+		guesses = []string{"test", "chore"}
+	case slices.ContainsFunc(files, func(f templateFile) bool { return f.status == "new" && f.category == categorySource }):
+		guesses = []string{"feat", "chore"}
+	default:
+		guesses = []string{"fix", "chore"}
+	}
+
+	for _, guess := range guesses {
+		for _, t := range types {
+			if strings.EqualFold(t.Name, guess) {
+				return t.Name
+			}
+		}
+	}
+	if len(types) > 0 {
+		return types[0].Name
+	}
+	return guesses[0]
+}
+
+// templateScope uses the directory the files have in common as the scope, if
+// the team uses scopes and allows it
+func templateScope(files []templateFile, scopes []string) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+	dir := path.Dir(files[0].path)
+	for _, f := range files[1:] {
+		for dir != "." && !strings.HasPrefix(f.path, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	for ; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if scope := allowedScope(path.Base(dir), scopes); scope != "" {
+			return scope
+		}
+	}
+	return ""
+}
+
+// templateSubject describes the change by what happened to which files, for
+// the user to replace with what the change does
+func templateSubject(files []templateFile) string {
+	verb := "update"
+	statuses := map[string]bool{}
+	for _, f := range files {
+		statuses[f.status] = true
+	}
+	if len(statuses) == 1 {
+		switch files[0].status {
+		case "new":
+			verb = "add"
+		case "deleted":
+			verb = "remove"
+		case "renamed":
+			verb = "rename"
+		}
+	}
+
+	if len(files) == 1 {
+		return verb + " " + path.Base(files[0].path)
+	}
+	return fmt.Sprintf("%s %d files", verb, len(files))
+}
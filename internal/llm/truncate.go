@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// File categories, from least to most important when the diff must be cut down
+const (
+	categoryGenerated = iota
+	categoryDocs
+	categoryTests
+	categorySource
+)
+
+type diffFile struct {
+	path    string
+	oldBlob string
+	newBlob string
+	header  []string
+	hunks   []*diffHunk
+}
+
+// FileDiff is the part of a diff that touches a single file
+type FileDiff struct {
+	Path    string
+	OldBlob string // Blob IDs from the diff's index line; empty if not present
+	NewBlob string
+	Diff    string
+	Changed bool // Whether the diff has any content changes (hunks), not just a header
+}
+
+// SplitDiff splits a diff into per-file parts
+func SplitDiff(diff string) []FileDiff {
+	_, files := parseDiff(diff)
+
+	parts := make([]FileDiff, 0, len(files))
+	for _, f := range files {
+		parts = append(parts, FileDiff{
+			Path:    f.path,
+			OldBlob: f.oldBlob,
+			NewBlob: f.newBlob,
+			Diff:    renderDiff(nil, []*diffFile{f}),
+			Changed: hasChangedLines(f),
+		})
+	}
+	return parts
+}
+
+type diffHunk struct {
+	file     *diffFile
+	index    int // Position in the diff, used to keep the ordering stable
+	header   string
+	lines    []string
+	added    int
+	dropped  bool
+	stripped bool
+}
+
+// EstimateTokens roughly approximates the number of tokens in text (1 word ≈ 1.3 tokens)
+func EstimateTokens(text string) int {
+	return int(float64(len(strings.Fields(text))) * 1.3)
+}
+
+// FitDiff shrinks a diff to fit within tokenLimit by removing its least important
+// content first: context lines before changed lines, and generated files, docs, and
+// tests before source code. It returns the diff and the number of hunks omitted.
+func FitDiff(diff string, tokenLimit int) (string, int) {
+	if tokenLimit <= 0 || EstimateTokens(diff) <= tokenLimit {
+		return diff, 0
+	}
+
+	preamble, files := parseDiff(diff)
+
+	var hunks []*diffHunk
+	for _, f := range files {
+		hunks = append(hunks, f.hunks...)
+	}
+
+	// Least important first: by file category, then fewest additions, then latest in the diff
+	sort.SliceStable(hunks, func(i, j int) bool {
+		ci, cj := fileCategory(hunks[i].file.path), fileCategory(hunks[j].file.path)
+		if ci != cj {
+			return ci < cj
+		}
+		if hunks[i].added != hunks[j].added {
+			return hunks[i].added < hunks[j].added
+		}
+		return hunks[i].index > hunks[j].index
+	})
+
+	// Track the estimate incrementally rather than re-rendering the diff at each step
+	total := EstimateTokens(diff)
+
+	// First pass: drop unchanged context lines, which the model needs least
+	for _, h := range hunks {
+		if total <= tokenLimit {
+			return renderDiff(preamble, files), 0
+		}
+		total -= h.tokens()
+		h.stripped = true
+		total += h.tokens()
+	}
+
+	// Second pass: drop whole hunks
+	omitted := 0
+	for _, h := range hunks {
+		if total <= tokenLimit {
+			break
+		}
+		total -= h.tokens()
+		h.dropped = true
+		omitted++
+	}
+
+	return renderDiff(preamble, files), omitted
+}
+
+// tokens estimates the size of the hunk as it would currently be rendered
+func (h *diffHunk) tokens() int {
+	if h.dropped {
+		return 0
+	}
+	words := len(strings.Fields(h.header))
+	for _, line := range h.lines {
+		if h.stripped && isContextLine(line) {
+			continue
+		}
+		words += len(strings.Fields(line))
+	}
+	return int(float64(words) * 1.3)
+}
+
+// parseDiff splits a (transformed) git diff into files and hunks
+func parseDiff(diff string) ([]string, []*diffFile) {
+	var preamble []string
+	var files []*diffFile
+	var currentFile *diffFile
+	var currentHunk *diffHunk
+	index := 0
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			currentFile = &diffFile{path: diffPath(line), header: []string{line}}
+			currentHunk = nil
+			files = append(files, currentFile)
+		case currentFile == nil:
+			preamble = append(preamble, line)
+		case strings.HasPrefix(line, "@@"):
+			currentHunk = &diffHunk{file: currentFile, index: index, header: line}
+			index++
+			currentFile.hunks = append(currentFile.hunks, currentHunk)
+		case currentHunk == nil:
+			if strings.HasPrefix(line, "index ") {
+				currentFile.oldBlob, currentFile.newBlob = indexBlobs(line)
+			}
+			currentFile.header = append(currentFile.header, line)
+		default:
+			if strings.HasPrefix(line, "ADDED:") || strings.HasPrefix(line, "+") {
+				currentHunk.added++
+			}
+			currentHunk.lines = append(currentHunk.lines, line)
+		}
+	}
+
+	return preamble, files
+}
+
+func renderDiff(preamble []string, files []*diffFile) string {
+	var b strings.Builder
+	for _, line := range preamble {
+		b.WriteString(line + "\n")
+	}
+
+	for _, f := range files {
+		for _, line := range f.header {
+			b.WriteString(line + "\n")
+		}
+
+		omitted := 0
+		for _, h := range f.hunks {
+			if h.dropped {
+				omitted++
+				continue
+			}
+			b.WriteString(h.header + "\n")
+			for _, line := range h.lines {
+				if h.stripped && isContextLine(line) {
+					continue
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+
+		if omitted > 0 {
+			b.WriteString(fmt.Sprintf("[%d of %d hunks in %s omitted to fit the token limit]\n", omitted, len(f.hunks), f.path))
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// hasChangedLines reports whether f has hunks with content, rather than none or
+// only a note such as an LFS asset description
+func hasChangedLines(f *diffFile) bool {
+	for _, h := range f.hunks {
+		if len(h.lines) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isContextLine(line string) bool {
+	return strings.HasPrefix(line, "UNCHANGED:") || strings.HasPrefix(line, " ")
+}
+
+// indexBlobs extracts the blob IDs from an "index <old>..<new> [mode]" line
+func indexBlobs(line string) (string, string) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", ""
+	}
+	oldBlob, newBlob, _ := strings.Cut(fields[1], "..")
+	return oldBlob, newBlob
+}
+
+// diffPath extracts the new path from a "diff --git a/... b/..." line
+func diffPath(line string) string {
+	if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+		return line[idx+3:]
+	}
+	return strings.TrimPrefix(line, "diff --git ")
+}
+
+// fileCategory classifies a path by how much it matters to the commit message
+func fileCategory(p string) int {
+	base := path.Base(p)
+	lower := strings.ToLower(p)
+
+	switch base {
+	case "go.sum", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "Cargo.lock",
+		"poetry.lock", "Gemfile.lock", "composer.lock", "Pipfile.lock", "uv.lock":
+		return categoryGenerated
+	}
+	if strings.HasSuffix(base, ".min.js") || strings.HasSuffix(base, ".min.css") ||
+		strings.HasSuffix(base, ".pb.go") || strings.HasSuffix(base, "_generated.go") ||
+		strings.HasSuffix(base, ".gen.go") || strings.HasSuffix(base, ".snap") {
+		return categoryGenerated
+	}
+	for _, dir := range []string{"vendor/", "node_modules/", "dist/"} {
+		if strings.HasPrefix(p, dir) || strings.Contains(p, "/"+dir) {
+			return categoryGenerated
+		}
+	}
+
+	switch strings.ToLower(path.Ext(base)) {
+	case ".md", ".rst", ".adoc", ".txt":
+		return categoryDocs
+	}
+	if strings.HasPrefix(lower, "docs/") || strings.Contains(lower, "/docs/") {
+		return categoryDocs
+	}
+
+	if strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_") {
+		return categoryTests
+	}
+	for _, dir := range []string{"test/", "tests/", "__tests__/", "testdata/"} {
+		if strings.HasPrefix(lower, dir) || strings.Contains(lower, "/"+dir) {
+			return categoryTests
+		}
+	}
+
+	return categorySource
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+const sourceDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,6 +1,7 @@
+ package main
+ 
+ import "fmt"
+ 
++// greet says hello
+ func greet() {
+ 	fmt.Println("hello")`
+
+const docsDiff = `diff --git a/README.md b/README.md
+index 3333333..4444444 100644
+--- a/README.md
++++ b/README.md
+@@ -1,2 +1,3 @@
+ # Project
++Some words about the project that take up a good part of the token budget.
+ More text here.`
+
+func TestFitDiff(t *testing.T) {
+	diff := sourceDiff + "\n" + docsDiff
+
+	t.Run("fits", func(t *testing.T) {
+		got, omitted := FitDiff(diff, 1000)
+		if got != diff || omitted != 0 {
+			t.Errorf("FitDiff() changed a diff that fits: %q, %d omitted", got, omitted)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		if got, _ := FitDiff(diff, 0); got != diff {
+			t.Errorf("FitDiff() with no limit changed the diff: %q", got)
+		}
+	})
+
+	t.Run("context lines go first", func(t *testing.T) {
+		got, omitted := FitDiff(diff, EstimateTokens(diff)-2)
+		if omitted != 0 {
+			t.Errorf("FitDiff() omitted %d hunks, want context lines removed instead", omitted)
+		}
+		if !strings.Contains(got, "+// greet says hello") || !strings.Contains(got, "+Some words") {
+			t.Errorf("FitDiff() removed changed lines:\n%s", got)
+		}
+		if strings.Contains(got, "\n More text here.") {
+			t.Errorf("FitDiff() kept the docs context, which is least important:\n%s", got)
+		}
+	})
+
+	t.Run("docs hunks dropped before source", func(t *testing.T) {
+		docsHeader, _, _ := strings.Cut(docsDiff, "@@")
+		got, omitted := FitDiff(diff, EstimateTokens(sourceDiff+"\n"+docsHeader))
+		if omitted != 1 {
+			t.Errorf("FitDiff() omitted %d hunks, want 1", omitted)
+		}
+		if !strings.Contains(got, "+// greet says hello") {
+			t.Errorf("FitDiff() dropped the source hunk:\n%s", got)
+		}
+		if strings.Contains(got, "+Some words") || !strings.Contains(got, "[1 of 1 hunks in README.md omitted to fit the token limit]") {
+			t.Errorf("FitDiff() didn't drop the docs hunk:\n%s", got)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// ValidationError reports a generated message that doesn't follow the commit convention
+type ValidationError struct {
+	Reason   string // Why the message was rejected, phrased as feedback for the model
+	Message  string // The rejected message, or the raw response if nothing was left after cleaning
+	Unusable bool   // The response was empty or had no message in it at all
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid commit message: " + e.Reason
+}
+
+// ValidateMessage checks a finished commit message against the convention:
+// a non-empty conventional header with a configured type, an allowed scope if
+// scopes are listed, and a subject that fits within max_length
+func ValidateMessage(message string, commitConfig config.CommitConfig) error {
+	invalid := func(format string, args ...any) error {
+		return &ValidationError{Reason: fmt.Sprintf(format, args...), Message: message}
+	}
+
+	// A subject line the user wrote is theirs to keep, conventional or not
+	if commitConfig.Subject != "" {
+		return nil
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if subject == "" {
+		return invalid("the message is empty")
+	}
+
+	h, ok := ParseHeader(subject)
+	if !ok {
+		return invalid("the first line must start with 'type: ' or 'type(scope): '")
+	}
+	if !commitConfig.HasType(h.Type) {
+		return invalid("'%s' is not a valid type; use one of: %s", h.Type, strings.Join(commitConfig.TypeNames(), ", "))
+	}
+	if h.Scope != "" && len(commitConfig.Scopes) > 0 && allowedScope(h.Scope, commitConfig.Scopes) == "" {
+		return invalid("'%s' is not an allowed scope; use one of: %s, or leave the scope out", h.Scope, strings.Join(commitConfig.Scopes, ", "))
+	}
+	if h.Subject == "" {
+		return invalid("the summary after '%s:' is empty", h.Type)
+	}
+
+	lowerSubject := strings.ToLower(h.Subject)
+	for _, phrase := range commitConfig.BannedPhrases {
+		if phrase != "" && strings.Contains(lowerSubject, strings.ToLower(phrase)) {
+			return invalid("the summary must not contain '%s'; say specifically what changed", phrase)
+		}
+	}
+
+	if !checkMood(h.Subject, commitConfig.Mood) {
+		return invalid("the summary must start with a verb in %s", moodExamples[commitConfig.Mood])
+	}
+
+	if commitConfig.MaxLength > 0 && textLength(subject) > commitConfig.MaxLength {
+		return invalid("the first line must be under %d characters", commitConfig.MaxLength)
+	}
+
+	return nil
+}
+
+// RetryTemperature returns the sampling temperature for an attempt. After an empty
+// or unusable response the temperature is raised with each attempt, to move the
+// model out of whatever degenerate output it got stuck in.
+func RetryTemperature(base float64, input PromptInput) float64 {
+	if input.Rejected == nil || !input.Rejected.Unusable || input.Attempt <= 1 {
+		return base
+	}
+	return min(base+0.2*float64(input.Attempt-1), 1.2)
+}
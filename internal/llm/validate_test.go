@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"git-ac/internal/config"
+)
+
+func TestValidateMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		adjust  func(*config.CommitConfig)
+		reason  string // Part of the expected reason; "" if the message is valid
+	}{
+		{
+			name:    "valid",
+			message: "feat(api): add token validation\n\nChecks the signature.",
+		},
+		{
+			name:    "empty",
+			message: "  ",
+			reason:  "the message is empty",
+		},
+		{
+			name:    "no header",
+			message: "add token validation",
+			reason:  "must start with 'type: '",
+		},
+		{
+			name:    "unknown type",
+			message: "feature: add token validation",
+			reason:  "'feature' is not a valid type",
+		},
+		{
+			name:    "empty summary",
+			message: "feat:",
+			reason:  "the summary after 'feat:' is empty",
+		},
+		{
+			name:    "allowed scope",
+			message: "feat(API): add token validation",
+			adjust:  func(c *config.CommitConfig) { c.Scopes = []string{"api", "cli"} },
+		},
+		{
+			name:    "scope outside the list",
+			message: "feat(ui): add token validation",
+			adjust:  func(c *config.CommitConfig) { c.Scopes = []string{"api", "cli"} },
+			reason:  "'ui' is not an allowed scope; use one of: api, cli",
+		},
+		{
+			name:    "no scope with a scope list",
+			message: "feat: add token validation",
+			adjust:  func(c *config.CommitConfig) { c.Scopes = []string{"api", "cli"} },
+		},
+		{
+			name:    "banned phrase",
+			message: "fix: various changes to parsing",
+			adjust:  func(c *config.CommitConfig) { c.BannedPhrases = []string{"various changes"} },
+			reason:  "must not contain 'various changes'",
+		},
+		{
+			name:    "banned phrase in the body allowed",
+			message: "fix: handle empty input\n\nAfter various changes upstream.",
+			adjust:  func(c *config.CommitConfig) { c.BannedPhrases = []string{"various changes"} },
+		},
+		{
+			name:    "wrong mood",
+			message: "fix: added token validation",
+			reason:  "imperative mood",
+		},
+		{
+			name:    "past mood",
+			message: "fix: added token validation",
+			adjust:  func(c *config.CommitConfig) { c.Mood = "past" },
+		},
+		{
+			name:    "too long",
+			message: "feat: " + strings.Repeat("x", 80),
+			reason:  "under 72 characters",
+		},
+		{
+			name:    "user's subject",
+			message: "Update stuff\n\nThe body.",
+			adjust:  func(c *config.CommitConfig) { c.Subject = "Update stuff" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitConfig := testCommitConfig()
+			if tt.adjust != nil {
+				tt.adjust(&commitConfig)
+			}
+			err := ValidateMessage(tt.message, commitConfig)
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("ValidateMessage() = %v, want nil", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("ValidateMessage() = %v, want a ValidationError", err)
+			}
+			if !strings.Contains(invalid.Reason, tt.reason) {
+				t.Errorf("ValidateMessage() reason = %q, want it to contain %q", invalid.Reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package llm
+
+import "strings"
+
+// WrapBody hard-wraps the body paragraphs of a commit message at width columns.
+// The subject paragraph and trailers are left as they are; a width of 0 disables wrapping.
+func WrapBody(message string, width int) string {
+	if width <= 0 {
+		return message
+	}
+	return mapBodyParagraphs(message, func(paragraph string) string {
+		return wrapText(paragraph, width)
+	})
+}
+
+// mapBodyParagraphs applies fn to each body paragraph of a commit message,
+// leaving the subject paragraph and trailers as they are
+func mapBodyParagraphs(message string, fn func(string) string) string {
+	paragraphs := strings.Split(message, "\n\n")
+	end := len(paragraphs)
+	if _, _, trailers := SplitMessage(message); trailers != "" {
+		end--
+	}
+	for i := 1; i < end; i++ {
+		paragraphs[i] = fn(paragraphs[i])
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// wrapText reflows each paragraph of text to width columns; list items are
+// wrapped individually so their structure is preserved, and other indented
+// lines (such as code) are left as they are
+func wrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	var out []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		var lines []string
+		var current []string
+
+		flush := func() {
+			if len(current) > 0 {
+				lines = append(lines, wrapLine(strings.Join(current, " "), width, "")...)
+				current = nil
+			}
+		}
+
+		for _, line := range strings.Split(paragraph, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if indent := listIndent(line); indent != "" {
+				flush()
+				lines = append(lines, wrapLine(strings.TrimRight(line, " "), width, indent)...)
+				continue
+			}
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				flush()
+				lines = append(lines, strings.TrimRight(line, " \t"))
+				continue
+			}
+			current = append(current, trimmed)
+		}
+		flush()
+
+		out = append(out, strings.Join(lines, "\n"))
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// listIndent returns the hanging indent for list items ("- ", "* ", "1. "), or "" for prose
+func listIndent(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	lead := len(line) - len(trimmed)
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(trimmed, marker) {
+			return strings.Repeat(" ", lead+len(marker))
+		}
+	}
+	if dot := strings.Index(trimmed, ". "); dot > 0 && dot <= 3 && strings.Trim(trimmed[:dot], "0123456789") == "" {
+		return strings.Repeat(" ", lead+dot+2)
+	}
+	return ""
+}
+
+// wrapLine wraps a single line at width, indenting continuation lines
+func wrapLine(line string, width int, indent string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil
+	}
+
+	// Keep the original leading text (e.g. list markers and their indentation) on the first line
+	first := line[:len(line)-len(strings.TrimLeft(line, " "))] + words[0]
+
+	var lines []string
+	current := first
+	for _, word := range words[1:] {
+		if textLength(current)+1+textLength(word) > width {
+			lines = append(lines, current)
+			current = indent + word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current)
+}
@@ -0,0 +1,63 @@
+// Package lock keeps git-ac runs in the same repository from racing each
+// other to stage and commit
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrHeld is returned by Acquire when another process holds the lock
+var ErrHeld = errors.New("the lock is held by another process")
+
+// Lock is a held lock on a file. The operating system releases it if the
+// process exits without releasing it, so a crash never leaves it stuck.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes the lock on the file at path, creating it if needed, without
+// waiting. If another process holds it, the error matches ErrHeld and names
+// that process, when it can be told.
+func Acquire(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		if holder := holderPID(path); holder != "" {
+			return nil, fmt.Errorf("%w (pid %s)", ErrHeld, holder)
+		}
+		return nil, ErrHeld
+	}
+
+	// Record who holds it, for the error others get
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release releases the lock. The file is left in place, since removing it
+// could let two processes lock different files under the same name.
+func (l *Lock) Release() error {
+	_ = l.f.Truncate(0)
+	return l.f.Close()
+}
+
+// holderPID returns the process ID recorded in the lock file at path, or ""
+func holderPID(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	pid := strings.TrimSpace(string(data))
+	if _, err := strconv.Atoi(pid); err != nil {
+		return ""
+	}
+	return pid
+}
@@ -0,0 +1,64 @@
+// Package pregen stores the commit messages git-ac watch generates ahead of
+// time, so git-ac can use them instead of waiting for the provider
+package pregen
+
+import (
+	"encoding/json"
+
+	"git-ac/internal/cache"
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+
+	"gopkg.in/yaml.v3"
+)
+
+// namespace is the cache namespace pre-generated messages are kept in
+const namespace = "pregenerated"
+
+// key identifies the messages for a prepared diff, under the settings that
+// shape them
+func key(diff string, cfg *config.Config) string {
+	commit := cfg.Commit
+	// How a message is delivered doesn't change it
+	commit.Copy, commit.Stream = false, false
+
+	// Command-line overrides aren't marshaled with the rest of the settings
+	settings, _ := yaml.Marshal(struct {
+		Commit    config.CommitConfig
+		Prompt    config.PromptConfig
+		Overrides []string
+	}{commit, cfg.Prompt, []string{commit.Type, commit.Scope, commit.Subject}})
+	return cache.Key(diff, cfg.Provider.Type, cfg.Model(), string(settings))
+}
+
+// Save stores the candidates generated for the prepared diff. They are only
+// kept on this machine, never in a shared cache.
+func Save(diff string, cfg *config.Config, candidates []llm.Candidate) error {
+	c, err := cache.Default()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(candidates)
+	if err != nil {
+		return err
+	}
+	return c.Put(namespace, key(diff, cfg), string(data))
+}
+
+// Load returns the candidates pre-generated for the prepared diff, if any
+func Load(diff string, cfg *config.Config) ([]llm.Candidate, bool) {
+	c, err := cache.Default()
+	if err != nil {
+		return nil, false
+	}
+	data, ok := c.Get(namespace, key(diff, cfg))
+	if !ok {
+		return nil, false
+	}
+
+	var candidates []llm.Candidate
+	if err := json.Unmarshal([]byte(data), &candidates); err != nil || len(candidates) == 0 {
+		return nil, false
+	}
+	return candidates, true
+}
@@ -0,0 +1,67 @@
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// IsInteractive reports whether stdin is a terminal that can answer prompts
+func IsInteractive() bool {
+	fileInfo, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return (fileInfo.Mode() & os.ModeCharDevice) != 0
+}
+
+// Choice asks question and returns the chosen option.
+// options are single letters; def is returned when the user just presses enter.
+func Choice(question string, options []string, def string) (string, error) {
+	labels := make([]string, len(options))
+	for i, option := range options {
+		if option == def {
+			labels[i] = strings.ToUpper(option)
+		} else {
+			labels[i] = option
+		}
+	}
+
+	for {
+		fmt.Printf("%s [%s] ", question, strings.Join(labels, "/"))
+
+		line, err := stdin.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" {
+			return def, nil
+		}
+		// Accept whole words too, e.g. "yes" for "y"
+		for _, option := range options {
+			if answer[:1] == option {
+				return option, nil
+			}
+		}
+		fmt.Printf("Please answer one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// Confirm asks a yes/no question
+func Confirm(question string, defaultYes bool) (bool, error) {
+	def := "n"
+	if defaultYes {
+		def = "y"
+	}
+	answer, err := Choice(question, []string{"y", "n"}, def)
+	if err != nil {
+		return false, err
+	}
+	return answer == "y", nil
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"context"
+
+	"git-ac/internal/config"
+)
+
+type commitConfigKey struct{}
+
+// WithCommitConfig returns a context under which commit messages follow
+// commitConfig rather than the settings the provider was created with, as a
+// gateway does with each client's settings
+func WithCommitConfig(ctx context.Context, commitConfig config.CommitConfig) context.Context {
+	return context.WithValue(ctx, commitConfigKey{}, commitConfig)
+}
+
+// commitConfigFrom returns the settings set by WithCommitConfig, or def
+func commitConfigFrom(ctx context.Context, def config.CommitConfig) config.CommitConfig {
+	if c, ok := ctx.Value(commitConfigKey{}).(config.CommitConfig); ok {
+		return c
+	}
+	return def
+}
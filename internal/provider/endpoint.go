@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// Endpoint returns the base URL the configured provider sends requests to
+func Endpoint(cfg *config.Config) string {
+	switch cfg.Provider.Type {
+	case "ollama":
+		if cfg.Provider.Ollama != nil && cfg.Provider.Ollama.Host != "" {
+			return cfg.Provider.Ollama.Host
+		}
+		return "http://localhost:11434"
+	case "openai":
+		if cfg.Provider.OpenAI != nil {
+			return cfg.Provider.OpenAI.BaseURL
+		}
+	case "gateway":
+		if cfg.Provider.Gateway != nil {
+			return cfg.Provider.Gateway.URL
+		}
+	}
+	return ""
+}
+
+// EndpointHost returns the host (without port) of an endpoint URL
+func EndpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		// Bare "host:port" values don't parse as URLs with a host
+		u, err = url.Parse("http://" + endpoint)
+		if err != nil {
+			return ""
+		}
+	}
+	return u.Hostname()
+}
+
+// IsLocalEndpoint reports whether endpoint is on this machine, so diffs sent
+// to it never leave it
+func IsLocalEndpoint(endpoint string) bool {
+	if UnixSocket(endpoint) != "" {
+		return true
+	}
+	host := EndpointHost(endpoint)
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
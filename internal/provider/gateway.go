@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
+	"git-ac/internal/llm"
+)
+
+// GatewayProvider generates messages through a team's git-ac gateway (git-ac
+// serve --auth), which holds the provider credentials. Messages follow this
+// machine's commit settings, except those that suit the gateway's model, which
+// the gateway keeps.
+type GatewayProvider struct {
+	config       *config.GatewayConfig
+	timeout      time.Duration
+	client       *http.Client
+	debugLog     *debuglog.Logger
+	maxRetries   int                 // Retries of generation requests that fail to reach the gateway
+	commitConfig config.CommitConfig // Settings, including --type, --scope, --subject, and --no-body, sent with each request
+	usageCounter
+}
+
+// gatewayHealth is the gateway's answer to GET /health
+type gatewayHealth struct {
+	Status   string `json:"status"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Error    string `json:"error"`
+}
+
+// gatewayGenerateRequest is the body of a POST to /generate
+type gatewayGenerateRequest struct {
+	Diff    string               `json:"diff"`
+	Project *llm.ProjectContext  `json:"project"`
+	Commit  *config.CommitConfig `json:"commit"`
+}
+
+// gatewayGenerateResponse is the gateway's answer to POST /generate
+type gatewayGenerateResponse struct {
+	Candidates []llm.Candidate `json:"candidates"`
+	Commit     bool            `json:"commit"` // The request's commit settings were followed; older gateways leave this out
+}
+
+// gatewayTextResponse is the gateway's answer to POST /text
+type gatewayTextResponse struct {
+	Text string `json:"text"`
+}
+
+// gatewayError is the body of a failed gateway request
+type gatewayError struct {
+	Error string `json:"error"`
+}
+
+func NewGatewayProvider(cfg *config.GatewayConfig, timeout time.Duration, commitCfg config.CommitConfig, debugLog *debuglog.Logger) *GatewayProvider {
+	return &GatewayProvider{
+		config:       cfg,
+		timeout:      timeout,
+		client:       newHTTPClient(),
+		debugLog:     debugLog,
+		commitConfig: commitCfg,
+	}
+}
+
+func (p *GatewayProvider) HealthCheck(ctx context.Context) error {
+	health, err := p.health(ctx)
+	if err != nil {
+		return err
+	}
+	if health.Status != "ok" {
+		return fmt.Errorf("the gateway's provider is unavailable: %s", health.Error)
+	}
+	return nil
+}
+
+// ListModels returns the one model the gateway uses
+func (p *GatewayProvider) ListModels(ctx context.Context) ([]string, error) {
+	health, err := p.health(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{health.Model}, nil
+}
+
+func (p *GatewayProvider) health(ctx context.Context) (*gatewayHealth, error) {
+	var health gatewayHealth
+	// An unhealthy provider is reported with status 503 and a health body
+	if err := p.do(ctx, "GET", "/health", nil, &health); err != nil && health.Status == "" {
+		return nil, err
+	}
+	return &health, nil
+}
+
+func (p *GatewayProvider) GenerateCommitMessage(ctx context.Context, diff string, project llm.ProjectContext) (string, error) {
+	return bestCandidate(p.GenerateCandidates(ctx, diff, project))
+}
+
+func (p *GatewayProvider) GenerateCandidates(ctx context.Context, diff string, project llm.ProjectContext) ([]llm.Candidate, error) {
+	var resp gatewayGenerateResponse
+	commitConfig := commitConfigFrom(ctx, p.commitConfig)
+	err := withRetries(ctx, p.maxRetries, func() error {
+		return p.do(ctx, "POST", "/generate", gatewayGenerateRequest{Diff: diff, Project: &project, Commit: &commitConfig}, &resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Commit {
+		return nil, fmt.Errorf("the gateway at %s ignores commit settings such as --type, --scope, --subject, and --no-body - ask its operator to upgrade git-ac", p.config.URL)
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("%w from the gateway", errEmptyResponse)
+	}
+	return resp.Candidates, nil
+}
+
+func (p *GatewayProvider) GenerateText(ctx context.Context, prompt llm.Prompt) (string, error) {
+	var resp gatewayTextResponse
+	err := withRetries(ctx, p.maxRetries, func() error {
+		return p.do(ctx, "POST", "/text", prompt, &resp)
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// WarmUp does nothing: the gateway keeps its own model loaded
+func (p *GatewayProvider) WarmUp(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// do sends a request to the gateway and decodes its JSON response into out
+func (p *GatewayProvider) do(ctx context.Context, method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(p.config.URL, "/")+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.config.Token)
+
+	p.debugLog.Request("gateway "+path, body)
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		p.debugLog.Error("gateway "+path, err)
+		if strings.Contains(err.Error(), "context deadline exceeded") {
+			return timedOut(fmt.Errorf("request timed out after %v - try increasing timeout in config", p.timeout))
+		}
+		return unreachable(fmt.Errorf("cannot connect to the gateway at %s: %w", p.config.URL, err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return unreachable(fmt.Errorf("failed to read the gateway's response: %w", err))
+	}
+	p.debugLog.Response("gateway "+path, string(data))
+
+	if resp.StatusCode != http.StatusOK {
+		var gwErr gatewayError
+		_ = json.Unmarshal(data, &gwErr)
+		_ = json.Unmarshal(data, out)
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("authentication failed (401) - check the gateway token")
+		case resp.StatusCode >= 500:
+			return unreachable(fmt.Errorf("gateway error (%d): %s", resp.StatusCode, gwErr.Error))
+		case gwErr.Error != "":
+			return fmt.Errorf("gateway: %s", gwErr.Error)
+		default:
+			return fmt.Errorf("gateway request failed with status %d", resp.StatusCode)
+		}
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
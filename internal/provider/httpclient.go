@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sharedTransport carries every provider's requests, so connections stay open
+// between requests, such as the two stages of two-stage mode and the requests
+// git-ac serve answers, rather than being set up again for each one
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   10,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// unixTransports holds a transport per unix socket, shared the same way
+var (
+	unixTransportsMu sync.Mutex
+	unixTransports   = make(map[string]*http.Transport)
+)
+
+// newHTTPClient returns a client on the shared transport. It has no timeout of
+// its own, which would also cut off streamed responses; each request is given
+// one through its context instead.
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: sharedTransport}
+}
+
+// UnixSocket returns the socket path of a unix:///path/to/socket endpoint, or
+// "" if endpoint isn't one
+func UnixSocket(endpoint string) string {
+	path, ok := strings.CutPrefix(endpoint, "unix://")
+	if !ok {
+		return ""
+	}
+	return path
+}
+
+// newUnixHTTPClient returns a client like newHTTPClient's that sends every
+// request to the unix socket at path, whatever host its URL names
+func newUnixHTTPClient(path string) *http.Client {
+	unixTransportsMu.Lock()
+	defer unixTransportsMu.Unlock()
+
+	transport, ok := unixTransports[path]
+	if !ok {
+		transport = sharedTransport.Clone()
+		transport.Proxy = nil
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		unixTransports[path] = transport
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"git-ac/internal/config"
+)
+
+// tokenExpiryMargin is how long before an access token expires it is replaced,
+// so it doesn't expire while a request is on its way
+const tokenExpiryMargin = 30 * time.Second
+
+// tokenSource gets access tokens from an OAuth 2.0 token endpoint with the
+// client credentials grant, and keeps using each until shortly before it expires
+type tokenSource struct {
+	config *config.OAuth2Config
+	client *http.Client
+
+	mu           sync.Mutex
+	token        string
+	expiry       time.Time // Zero if the endpoint didn't say when the token expires
+	secretInBody bool      // The endpoint wants the client credentials in the form rather than basic auth
+}
+
+func newTokenSource(cfg *config.OAuth2Config, client *http.Client) *tokenSource {
+	return &tokenSource{config: cfg, client: client}
+}
+
+// Token returns a current access token, getting a new one if needed
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && (s.expiry.IsZero() || time.Now().Before(s.expiry.Add(-tokenExpiryMargin))) {
+		return s.token, nil
+	}
+
+	// As endpoints differ in where they accept the client credentials, try
+	// basic auth, which the spec requires them to support, then the form
+	status, err := s.fetch(ctx)
+	if !s.secretInBody && (status == http.StatusBadRequest || status == http.StatusUnauthorized) {
+		s.secretInBody = true
+		if _, bodyErr := s.fetch(ctx); bodyErr == nil {
+			err = nil
+		} else {
+			s.secretInBody = false
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.token, nil
+}
+
+// Invalidate discards the current token, after a server rejected it
+func (s *tokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// fetch requests a new token, returning the endpoint's status code
+func (s *tokenSource) fetch(ctx context.Context) (int, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	if s.secretInBody {
+		form.Set("client_id", s.config.ClientID)
+		form.Set("client_secret", s.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !s.secretInBody {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, unreachable(fmt.Errorf("failed to get an access token from %s: %w", s.config.TokenURL, err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("failed to get an access token from %s (%d): %s - check oauth2.client_id and client_secret", s.config.TokenURL, resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode >= 500 {
+			err = unreachable(err)
+		}
+		return resp.StatusCode, err
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return resp.StatusCode, fmt.Errorf("the token endpoint %s didn't return an access token", s.config.TokenURL)
+	}
+	s.token = token.AccessToken
+	s.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return resp.StatusCode, nil
+}
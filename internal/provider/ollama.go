@@ -3,32 +3,44 @@ package provider
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"git-ac/internal/cache"
 	"git-ac/internal/color"
 	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
 	"git-ac/internal/llm"
 
 	"github.com/ollama/ollama/api"
 )
 
+// healthTTL is how long a passed health check is trusted by later runs
+const healthTTL = 5 * time.Minute
+
 type OllamaProvider struct {
 	client       *api.Client
 	config       *config.OllamaConfig
 	timeout      time.Duration
 	commitConfig config.CommitConfig
+	summaryCache *cache.Cache
+	debugLog     *debuglog.Logger
+	maxRetries   int         // Retries of requests that fail to reach Ollama
+	numCtx       int         // Context window to load the model with
+	healthy      atomic.Bool // A health check has passed, so later generations skip it
+	usageCounter
 }
 
 func NewOllamaProvider(cfg *config.OllamaConfig, timeout time.Duration, commitCfg config.CommitConfig) (*OllamaProvider, error) {
-	httpClient := &http.Client{
-		Timeout: timeout,
-	}
+	httpClient := newHTTPClient()
 
 	client := api.NewClient(&url.URL{Scheme: "http", Host: "localhost:11434"}, httpClient)
-	if cfg.Host != "" {
+	if socket := UnixSocket(cfg.Host); socket != "" {
+		// The host in the URL is only used for the Host header
+		client = api.NewClient(&url.URL{Scheme: "http", Host: "localhost"}, newUnixHTTPClient(socket))
+	} else if cfg.Host != "" {
 		if u, err := url.Parse(cfg.Host); err == nil {
 			client = api.NewClient(u, httpClient)
 		}
@@ -39,21 +51,54 @@ func NewOllamaProvider(cfg *config.OllamaConfig, timeout time.Duration, commitCf
 		config:       cfg,
 		timeout:      timeout,
 		commitConfig: commitCfg,
+		numCtx:       config.DefaultNumCtx,
 	}, nil
 }
 
-func (p *OllamaProvider) HealthCheck() error {
+// ensureHealthy runs a health check before the first request, unless one
+// passed recently for the same host and model or --no-health-check was given
+func (p *OllamaProvider) ensureHealthy(ctx context.Context) error {
+	if p.healthy.Load() || p.config.SkipHealthCheck {
+		return nil
+	}
+
+	// Health results are only kept on this machine, never in a shared cache
+	healthCache, err := cache.Default()
+	if err != nil {
+		defer recordHealthCheck(ctx, time.Now())
+		return p.HealthCheck(ctx)
+	}
+	key := cache.Key(p.config.Host, p.config.Model)
+	if checked, ok := healthCache.Get("health", key); ok {
+		if t, err := time.Parse(time.RFC3339, checked); err == nil && time.Since(t) < healthTTL {
+			p.healthy.Store(true)
+			return nil
+		}
+	}
+
+	started := time.Now()
+	err = p.HealthCheck(ctx)
+	recordHealthCheck(ctx, started)
+	if err != nil {
+		return err
+	}
+	// A health result that can't be stored is only checked again next time
+	_ = healthCache.Put("health", key, time.Now().Format(time.RFC3339))
+	return nil
+}
+
+func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
 	// Test connection with a short timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	// Try to list models to verify connection and get available models
 	resp, err := p.client.List(ctx)
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			return fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running with 'ollama serve'", p.config.Host)
+			return unreachable(fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running with 'ollama serve'", p.config.Host))
 		}
-		return fmt.Errorf("failed to connect to Ollama: %w", err)
+		return unreachable(fmt.Errorf("failed to connect to Ollama: %w", err))
 	}
 
 	// Check if the requested model is available
@@ -72,109 +117,232 @@ func (p *OllamaProvider) HealthCheck() error {
 			p.config.Model, strings.Join(availableModels, ", "), p.config.Model)
 	}
 
+	p.healthy.Store(true)
 	return nil
 }
 
-func (p *OllamaProvider) GenerateCommitMessage(diff, readme string) (string, error) {
-	// First, check if Ollama is reachable and the model exists
-	if err := p.HealthCheck(); err != nil {
-		return "", err
+// WarmUp checks the connection and loads the model with an empty request,
+// which Ollama answers as soon as the model is in memory
+func (p *OllamaProvider) WarmUp(ctx context.Context) (bool, error) {
+	if err := p.ensureHealthy(ctx); err != nil {
+		return false, err
 	}
 
-	color.FaintPrintf("Generating commit message using model '%s' (timeout: %v)...\n", p.config.Model, p.timeout)
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
 
-	// Check if diff is too large for direct processing
-	if llm.IsDiffTooLarge(diff, p.commitConfig) {
-		return p.generateCommitMessageTwoStage(diff, readme)
+	req := &api.GenerateRequest{
+		Model:     p.config.Model,
+		Stream:    new(bool),
+		KeepAlive: p.keepAlive(),
 	}
+	if err := p.client.Generate(ctx, req, func(api.GenerateResponse) error { return nil }); err != nil {
+		if strings.Contains(err.Error(), "connection refused") {
+			return false, unreachable(fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running", p.config.Host))
+		}
+		return false, fmt.Errorf("failed to load model '%s': %w", p.config.Model, err)
+	}
+	return true, nil
+}
 
-	// Direct approach for smaller diffs
-	prompt := llm.BuildCommitPrompt(diff, readme, false, p.commitConfig)
-	return p.generateFromPrompt(prompt)
+// keepAlive returns how long Ollama should keep the model loaded, or nil for its default
+func (p *OllamaProvider) keepAlive() *api.Duration {
+	if p.config.KeepAlive == 0 {
+		return nil
+	}
+	return &api.Duration{Duration: p.config.KeepAlive}
 }
 
-func (p *OllamaProvider) generateCommitMessageTwoStage(diff, readme string) (string, error) {
-	// Stage 1: Summarize changes per file
-	fileSummaries, err := p.summarizeFileChanges(diff)
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := p.client.List(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to summarize file changes: %w", err)
+		if strings.Contains(err.Error(), "connection refused") {
+			return nil, unreachable(fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running with 'ollama serve'", p.config.Host))
+		}
+		return nil, unreachable(fmt.Errorf("failed to connect to Ollama: %w", err))
+	}
+
+	models := make([]string, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		models = append(models, model.Name)
 	}
+	return models, nil
+}
 
-	// Stage 2: Generate commit message from summaries
-	prompt := llm.BuildCommitPrompt(fileSummaries, readme, true, p.commitConfig)
-	return p.generateFromPrompt(prompt)
+func (p *OllamaProvider) GenerateCommitMessage(ctx context.Context, diff string, project llm.ProjectContext) (string, error) {
+	return bestCandidate(p.GenerateCandidates(ctx, diff, project))
 }
 
-func (p *OllamaProvider) summarizeFileChanges(diff string) (string, error) {
+func (p *OllamaProvider) GenerateCandidates(ctx context.Context, diff string, project llm.ProjectContext) ([]llm.Candidate, error) {
+	// First, check if Ollama is reachable and the model exists. A long-running
+	// process only needs to do this once.
+	if err := p.ensureHealthy(ctx); err != nil {
+		return nil, err
+	}
+
+	color.FaintPrintf("Generating commit message using model '%s' (timeout: %v)...\n", p.config.Model, p.timeout)
+
+	commitConfig := commitConfigFrom(ctx, p.commitConfig)
+	input := llm.PromptInput{
+		Content:         diff,
+		Project:         project,
+		BreakingChanges: llm.DetectBreakingChanges(diff),
+		Release:         llm.DetectRelease(diff),
+	}
+	if input.Release != "" {
+		color.FaintPrintf("Only the version changed; writing a release commit for %s.\n", input.Release)
+	}
+
+	// Check if diff is too large for direct processing. Summaries are made
+	// from the whole diff, so nothing is cut from it.
+	if llm.UseTwoStage(diff, commitConfig) {
+		return generateTwoStage(ctx, input, p.config.Model, p.summaryCache, p.summarizeFileChanges, commitConfig, p.generateFromInput)
+	}
+
+	// Direct approach for smaller diffs: cut the diff down to the token limit,
+	// dropping the least important changes first
+	diff, omitted := llm.FitDiff(diff, commitConfig.DiffTokenLimit)
+	if omitted > 0 {
+		color.FaintPrintf("Diff exceeds the token limit; omitted %d less important hunks.\n", omitted)
+	}
+	input.Content = diff
+	return generateCandidates(ctx, diff, input, commitConfig, p.generateFromInput)
+}
+
+func (p *OllamaProvider) summarizeFileChanges(ctx context.Context, diff string) (string, error) {
 	prompt := llm.BuildSummarizePrompt(diff)
 
 	req := &api.GenerateRequest{
 		Model:   p.config.Model,
-		Prompt:  prompt,
+		Prompt:  prompt.String(),
 		Stream:  new(bool),
 		Context: nil, // Explicitly clear context to prevent cross-invocation contamination
 		Options: map[string]interface{}{
 			"temperature": 0.3, // Lower temperature for more focused analysis
 			"top_p":       0.8,
-			"num_ctx":     4096,
+			"num_ctx":     p.numCtx,
 			// Remove num_predict limit for thinking models
 			"stop": []string{"\n\nDIFF:", "\n\nCOMMIT"},
 		},
 	}
 
-	return p.generateFromRequest(req)
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return llm.StripThinking(response, commitConfigFrom(ctx, p.commitConfig).Cleaning.ThinkTags), nil
+}
+
+func (p *OllamaProvider) GenerateText(ctx context.Context, prompt llm.Prompt) (string, error) {
+	if err := p.ensureHealthy(ctx); err != nil {
+		return "", err
+	}
+
+	req := &api.GenerateRequest{
+		Model:   p.config.Model,
+		Prompt:  prompt.String(),
+		Stream:  new(bool),
+		Context: nil, // Explicitly clear context to prevent cross-invocation contamination
+		Options: map[string]interface{}{
+			"temperature": 0.5,
+			"top_p":       0.9,
+			"num_ctx":     p.numCtx,
+		},
+	}
+
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return llm.StripThinking(response, commitConfigFrom(ctx, p.commitConfig).Cleaning.ThinkTags), nil
 }
 
-func (p *OllamaProvider) generateFromPrompt(prompt string) (string, error) {
+func (p *OllamaProvider) generateFromInput(ctx context.Context, input llm.PromptInput) (string, error) {
+	commitConfig := commitConfigFrom(ctx, p.commitConfig)
+	prompt := llm.BuildCommitPrompt(input, commitConfig)
+
 	// Remove strict limits for thinking models
 	req := &api.GenerateRequest{
 		Model:   p.config.Model,
-		Prompt:  prompt,
+		Prompt:  prompt.String(),
 		Stream:  new(bool),
 		Context: nil, // Explicitly clear context to prevent cross-invocation contamination
 		Options: map[string]interface{}{
-			"temperature": 0.7,
+			"temperature": llm.RetryTemperature(0.7, input),
 			"top_p":       0.9,
-			"num_ctx":     4096,
+			"num_ctx":     p.numCtx,
 			// Remove num_predict limit to allow thinking models to work
 		},
 	}
+	if commitConfig.StructuredOutput {
+		req.Format = llm.PartsSchema
+	} else if streamFrom(ctx) != nil {
+		streaming := true
+		req.Stream = &streaming
+	}
+
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", retriable(err)
+	}
+	return llm.FinishMessage(response, input, commitConfig)
+}
 
-	return p.generateFromRequest(req)
+func (p *OllamaProvider) generateFromRequest(ctx context.Context, req *api.GenerateRequest) (string, error) {
+	var message string
+	err := withRetries(ctx, p.maxRetries, func() (err error) {
+		message, err = p.generateOnce(ctx, req)
+		return err
+	})
+	return message, err
 }
 
-func (p *OllamaProvider) generateFromRequest(req *api.GenerateRequest) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+// generateOnce sends req to Ollama and returns the response
+func (p *OllamaProvider) generateOnce(ctx context.Context, req *api.GenerateRequest) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
 	var fullResponse strings.Builder
+	req.KeepAlive = p.keepAlive()
 
+	var stream Stream
+	if req.Stream != nil && *req.Stream {
+		stream = streamFrom(ctx)
+		defer stream.Clear()
+	}
+
+	p.debugLog.Request("ollama generate", req)
 	err := p.client.Generate(ctx, req, func(response api.GenerateResponse) error {
 		fullResponse.WriteString(response.Response)
+		if stream != nil {
+			stream.Write(response.Response)
+		}
+		if response.Done {
+			p.add(response.PromptEvalCount, response.EvalCount)
+		}
 		return nil
 	})
 
 	if err != nil {
+		p.debugLog.Error("ollama generate", err)
 		if strings.Contains(err.Error(), "context deadline exceeded") {
-			return "", fmt.Errorf("request timed out after %v - try increasing timeout in config or check if model '%s' is available", p.timeout, p.config.Model)
+			return "", timedOut(fmt.Errorf("request timed out after %v - try increasing timeout in config or check if model '%s' is available", p.timeout, p.config.Model))
 		}
 		if strings.Contains(err.Error(), "connection refused") {
-			return "", fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running", p.config.Host)
+			return "", unreachable(fmt.Errorf("cannot connect to Ollama at %s - make sure Ollama is running", p.config.Host))
 		}
 		return "", fmt.Errorf("failed to generate response: %w", err)
 	}
 
+	p.debugLog.Response("ollama generate", fullResponse.String())
+
 	message := strings.TrimSpace(fullResponse.String())
 	if message == "" {
-		return "", fmt.Errorf("received empty response from Ollama")
-	}
-
-	// Clean up the message
-	cleanedMessage := llm.CleanCommitMessage(message, p.commitConfig)
-
-	if cleanedMessage == "" {
-		return "", fmt.Errorf("commit message became empty after cleaning - raw response was: %q", message)
+		return "", fmt.Errorf("%w from Ollama", errEmptyResponse)
 	}
 
-	return cleanedMessage, nil
+	return message, nil
 }
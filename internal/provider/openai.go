@@ -1,17 +1,22 @@
 package provider
 
 import (
+	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"git-ac/internal/cache"
 	"git-ac/internal/color"
 	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
 	"git-ac/internal/llm"
 )
 
@@ -20,6 +25,13 @@ type OpenAIProvider struct {
 	timeout      time.Duration
 	commitConfig config.CommitConfig
 	client       *http.Client
+	baseURL      string // Where requests are sent: config.BaseURL, or the API path on a unix socket
+	summaryCache *cache.Cache
+	debugLog     *debuglog.Logger
+	maxRetries   int          // Retries of requests that fail to reach the API
+	tokens       *tokenSource // Access tokens with oauth2; nil with api_key
+	maxTokens    int          // Most tokens a response may have
+	usageCounter
 }
 
 type ChatMessage struct {
@@ -28,13 +40,29 @@ type ChatMessage struct {
 }
 
 type ChatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float64       `json:"temperature"`
-	TopP        float64       `json:"top_p,omitempty"`
-	Stop        []string      `json:"stop,omitempty"`
-	Stream      bool          `json:"stream"`
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Temperature    float64         `json:"temperature"`
+	TopP           float64         `json:"top_p,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
 }
 
 type ChatCompletionResponse struct {
@@ -48,9 +76,18 @@ type ChatCompletionResponse struct {
 type Choice struct {
 	Index        int         `json:"index"`
 	Message      ChatMessage `json:"message"`
+	Delta        ChatMessage `json:"delta"` // The next part of the message, when streaming
 	FinishReason string      `json:"finish_reason"`
 }
 
+type ModelList struct {
+	Data []Model `json:"data"`
+}
+
+type Model struct {
+	ID string `json:"id"`
+}
+
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
@@ -58,17 +95,50 @@ type Usage struct {
 }
 
 func NewOpenAIProvider(cfg *config.OpenAIConfig, timeout time.Duration, commitCfg config.CommitConfig) (*OpenAIProvider, error) {
-	return &OpenAIProvider{
+	p := &OpenAIProvider{
 		config:       cfg,
 		timeout:      timeout,
 		commitConfig: commitCfg,
-		client: &http.Client{
-			Timeout: timeout,
-		},
-	}, nil
+		client:       newHTTPClient(),
+		baseURL:      cfg.BaseURL,
+		maxTokens:    config.DefaultMaxTokens,
+	}
+	if socket := UnixSocket(cfg.BaseURL); socket != "" {
+		// OpenAI-compatible servers serve the API under /v1; the host in the
+		// URL is only used for the Host header
+		p.client = newUnixHTTPClient(socket)
+		p.baseURL = "http://localhost/v1"
+	}
+	if cfg.OAuth2 != nil {
+		p.tokens = newTokenSource(cfg.OAuth2, newHTTPClient())
+	}
+	return p, nil
+}
+
+// authorize adds the API key, or with oauth2 a current access token, to req
+func (p *OpenAIProvider) authorize(ctx context.Context, req *http.Request) error {
+	key := p.config.APIKey
+	if p.tokens != nil {
+		var err error
+		if key, err = p.tokens.Token(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	return nil
 }
 
-func (p *OpenAIProvider) HealthCheck() error {
+// authFailed returns the error for a request the API refused as unauthorized.
+// With oauth2, the token is discarded, so the next request gets a new one.
+func (p *OpenAIProvider) authFailed() error {
+	if p.tokens != nil {
+		p.tokens.Invalidate()
+		return fmt.Errorf("authentication failed (401) - the API rejected the access token from %s; check that oauth2.token_url is the API's token endpoint and that oauth2.client_id may use the API", p.config.OAuth2.TokenURL)
+	}
+	return fmt.Errorf("authentication failed (401) - check your API key")
+}
+
+func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
 	// Simple health check by making a minimal request
 	req := ChatCompletionRequest{
 		Model: p.config.Model,
@@ -80,12 +150,17 @@ func (p *OpenAIProvider) HealthCheck() error {
 		Stream:      false,
 	}
 
-	_, err := p.makeRequest(req)
+	_, err := p.makeRequest(ctx, req)
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such host") {
-			return fmt.Errorf("cannot connect to OpenAI API at %s - check your network connection and base_url", p.config.BaseURL)
+			return unreachable(fmt.Errorf("cannot connect to OpenAI API at %s - check your network connection and base_url", p.config.BaseURL))
 		}
 		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "authentication") {
+			if p.tokens != nil {
+				// Either the token endpoint refused the client, or the API
+				// refused its token; the error says which
+				return err
+			}
 			return fmt.Errorf("authentication failed - check your API key")
 		}
 		if strings.Contains(err.Error(), "404") {
@@ -97,120 +172,207 @@ func (p *OpenAIProvider) HealthCheck() error {
 	return nil
 }
 
-func (p *OpenAIProvider) GenerateCommitMessage(diff, readme string) (string, error) {
-	color.FaintPrintf("Generating commit message using model '%s' (timeout: %v)...\n", p.config.Model, p.timeout)
+// WarmUp does nothing: OpenAI-compatible APIs load models on demand
+func (p *OpenAIProvider) WarmUp(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
 
-	// Check if diff is too large for direct processing
-	if p.isDiffTooLarge(diff) {
-		return p.generateCommitMessageTwoStage(diff, readme)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := p.authorize(ctx, httpReq); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such host") {
+			return nil, unreachable(fmt.Errorf("cannot connect to OpenAI API at %s - check your network connection and base_url", p.config.BaseURL))
+		}
+		return nil, unreachable(fmt.Errorf("failed to make request: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == 401 {
+			return nil, p.authFailed()
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("model list request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var list ModelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
 	}
 
-	// Direct approach for smaller diffs
-	prompt := p.buildPrompt(diff, readme)
-	return p.generateFromPrompt(prompt)
+	models := make([]string, 0, len(list.Data))
+	for _, model := range list.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
 }
 
-func (p *OpenAIProvider) isDiffTooLarge(diff string) bool {
-	return llm.IsDiffTooLarge(diff, p.commitConfig)
+func (p *OpenAIProvider) GenerateCommitMessage(ctx context.Context, diff string, project llm.ProjectContext) (string, error) {
+	return bestCandidate(p.GenerateCandidates(ctx, diff, project))
 }
 
-func (p *OpenAIProvider) generateCommitMessageTwoStage(diff, readme string) (string, error) {
-	// Stage 1: Summarize changes per file
-	fileSummaries, err := p.summarizeFileChanges(diff)
-	if err != nil {
-		return "", fmt.Errorf("failed to summarize file changes: %w", err)
+func (p *OpenAIProvider) GenerateCandidates(ctx context.Context, diff string, project llm.ProjectContext) ([]llm.Candidate, error) {
+	color.FaintPrintf("Generating commit message using model '%s' (timeout: %v)...\n", p.config.Model, p.timeout)
+
+	commitConfig := commitConfigFrom(ctx, p.commitConfig)
+	input := llm.PromptInput{
+		Content:         diff,
+		Project:         project,
+		BreakingChanges: llm.DetectBreakingChanges(diff),
+		Release:         llm.DetectRelease(diff),
+	}
+	if input.Release != "" {
+		color.FaintPrintf("Only the version changed; writing a release commit for %s.\n", input.Release)
 	}
 
-	// Stage 2: Generate commit message from summaries
-	prompt := p.buildCommitPromptFromSummaries(fileSummaries, readme)
-	return p.generateFromPrompt(prompt)
+	// Check if diff is too large for direct processing. Summaries are made
+	// from the whole diff, so nothing is cut from it.
+	if llm.UseTwoStage(diff, commitConfig) {
+		return generateTwoStage(ctx, input, p.config.Model, p.summaryCache, p.summarizeFileChanges, commitConfig, p.generateFromInput)
+	}
+
+	// Direct approach for smaller diffs: cut the diff down to the token limit,
+	// dropping the least important changes first
+	diff, omitted := llm.FitDiff(diff, commitConfig.DiffTokenLimit)
+	if omitted > 0 {
+		color.FaintPrintf("Diff exceeds the token limit; omitted %d less important hunks.\n", omitted)
+	}
+	input.Content = diff
+	return generateCandidates(ctx, diff, input, commitConfig, p.generateFromInput)
 }
 
-func (p *OpenAIProvider) summarizeFileChanges(diff string) (string, error) {
+func (p *OpenAIProvider) summarizeFileChanges(ctx context.Context, diff string) (string, error) {
 	prompt := llm.BuildSummarizePrompt(diff)
 
 	req := ChatCompletionRequest{
-		Model: p.config.Model,
-		Messages: []ChatMessage{
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens:   4096,                                // Match Ollama's num_ctx
+		Model:       p.config.Model,
+		Messages:    chatMessages(prompt),
+		MaxTokens:   p.maxTokens,
 		Temperature: 0.3,                                 // Lower temperature for more focused analysis
 		TopP:        0.8,                                 // Match Ollama's top_p
 		Stop:        []string{"\n\nDIFF:", "\n\nCOMMIT"}, // Match Ollama's stop sequences
 		Stream:      false,
 	}
 
-	return p.generateFromRequest(req)
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return llm.StripThinking(response, commitConfigFrom(ctx, p.commitConfig).Cleaning.ThinkTags), nil
 }
 
-func (p *OpenAIProvider) buildCommitPromptFromSummaries(summaries, readme string) string {
-	return llm.BuildCommitPrompt(summaries, readme, true, p.commitConfig)
+func (p *OpenAIProvider) GenerateText(ctx context.Context, prompt llm.Prompt) (string, error) {
+	req := ChatCompletionRequest{
+		Model:       p.config.Model,
+		Messages:    chatMessages(prompt),
+		MaxTokens:   p.maxTokens,
+		Temperature: 0.5, // Match Ollama's free-form temperature
+		TopP:        0.9, // Match Ollama's generation top_p
+		Stream:      false,
+	}
+
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return llm.StripThinking(response, commitConfigFrom(ctx, p.commitConfig).Cleaning.ThinkTags), nil
 }
 
-func (p *OpenAIProvider) generateFromPrompt(prompt string) (string, error) {
+func (p *OpenAIProvider) generateFromInput(ctx context.Context, input llm.PromptInput) (string, error) {
+	commitConfig := commitConfigFrom(ctx, p.commitConfig)
+	prompt := llm.BuildCommitPrompt(input, commitConfig)
+
 	req := ChatCompletionRequest{
-		Model: p.config.Model,
-		Messages: []ChatMessage{
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens:   4096, // Match Ollama's num_ctx
-		Temperature: 0.7,  // Match Ollama's generation temperature
-		TopP:        0.9,  // Match Ollama's generation top_p
+		Model:       p.config.Model,
+		Messages:    chatMessages(prompt),
+		MaxTokens:   p.maxTokens,
+		Temperature: llm.RetryTemperature(0.7, input), // Match Ollama's generation temperature
+		TopP:        0.9,                              // Match Ollama's generation top_p
 		Stream:      false,
 	}
+	if commitConfig.StructuredOutput {
+		req.ResponseFormat = &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &JSONSchema{Name: "commit_message", Schema: llm.PartsSchema},
+		}
+	} else if streamFrom(ctx) != nil {
+		req.Stream = true
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
 
-	return p.generateFromRequest(req)
+	response, err := p.generateFromRequest(ctx, req)
+	if err != nil {
+		return "", retriable(err)
+	}
+	return llm.FinishMessage(response, input, commitConfig)
 }
 
-func (p *OpenAIProvider) generateFromRequest(req ChatCompletionRequest) (string, error) {
-	resp, err := p.makeRequest(req)
+func (p *OpenAIProvider) generateFromRequest(ctx context.Context, req ChatCompletionRequest) (string, error) {
+	p.debugLog.Request("openai chat completion", req)
+	var resp *ChatCompletionResponse
+	err := withRetries(ctx, p.maxRetries, func() (err error) {
+		resp, err = p.makeRequest(ctx, req)
+		return err
+	})
 	if err != nil {
+		p.debugLog.Error("openai chat completion", err)
 		return "", err
 	}
+	p.debugLog.Response("openai chat completion", resp)
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return "", fmt.Errorf("%w from OpenAI", errEmptyResponse)
 	}
 
 	message := strings.TrimSpace(resp.Choices[0].Message.Content)
 	if message == "" {
-		return "", fmt.Errorf("received empty response from OpenAI")
+		return "", fmt.Errorf("%w from OpenAI", errEmptyResponse)
 	}
 
-	// Clean up the message
-	cleanedMessage := llm.CleanCommitMessage(message, p.commitConfig)
-
-	if cleanedMessage == "" {
-		return "", fmt.Errorf("commit message became empty after cleaning - raw response was: %q", message)
-	}
-
-	return cleanedMessage, nil
+	return message, nil
 }
 
-func (p *OpenAIProvider) makeRequest(req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+func (p *OpenAIProvider) makeRequest(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(context.Background(), "POST", p.config.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+cmp.Or(p.config.CompletionsPath, "/chat/completions"), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	if err := p.authorize(ctx, httpReq); err != nil {
+		return nil, err
+	}
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		if strings.Contains(err.Error(), "context deadline exceeded") || strings.Contains(err.Error(), "timeout") {
-			return nil, fmt.Errorf("request timed out after %v - try increasing timeout in config or check if the API is accessible", p.timeout)
+		if strings.Contains(err.Error(), "context deadline exceeded") {
+			return nil, timedOut(fmt.Errorf("request timed out after %v - try increasing timeout in config or check if the API is accessible", p.timeout))
 		}
 		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such host") {
-			return nil, fmt.Errorf("cannot connect to OpenAI API at %s - check your network connection and base_url", p.config.BaseURL)
+			return nil, unreachable(fmt.Errorf("cannot connect to OpenAI API at %s - check your network connection and base_url", p.config.BaseURL))
 		}
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, unreachable(fmt.Errorf("failed to make request: %w", err))
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -220,26 +382,89 @@ func (p *OpenAIProvider) makeRequest(req ChatCompletionRequest) (*ChatCompletion
 		body, _ := io.ReadAll(resp.Body)
 		switch resp.StatusCode {
 		case 401:
-			return nil, fmt.Errorf("authentication failed (401) - check your API key")
+			return nil, p.authFailed()
 		case 404:
-			return nil, fmt.Errorf("model '%s' not found (404) - check if the model exists and you have access", p.config.Model)
+			return nil, fmt.Errorf("not found (404) - check that model '%s' exists and you have access, and that base_url and completions_path lead to the chat completions endpoint", p.config.Model)
 		case 429:
-			return nil, fmt.Errorf("rate limit exceeded (429) - try again later or increase timeout")
+			return nil, fmt.Errorf("%w - try again later or increase timeout", errRateLimited)
 		case 500, 502, 503, 504:
-			return nil, fmt.Errorf("server error (%d) - the API service may be experiencing issues", resp.StatusCode)
+			return nil, unreachable(fmt.Errorf("server error (%d) - the API service may be experiencing issues", resp.StatusCode))
 		default:
 			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 		}
 	}
 
 	var chatResp ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+	if req.Stream {
+		chatResp, err = readStream(resp.Body, streamFrom(ctx))
+		if err != nil {
+			return nil, err
+		}
+	} else if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	p.add(chatResp.Usage.PromptTokens, chatResp.Usage.CompletionTokens)
 
 	return &chatResp, nil
 }
 
-func (p *OpenAIProvider) buildPrompt(diff, readme string) string {
-	return llm.BuildCommitPrompt(diff, readme, false, p.commitConfig)
+// readStream reads a streamed chat completion, a series of server-sent events
+// each holding the next part of the message, and shows it on stream as it
+// arrives. It returns the completion as if it had come whole.
+func readStream(body io.Reader, stream Stream) (ChatCompletionResponse, error) {
+	if stream != nil {
+		defer stream.Clear()
+	}
+
+	var chatResp ChatCompletionResponse
+	var content strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return chatResp, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chunk.Usage.TotalTokens > 0 || chunk.Usage.PromptTokens > 0 {
+			chatResp.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if stream != nil {
+			stream.Write(chunk.Choices[0].Delta.Content)
+		}
+		chatResp.ID = chunk.ID
+		chatResp.Choices = []Choice{{FinishReason: chunk.Choices[0].FinishReason}}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return chatResp, timedOut(fmt.Errorf("the response didn't finish in time - try increasing timeout in config"))
+		}
+		return chatResp, unreachable(fmt.Errorf("failed to read the streamed response: %w", err))
+	}
+
+	if len(chatResp.Choices) > 0 {
+		chatResp.Choices[0].Message = ChatMessage{Role: "assistant", Content: content.String()}
+	}
+	return chatResp, nil
+}
+
+// chatMessages sends the prompt's instructions as a system message and its content as
+// a user message, which chat models follow more reliably than one combined message
+func chatMessages(prompt llm.Prompt) []ChatMessage {
+	return []ChatMessage{
+		{Role: "system", Content: prompt.System},
+		{Role: "user", Content: prompt.User},
+	}
 }
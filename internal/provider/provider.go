@@ -1,28 +1,192 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+
+	"git-ac/internal/cache"
 	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
+	"git-ac/internal/llm"
 )
 
+// ErrUnreachable matches errors from failing to reach the provider at all:
+// connection failures and server errors
+var ErrUnreachable = errors.New("provider unreachable")
+
+// ErrTimeout matches errors from a request the provider didn't answer within
+// provider.timeout. Unlike ErrUnreachable, it isn't retried: the model was
+// only slow, and would most likely be as slow again.
+var ErrTimeout = errors.New("provider timed out")
+
+// unreachableError marks err as matching ErrUnreachable, keeping its message
+type unreachableError struct {
+	error
+}
+
+func (e unreachableError) Is(target error) bool { return target == ErrUnreachable }
+func (e unreachableError) Unwrap() error        { return e.error }
+
+// unreachable marks err as a failure to reach the provider
+func unreachable(err error) error {
+	return unreachableError{err}
+}
+
+// timeoutError marks err as matching ErrTimeout, keeping its message
+type timeoutError struct {
+	error
+}
+
+func (e timeoutError) Is(target error) bool { return target == ErrTimeout }
+func (e timeoutError) Unwrap() error        { return e.error }
+
+// timedOut marks err as a request that ran out of time
+func timedOut(err error) error {
+	return timeoutError{err}
+}
+
 // LLMProvider defines the interface for language model providers
 type LLMProvider interface {
 	// HealthCheck verifies the provider is accessible and configured correctly
-	HealthCheck() error
+	HealthCheck(ctx context.Context) error
+
+	// GenerateCommitMessage generates a commit message from the given diff and project context
+	GenerateCommitMessage(ctx context.Context, diff string, project llm.ProjectContext) (string, error)
+
+	// GenerateCandidates generates commit.candidates messages, ranked best first
+	GenerateCandidates(ctx context.Context, diff string, project llm.ProjectContext) ([]llm.Candidate, error)
 
-	// GenerateCommitMessage generates a commit message from the given diff and readme content
-	GenerateCommitMessage(diff, readme string) (string, error)
+	// GenerateText runs a free-form prompt, for text other than commit
+	// messages, and returns the response without the model's reasoning
+	GenerateText(ctx context.Context, prompt llm.Prompt) (string, error)
+
+	// ListModels returns the names of the models the provider offers
+	ListModels(ctx context.Context) ([]string, error)
+
+	// Usage returns the tokens used since the provider was created
+	Usage() TokenUsage
+
+	// WarmUp loads the model ahead of the first request, reporting whether
+	// there was anything to load. Providers that load models on demand do nothing.
+	WarmUp(ctx context.Context) (bool, error)
 }
 
-// NewProvider creates a new LLM provider based on the config
-func NewProvider(cfg *config.Config) (LLMProvider, error) {
+// NewProvider creates a new LLM provider based on the config. Requests and
+// responses are recorded in debugLog, which may be nil.
+func NewProvider(cfg *config.Config, debugLog *debuglog.Logger) (LLMProvider, error) {
+	summaryCache, err := NewSummaryCache(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+
 	switch cfg.Provider.Type {
 	case "ollama":
-		return NewOllamaProvider(cfg.Provider.Ollama, cfg.Provider.Timeout, cfg.Commit)
+		p, err := NewOllamaProvider(cfg.Provider.Ollama, cfg.Provider.Timeout, cfg.Commit)
+		if err != nil {
+			return nil, err
+		}
+		p.summaryCache = summaryCache
+		p.debugLog = debugLog
+		p.maxRetries = cfg.Provider.MaxRetries
+		p.numCtx = cfg.Provider.NumCtx()
+		return p, nil
 	case "openai":
-		return NewOpenAIProvider(cfg.Provider.OpenAI, cfg.Provider.Timeout, cfg.Commit)
+		p, err := NewOpenAIProvider(cfg.Provider.OpenAI, cfg.Provider.Timeout, cfg.Commit)
+		if err != nil {
+			return nil, err
+		}
+		p.summaryCache = summaryCache
+		p.debugLog = debugLog
+		p.maxRetries = cfg.Provider.MaxRetries
+		p.maxTokens = cfg.Provider.MaxResponseTokens()
+		return p, nil
+	case "gateway":
+		// The gateway prepares prompts and caches summaries itself
+		p := NewGatewayProvider(cfg.Provider.Gateway, cfg.Provider.Timeout, cfg.Commit, debugLog)
+		p.maxRetries = cfg.Provider.MaxRetries
+		return p, nil
 	default:
 		// This should never happen due to config validation, but defensive programming
 		return nil, fmt.Errorf("unsupported provider type: %s", cfg.Provider.Type)
 	}
 }
+
+// NewSummaryCache returns the cache for summaries, or nil if caching
+// is disabled
+func NewSummaryCache(cfg config.CacheConfig) (*cache.Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	remote, err := newRemoteCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	summaryCache, err := cache.Default()
+	switch {
+	case err != nil && remote != nil:
+		// Without a usable cache directory, the shared cache is still used
+		return cache.NewRemote(remote), nil
+	case err != nil:
+		// Without a usable cache directory, summaries are simply not cached
+		return nil, nil
+	case remote != nil:
+		return summaryCache.WithRemote(remote), nil
+	default:
+		return summaryCache, nil
+	}
+}
+
+// newRemoteCache returns the shared cache backend cache.backend selects, or
+// nil for the local cache alone
+func newRemoteCache(cfg config.CacheConfig) (cache.Remote, error) {
+	switch cfg.Backend {
+	case "redis":
+		remote, err := cache.NewRedis(cfg.Redis.URL, cfg.Redis.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("cache.redis: %w", err)
+		}
+		return remote, nil
+	case "s3":
+		opts := cache.S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Bucket:    cfg.S3.Bucket,
+			Prefix:    cfg.S3.Prefix,
+			Region:    cfg.S3.Region,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		}
+		if opts.AccessKey == "" && opts.SecretKey == "" {
+			opts.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+			opts.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			opts.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+		remote, err := cache.NewS3(opts)
+		if err != nil {
+			return nil, fmt.Errorf("cache.s3: %w", err)
+		}
+		return remote, nil
+	default:
+		return nil, nil
+	}
+}
+
+// ValidateModel checks that model is offered by the provider
+func ValidateModel(ctx context.Context, p LLMProvider, model string) error {
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+
+	for _, m := range models {
+		// Ollama reports untagged models with an explicit ":latest" tag
+		if m == model || m == model+":latest" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("model '%s' not found - available models: %s", model, strings.Join(models, ", "))
+}
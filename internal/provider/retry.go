@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"git-ac/internal/color"
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+)
+
+// errEmptyResponse is returned when the model's response has no text in it
+var errEmptyResponse = errors.New("received empty response")
+
+// retriable turns an empty response into a validation error, so it is retried
+// like any other unusable message
+func retriable(err error) error {
+	if errors.Is(err, errEmptyResponse) {
+		return &llm.ValidationError{Reason: "the response was empty", Unusable: true}
+	}
+	return err
+}
+
+// errRateLimited is returned when the provider turns a request away for
+// exceeding its rate limit
+var errRateLimited = errors.New("rate limit exceeded (429)")
+
+// maxRetryDelay caps the wait before retrying a failed request
+const maxRetryDelay = 30 * time.Second
+
+// withRetries calls request, and while it fails because the provider couldn't
+// be reached or was rate limited, retries it up to maxRetries times, waiting a
+// second before the first retry and twice as long before each one after that
+func withRetries(ctx context.Context, maxRetries int, request func() error) error {
+	delay := time.Second
+	for retry := 1; ; retry++ {
+		err := request()
+		if err == nil || retry > maxRetries || ctx.Err() != nil ||
+			!errors.Is(err, ErrUnreachable) && !errors.Is(err, errRateLimited) {
+			return err
+		}
+
+		color.FaintPrintf("Request failed (%v); retrying in %v (%d of %d)...\n", err, delay, retry, maxRetries)
+		recordRetry(ctx)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// generateFunc generates a commit message from prompt input
+type generateFunc func(ctx context.Context, input llm.PromptInput) (string, error)
+
+// generateValidated generates a message with generate and validates it, retrying
+// with the rejected attempt and the reason as feedback until a valid message is
+// produced or the configured number of attempts is used up
+func generateValidated(ctx context.Context, input llm.PromptInput, commitConfig config.CommitConfig, generate generateFunc) (string, error) {
+	var err error
+	for attempt := 1; attempt <= commitConfig.MaxAttempts; attempt++ {
+		input.Attempt = attempt
+
+		var message string
+		message, err = generate(ctx, input)
+		if err == nil {
+			err = llm.ValidateMessage(message, commitConfig)
+		}
+
+		var invalid *llm.ValidationError
+		if !errors.As(err, &invalid) {
+			// Valid, or a failure that retrying with feedback won't fix
+			return message, err
+		}
+
+		if attempt < commitConfig.MaxAttempts {
+			color.FaintPrintf("Generated message was invalid (%s); retrying...\n", invalid.Reason)
+			recordRetry(ctx)
+		}
+		input.Rejected = invalid
+	}
+
+	return "", fmt.Errorf("no valid commit message after %d attempts: %w", commitConfig.MaxAttempts, err)
+}
+
+// generateCandidates generates the configured number of validated candidates with
+// generate and ranks them, best first. It fails only if no candidate could be generated.
+func generateCandidates(ctx context.Context, diff string, input llm.PromptInput, commitConfig config.CommitConfig, generate generateFunc) ([]llm.Candidate, error) {
+	// Validate and rank with the types the repository's conventions list, and
+	// a release commit's header
+	commitConfig = input.CommitConfig(commitConfig)
+	n := commitConfig.Candidates
+	var messages []string
+	var err error
+	for i := 0; i < n; i++ {
+		if n > 1 {
+			color.FaintPrintf("Generating candidate %d of %d...\n", i+1, n)
+		}
+
+		var message string
+		message, err = generateValidated(ctx, input, commitConfig, generate)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Canceled; don't bother with the remaining candidates
+				return nil, err
+			}
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil, err
+	}
+	return llm.RankCandidates(messages, diff, commitConfig), nil
+}
+
+// bestCandidate returns the top-ranked candidate's message
+func bestCandidate(candidates []llm.Candidate, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return candidates[0].Message, nil
+}
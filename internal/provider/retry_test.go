@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+)
+
+// testCommitConfig returns the default commit settings
+func testCommitConfig() config.CommitConfig {
+	return config.CommitConfig{
+		MaxLength:   72,
+		Types:       config.DefaultCommitTypes(),
+		IncludeBody: "auto",
+		Mood:        "imperative",
+		MaxAttempts: 3,
+		Candidates:  1,
+		Cleaning:    config.DefaultCleaningConfig(),
+	}
+}
+
+// scripted returns a generateFunc that answers with responses in turn, and
+// records the input of each call
+func scripted(calls *[]llm.PromptInput, responses ...string) generateFunc {
+	return func(ctx context.Context, input llm.PromptInput) (string, error) {
+		*calls = append(*calls, input)
+		return responses[min(len(*calls), len(responses))-1], nil
+	}
+}
+
+func TestGenerateValidated(t *testing.T) {
+	t.Run("valid first time", func(t *testing.T) {
+		var calls []llm.PromptInput
+		message, err := generateValidated(context.Background(), llm.PromptInput{}, testCommitConfig(), scripted(&calls, "feat: add token validation"))
+		if err != nil || message != "feat: add token validation" || len(calls) != 1 {
+			t.Errorf("generateValidated() = %q, %v after %d calls", message, err, len(calls))
+		}
+	})
+
+	t.Run("invalid message retried with feedback", func(t *testing.T) {
+		var calls []llm.PromptInput
+		message, err := generateValidated(context.Background(), llm.PromptInput{}, testCommitConfig(),
+			scripted(&calls, "feature: add token validation", "feat: add token validation"))
+		if err != nil || message != "feat: add token validation" {
+			t.Fatalf("generateValidated() = %q, %v", message, err)
+		}
+		if len(calls) != 2 || calls[1].Attempt != 2 || calls[1].Rejected == nil ||
+			!strings.Contains(calls[1].Rejected.Reason, "'feature' is not a valid type") {
+			t.Errorf("the retry wasn't given the rejected attempt: %+v", calls)
+		}
+	})
+
+	t.Run("attempts used up", func(t *testing.T) {
+		var calls []llm.PromptInput
+		_, err := generateValidated(context.Background(), llm.PromptInput{}, testCommitConfig(), scripted(&calls, "add token validation"))
+		var invalid *llm.ValidationError
+		if !errors.As(err, &invalid) || !strings.Contains(err.Error(), "no valid commit message after 3 attempts") || len(calls) != 3 {
+			t.Errorf("generateValidated() = %v after %d calls, want a ValidationError after 3", err, len(calls))
+		}
+	})
+
+	t.Run("failure not retried", func(t *testing.T) {
+		calls := 0
+		failed := errors.New("model not found")
+		_, err := generateValidated(context.Background(), llm.PromptInput{}, testCommitConfig(), func(ctx context.Context, input llm.PromptInput) (string, error) {
+			calls++
+			return "", failed
+		})
+		if !errors.Is(err, failed) || calls != 1 {
+			t.Errorf("generateValidated() = %v after %d calls, want the failure after 1", err, calls)
+		}
+	})
+}
+
+func TestWithRetries(t *testing.T) {
+	refused := unreachable(errors.New("connection refused"))
+	notFound := errors.New("model not found")
+	deadline := timedOut(errors.New("context deadline exceeded"))
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error // Returned by each call in turn
+		wantCalls  int
+		wantErr    error
+	}{
+		{"success", 2, []error{nil}, 1, nil},
+		{"unreachable, then success", 2, []error{refused, nil}, 2, nil},
+		{"rate limited, then success", 2, []error{errRateLimited, nil}, 2, nil},
+		{"no retries", 0, []error{refused}, 1, ErrUnreachable},
+		{"other failures not retried", 2, []error{notFound}, 1, notFound},
+		{"timeouts not retried", 2, []error{deadline}, 1, ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			err := withRetries(context.Background(), tt.maxRetries, func() error {
+				calls++
+				return tt.errs[min(calls, len(tt.errs))-1]
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("withRetries() made %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("withRetries() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := withRetries(ctx, 5, func() error {
+			calls++
+			cancel()
+			return refused
+		})
+		if calls != 1 || !errors.Is(err, ErrUnreachable) {
+			t.Errorf("withRetries() = %v after %d calls, want the failure after 1", err, calls)
+		}
+	})
+}
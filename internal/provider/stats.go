@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stats measures the work around generating a message that token counts
+// don't show, for --stats. Providers record into the Stats set by WithStats.
+type Stats struct {
+	healthCheck atomic.Int64 // Nanoseconds spent checking the provider's health
+	retries     atomic.Int64
+}
+
+// HealthCheck returns the time spent checking that the provider is up and
+// has the model; 0 if no check was needed
+func (s *Stats) HealthCheck() time.Duration {
+	return time.Duration(s.healthCheck.Load())
+}
+
+// Retries returns the number of requests repeated after a failure, and of
+// messages regenerated after failing validation
+func (s *Stats) Retries() int {
+	return int(s.retries.Load())
+}
+
+type statsKey struct{}
+
+// WithStats returns a context under which providers record into s
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// recordHealthCheck adds the time since started to the health check time
+// recorded under ctx, if any
+func recordHealthCheck(ctx context.Context, started time.Time) {
+	if s, ok := ctx.Value(statsKey{}).(*Stats); ok {
+		s.healthCheck.Add(int64(time.Since(started)))
+	}
+}
+
+// recordRetry counts a retry under ctx, if stats are recorded
+func recordRetry(ctx context.Context) {
+	if s, ok := ctx.Value(statsKey{}).(*Stats); ok {
+		s.retries.Add(1)
+	}
+}
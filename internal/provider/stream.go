@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"context"
+)
+
+// Stream shows a commit message as the model generates it
+type Stream interface {
+	// Write receives the next part of the response
+	Write(text string)
+
+	// Clear removes what was written, once the response is complete
+	Clear()
+}
+
+type streamKey struct{}
+
+// WithStream returns a context under which commit messages are streamed to s
+// as they are generated. Summaries, free-form text, and structured output
+// aren't streamed, and neither are messages from a gateway.
+func WithStream(ctx context.Context, s Stream) context.Context {
+	return context.WithValue(ctx, streamKey{}, s)
+}
+
+// streamFrom returns the stream set by WithStream, or nil
+func streamFrom(ctx context.Context) Stream {
+	s, _ := ctx.Value(streamKey{}).(Stream)
+	return s
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"git-ac/internal/cache"
+	"git-ac/internal/color"
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+)
+
+// summaryNamespace is the cache namespace for per-file summaries
+const summaryNamespace = "summaries"
+
+// summaryShare is the part of the time left that summarizing may take in
+// two-stage mode, so a slow summarization still leaves time to generate the message
+const summaryShare = 0.6
+
+// generateTwoStage generates candidates for a large diff in two stages:
+// summarizing each file with summarize, then generating the message from the
+// summaries with generate. If ctx has a deadline, summarizing gets
+// summaryShare of the time left.
+func generateTwoStage(ctx context.Context, input llm.PromptInput, model string, summaryCache *cache.Cache, summarize summarizeFunc, commitConfig config.CommitConfig, generate generateFunc) ([]llm.Candidate, error) {
+	// Stage 1: Summarize changes per file
+	summarizeCtx := ctx
+	var limit time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = time.Duration(float64(time.Until(deadline)) * summaryShare)
+		var cancel context.CancelFunc
+		summarizeCtx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+		color.FaintPrintf("Stage 1 of 2: summarizing each file (up to %v)...\n", limit.Round(time.Second))
+	} else {
+		color.FaintPrintf("Stage 1 of 2: summarizing each file...\n")
+	}
+	started := time.Now()
+	fileSummaries, err := summarizeFiles(summarizeCtx, input.Content, model, summaryCache, summarize)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(summarizeCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("summarizing the files took longer than its share of the time (%v) - allow more time, or raise commit.large_diff_threshold to summarize fewer diffs", limit.Round(time.Second))
+		}
+		return nil, fmt.Errorf("failed to summarize file changes: %w", err)
+	}
+	color.FaintPrintf("Summarized the files in %v.\n", time.Since(started).Round(time.Millisecond))
+
+	// Stage 2: Generate commit message from summaries
+	color.FaintPrintf("Stage 2 of 2: generating the message from the summaries...\n")
+	started = time.Now()
+	diff := input.Content
+	input.Content = fileSummaries
+	input.IsFileSummary = true
+	candidates, err := generateCandidates(ctx, diff, input, commitConfig, generate)
+	if err != nil {
+		return nil, err
+	}
+	color.FaintPrintf("Generated the message in %v.\n", time.Since(started).Round(time.Millisecond))
+	return candidates, nil
+}
+
+// summarizeFunc summarizes the changes in a diff
+type summarizeFunc func(ctx context.Context, diff string) (string, error)
+
+// summarizeFiles summarizes each file in the diff separately using summarize.
+// Summaries are cached by (old blob, new blob, model), so files that haven't
+// changed since the last run are not summarized again.
+func summarizeFiles(ctx context.Context, diff, model string, summaryCache *cache.Cache, summarize summarizeFunc) (string, error) {
+	files := llm.SplitDiff(diff)
+	if len(files) == 0 {
+		// Not a diff we can split; summarize it as a whole
+		return summarize(ctx, diff)
+	}
+
+	var summaries strings.Builder
+	cached := 0
+	for _, file := range files {
+		summary, fromCache, err := summarizeFile(ctx, file, model, summaryCache, summarize)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize %s: %w", file.Path, err)
+		}
+		if fromCache {
+			cached++
+		}
+		summaries.WriteString(file.Path + ":\n" + summary + "\n\n")
+	}
+
+	if cached > 0 {
+		color.FaintPrintf("Reused cached summaries for %d of %d files.\n", cached, len(files))
+	}
+
+	return strings.TrimSpace(summaries.String()), nil
+}
+
+func summarizeFile(ctx context.Context, file llm.FileDiff, model string, summaryCache *cache.Cache, summarize summarizeFunc) (string, bool, error) {
+	// Renames, mode changes, and binary files have no content to summarize
+	if !file.Changed {
+		return file.Diff, false, nil
+	}
+
+	key := ""
+	if summaryCache != nil && file.OldBlob != "" && file.NewBlob != "" {
+		key = cache.Key(file.OldBlob, file.NewBlob, model)
+		if summary, ok := summaryCache.Get(summaryNamespace, key); ok {
+			return summary, true, nil
+		}
+	}
+
+	summary, err := summarize(ctx, file.Diff)
+	if err != nil {
+		return "", false, err
+	}
+
+	if key != "" {
+		// A failed cache write only costs a re-summarization next time
+		_ = summaryCache.Put(summaryNamespace, key, summary)
+	}
+
+	return summary, false, nil
+}
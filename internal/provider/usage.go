@@ -0,0 +1,30 @@
+package provider
+
+import "sync/atomic"
+
+// TokenUsage counts the tokens sent to and generated by a model
+type TokenUsage struct {
+	Prompt     int // Tokens in the prompts
+	Completion int // Tokens generated
+}
+
+// Sub returns the tokens used since an earlier reading
+func (u TokenUsage) Sub(earlier TokenUsage) TokenUsage {
+	return TokenUsage{Prompt: u.Prompt - earlier.Prompt, Completion: u.Completion - earlier.Completion}
+}
+
+// usageCounter totals the tokens a provider has used, as reported by the API
+type usageCounter struct {
+	prompt     atomic.Int64
+	completion atomic.Int64
+}
+
+func (c *usageCounter) add(prompt, completion int) {
+	c.prompt.Add(int64(prompt))
+	c.completion.Add(int64(completion))
+}
+
+// Usage returns the tokens used since the provider was created
+func (c *usageCounter) Usage() TokenUsage {
+	return TokenUsage{Prompt: int(c.prompt.Load()), Completion: int(c.completion.Load())}
+}
@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WithholdPaths removes the contents of files matching the configured redact_paths
+// patterns from diff, keeping only their headers (file name and change type). It
+// returns the diff and the paths that were withheld.
+func (r *Redactor) WithholdPaths(diff string) (string, []string) {
+	if len(r.paths) == 0 {
+		return diff, nil
+	}
+
+	var out []string
+	var withheld []string
+	skipping := false
+	inHeader := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			path := diffPath(line)
+			skipping = r.matchesPath(path)
+			inHeader = true
+			out = append(out, line)
+			if skipping {
+				withheld = append(withheld, path)
+			}
+			continue
+		}
+		if !skipping {
+			out = append(out, line)
+			continue
+		}
+
+		// Keep the file's change type (new, deleted, renamed, mode change), but
+		// not its blob IDs or any content
+		if inHeader && strings.HasPrefix(line, "@@") {
+			inHeader = false
+			out = append(out, "[contents withheld by privacy.redact_paths]")
+		}
+		if inHeader && !strings.HasPrefix(line, "index ") && !strings.HasPrefix(line, "Binary files ") {
+			out = append(out, line)
+		}
+	}
+
+	return strings.Join(out, "\n"), withheld
+}
+
+func (r *Redactor) matchesPath(path string) bool {
+	for _, pattern := range r.paths {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob converts a gitignore-style glob into a regular expression matching
+// repository-relative paths. Patterns without a slash match the file name in any
+// directory; "**" matches any number of directories.
+func compileGlob(glob string) *regexp.Regexp {
+	glob = strings.TrimPrefix(glob, "/")
+	anywhere := !strings.Contains(glob, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if anywhere {
+		b.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// A directory pattern also matches everything below it
+	b.WriteString("(/.*)?$")
+
+	return regexp.MustCompile(b.String())
+}
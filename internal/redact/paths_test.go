@@ -0,0 +1,55 @@
+package redact
+
+import (
+	"slices"
+	"testing"
+
+	"git-ac/internal/config"
+)
+
+func TestWithholdPaths(t *testing.T) {
+	r, err := New(config.PrivacyConfig{RedactPaths: []string{".env", "secrets/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := "diff --git a/.env b/.env\nnew file mode 100644\nindex 0000000..1111111\n--- /dev/null\n+++ b/.env\n@@ -0,0 +1 @@\n+PASSWORD=hunter2\n" +
+		"diff --git a/main.go b/main.go\n@@ -1 +1 @@\n+func main() {}"
+	want := "diff --git a/.env b/.env\nnew file mode 100644\n--- /dev/null\n+++ b/.env\n[contents withheld by privacy.redact_paths]\n" +
+		"diff --git a/main.go b/main.go\n@@ -1 +1 @@\n+func main() {}"
+
+	got, withheld := r.WithholdPaths(diff)
+	if got != want {
+		t.Errorf("WithholdPaths() = %q, want %q", got, want)
+	}
+	if !slices.Equal(withheld, []string{".env"}) {
+		t.Errorf("WithholdPaths() withheld %q, want .env", withheld)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{".env", ".env", true},
+		{".env", "config/.env", true},
+		{"*.pem", "certs/server.pem", true},
+		{"*.pem", "server.pem.txt", false},
+		{"config/*.yaml", "config/prod.yaml", true},
+		{"config/*.yaml", "config/prod/db.yaml", false},
+		{"/config", "config/prod.yaml", true},
+		{"secrets/**", "secrets/a/b.txt", true},
+		{"**/keys/*.json", "svc/keys/k.json", true},
+		{"**/keys/*.json", "keys/k.json", true},
+		{"key?.txt", "key1.txt", true},
+		{"key?.txt", "key/.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := compileGlob(tt.glob).MatchString(tt.path); got != tt.want {
+			t.Errorf("compileGlob(%q) matching %q = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"git-ac/internal/config"
+)
+
+// detector finds one kind of secret in a line of the diff
+type detector struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// builtinDetectors match well-known credential formats
+var builtinDetectors = []detector{
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(proj-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+
+	// tokenPattern matches candidates for the high-entropy check
+	tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{24,}`)
+)
+
+// minEntropy is the Shannon entropy (bits per character) above which a token is
+// treated as a secret. Hex strings such as commit and blob IDs stay below 4.
+const minEntropy = 4.0
+
+// Finding describes one redacted secret
+type Finding struct {
+	Kind string
+	Path string // File the secret was found in
+}
+
+// Redactor removes secrets and sensitive files from diffs
+type Redactor struct {
+	detectors   []detector
+	highEntropy bool
+	paths       []*regexp.Regexp
+}
+
+// New creates a Redactor using the built-in detectors and the configured patterns and paths
+func New(cfg config.PrivacyConfig) (*Redactor, error) {
+	detectors := append([]detector(nil), builtinDetectors...)
+	for _, p := range cfg.SecretPatterns {
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern '%s': %w", p.Name, err)
+		}
+		detectors = append(detectors, detector{name: p.Name, pattern: pattern})
+	}
+
+	var paths []*regexp.Regexp
+	for _, glob := range cfg.RedactPaths {
+		paths = append(paths, compileGlob(glob))
+	}
+
+	return &Redactor{detectors: detectors, highEntropy: cfg.DetectHighEntropy, paths: paths}, nil
+}
+
+// Redact replaces secrets in the changed and context lines of diff, returning
+// the redacted diff and what was found
+func (r *Redactor) Redact(diff string) (string, []Finding) {
+	var findings []Finding
+	path := ""
+	inKey := false
+
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			path = diffPath(line)
+			inKey = false
+			continue
+		}
+
+		prefix, content, ok := splitContentLine(line)
+		if !ok {
+			continue
+		}
+
+		// Private keys span many lines; drop everything between the markers
+		if inKey {
+			if privateKeyEnd.MatchString(content) {
+				inKey = false
+			}
+			lines[i] = prefix + "[REDACTED private key]"
+			continue
+		}
+		if privateKeyBegin.MatchString(content) {
+			findings = append(findings, Finding{Kind: "private key", Path: path})
+			inKey = !privateKeyEnd.MatchString(content)
+			lines[i] = prefix + "[REDACTED private key]"
+			continue
+		}
+
+		for _, d := range r.detectors {
+			if d.pattern.MatchString(content) {
+				content = d.pattern.ReplaceAllString(content, "[REDACTED "+d.name+"]")
+				findings = append(findings, Finding{Kind: d.name, Path: path})
+			}
+		}
+
+		if r.highEntropy {
+			found := false
+			content = tokenPattern.ReplaceAllStringFunc(content, func(token string) string {
+				if !looksRandom(token) {
+					return token
+				}
+				found = true
+				return "[REDACTED token]"
+			})
+			if found {
+				findings = append(findings, Finding{Kind: "high-entropy token", Path: path})
+			}
+		}
+
+		lines[i] = prefix + content
+	}
+
+	return strings.Join(lines, "\n"), findings
+}
+
+// Summary describes findings for a warning, e.g. "AWS access key in config.go"
+func Summary(findings []Finding) []string {
+	seen := make(map[Finding]bool)
+	var out []string
+	for _, f := range findings {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		if f.Path != "" {
+			out = append(out, f.Kind+" in "+f.Path)
+		} else {
+			out = append(out, f.Kind)
+		}
+	}
+	return out
+}
+
+// splitContentLine splits a diff line into its marker and content, reporting
+// whether it is an added, removed, or unchanged line. Both annotated
+// (ADDED:/REMOVED:/UNCHANGED:) and unified (+/-/space) diffs are supported.
+func splitContentLine(line string) (string, string, bool) {
+	for _, prefix := range []string{"ADDED: ", "REMOVED: ", "UNCHANGED: "} {
+		if strings.HasPrefix(line, prefix) {
+			return prefix, line[len(prefix):], true
+		}
+	}
+	if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+		return "", "", false
+	}
+	if line != "" && strings.ContainsAny(line[:1], "+- ") {
+		return line[:1], line[1:], true
+	}
+	return "", "", false
+}
+
+// diffPath extracts the new path from a "diff --git a/... b/..." line
+func diffPath(line string) string {
+	if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+		return line[idx+3:]
+	}
+	return strings.TrimPrefix(line, "diff --git ")
+}
+
+// looksRandom reports whether token looks like a generated secret rather than an
+// identifier or path: upper and lower case in similar amounts, several digits,
+// and high entropy
+func looksRandom(token string) bool {
+	var upper, lower, digits int
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digits++
+		}
+	}
+
+	// Identifiers like someLongFunctionName2 are mostly lowercase with few digits
+	letters := upper + lower
+	if letters == 0 || digits < 2 {
+		return false
+	}
+	if ratio := float64(upper) / float64(letters); ratio < 0.25 || ratio > 0.75 {
+		return false
+	}
+	return entropy(token) >= minEntropy
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+
+	var e float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
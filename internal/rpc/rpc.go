@@ -0,0 +1,234 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+	"git-ac/internal/provider"
+	"git-ac/pkg/gitac"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeFailed         = -32000 // Generation failed
+	codeNoChanges      = -32001 // Nothing is staged
+	codeNoConsent      = -32002 // The repository hasn't confirmed sending its diff to the remote provider
+	codeCanceled       = -32800 // The request was canceled
+)
+
+// maxMessageBytes limits the size of one message, which may contain a diff
+const maxMessageBytes = 16 << 20
+
+// Server speaks JSON-RPC 2.0 over a stream with one message per line, for
+// editor extensions. Requests run concurrently and can be canceled.
+type Server struct {
+	cfg      *config.Config
+	provider provider.LLMProvider
+
+	writeMu sync.Mutex
+	out     *json.Encoder
+
+	pendingMu sync.Mutex
+	pending   map[string]context.CancelFunc // Cancels in-flight requests, by ID
+}
+
+// New creates a server that generates messages with p
+func New(cfg *config.Config, p provider.LLMProvider) *Server {
+	return &Server{
+		cfg:      cfg,
+		provider: p,
+		pending:  make(map[string]context.CancelFunc),
+	}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// GenerateParams are the parameters of generate and regenerate
+type GenerateParams struct {
+	Dir      string `json:"dir"`                // Directory inside the repository
+	Diff     string `json:"diff,omitempty"`     // Diff to describe instead of the staged changes
+	Previous string `json:"previous,omitempty"` // regenerate: the message to revise
+	Feedback string `json:"feedback,omitempty"` // regenerate: what the user wants changed
+}
+
+// GenerateResult is the result of generate and regenerate
+type GenerateResult struct {
+	Message    string          `json:"message"`
+	Candidates []llm.Candidate `json:"candidates"`
+	Withheld   []string        `json:"withheld,omitempty"`
+	Redacted   []string        `json:"redacted,omitempty"`
+}
+
+// CancelParams are the parameters of cancel
+type CancelParams struct {
+	ID json.RawMessage `json:"id"` // ID of the request to cancel
+}
+
+// Serve reads requests from r and writes responses to w until r is closed,
+// then waits for in-flight requests to finish
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = json.NewEncoder(w)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(nullID, nil, &rpcError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			id := req.ID
+			if id == nil {
+				id = nullID
+			}
+			s.reply(id, nil, &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+
+		// Register the request before handling it, so a cancel that follows
+		// right behind it always finds it
+		ctx, cancel := context.WithCancel(context.Background())
+		if req.ID != nil {
+			s.pendingMu.Lock()
+			s.pending[string(req.ID)] = cancel
+			s.pendingMu.Unlock()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.finish(req.ID, cancel)
+			s.handle(ctx, req)
+		}()
+	}
+	return scanner.Err()
+}
+
+// finish forgets a request once it has been answered
+func (s *Server) finish(id json.RawMessage, cancel context.CancelFunc) {
+	cancel()
+	if id != nil {
+		s.pendingMu.Lock()
+		delete(s.pending, string(id))
+		s.pendingMu.Unlock()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req request) {
+	switch req.Method {
+	case "generate", "regenerate":
+		var params GenerateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.reply(req.ID, nil, &rpcError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		if req.Method == "regenerate" && params.Feedback == "" {
+			s.reply(req.ID, nil, &rpcError{Code: codeInvalidParams, Message: "regenerate needs feedback"})
+			return
+		}
+		result, rpcErr := s.generate(ctx, params)
+		if rpcErr != nil {
+			s.reply(req.ID, nil, rpcErr)
+			return
+		}
+		s.reply(req.ID, result, nil)
+	case "cancel":
+		var params CancelParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.reply(req.ID, nil, &rpcError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		s.pendingMu.Lock()
+		if cancel, ok := s.pending[string(params.ID)]; ok {
+			cancel()
+		}
+		s.pendingMu.Unlock()
+		s.reply(req.ID, true, nil)
+	default:
+		s.reply(req.ID, nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method: %s", req.Method)})
+	}
+}
+
+// generate runs a generation, which stops when ctx is canceled
+func (s *Server) generate(ctx context.Context, params GenerateParams) (*GenerateResult, *rpcError) {
+	result, err := gitac.Generate(ctx, gitac.Options{
+		Config:   s.cfg,
+		Dir:      params.Dir,
+		Diff:     params.Diff,
+		Provider: s.provider,
+		// There's no one to ask, so a remote provider needs consent given beforehand
+		RequireConsent:  true,
+		PreviousMessage: params.Previous,
+		Feedback:        params.Feedback,
+	})
+	if err != nil {
+		code := codeFailed
+		switch {
+		case ctx.Err() != nil:
+			code = codeCanceled
+		case errors.Is(err, gitac.ErrNoChanges):
+			code = codeNoChanges
+		case errors.Is(err, gitac.ErrNoConsent):
+			code = codeNoConsent
+		}
+		return nil, &rpcError{Code: code, Message: err.Error()}
+	}
+
+	return &GenerateResult{
+		Message:    result.Message,
+		Candidates: result.Candidates,
+		Withheld:   result.Withheld,
+		Redacted:   result.Redacted,
+	}, nil
+}
+
+// nullID is the ID of responses to requests whose ID couldn't be read
+var nullID = json.RawMessage("null")
+
+// reply writes a response, unless the request was a notification
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if id == nil {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.out.Encode(response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
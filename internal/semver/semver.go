@@ -0,0 +1,133 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"git-ac/internal/llm"
+)
+
+// versionPattern matches a release version tag such as v1.2.3 or 1.2.3;
+// prereleases and build metadata are not releases to bump from
+var versionPattern = regexp.MustCompile(`^(v?)(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)$`)
+
+// breakingFooterPattern matches a BREAKING CHANGE footer line
+var breakingFooterPattern = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+
+// Version is a semantic version
+type Version struct {
+	Prefix string // "v" or ""
+	Major  int
+	Minor  int
+	Patch  int
+}
+
+// Parse parses a release version tag, reporting whether it is one
+func Parse(tag string) (Version, bool) {
+	m := versionPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return Version{}, false
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	patch, _ := strconv.Atoi(m[4])
+	return Version{Prefix: m[1], Major: major, Minor: minor, Patch: patch}, true
+}
+
+// String formats the version as a tag
+func (v Version) String() string {
+	return fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is an earlier version than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// Bump is the kind of release a set of changes calls for
+type Bump int
+
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+// Next returns the version after v for a release of the given kind. Before
+// 1.0.0, breaking changes bump the minor version rather than the major one.
+func (v Version) Next(b Bump) Version {
+	if b == BumpMajor && v.Major == 0 {
+		b = BumpMinor
+	}
+	switch b {
+	case BumpMajor:
+		return Version{Prefix: v.Prefix, Major: v.Major + 1}
+	case BumpMinor:
+		return Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor + 1}
+	case BumpPatch:
+		return Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	default:
+		return v
+	}
+}
+
+// Analysis counts the kinds of conventional commits in a release
+type Analysis struct {
+	Commits  int
+	Breaking int // Commits marked with ! or a BREAKING CHANGE footer
+	Features int // feat commits
+	Fixes    int // fix and perf commits
+	Bump     Bump
+}
+
+// Analyze determines the release that commits with the given messages call
+// for: major for breaking changes, minor for features, and patch for fixes.
+// Other commits, including ones that aren't conventional, don't call for one.
+func Analyze(messages []string) Analysis {
+	a := Analysis{Commits: len(messages)}
+	for _, message := range messages {
+		subject, _, _ := strings.Cut(message, "\n")
+		h, ok := llm.ParseHeader(subject)
+		switch {
+		case h.Breaking || breakingFooterPattern.MatchString(message):
+			a.Breaking++
+		case !ok:
+		case strings.EqualFold(h.Type, "feat"):
+			a.Features++
+		case strings.EqualFold(h.Type, "fix"), strings.EqualFold(h.Type, "perf"):
+			a.Fixes++
+		}
+	}
+
+	switch {
+	case a.Breaking > 0:
+		a.Bump = BumpMajor
+	case a.Features > 0:
+		a.Bump = BumpMinor
+	case a.Fixes > 0:
+		a.Bump = BumpPatch
+	}
+	return a
+}
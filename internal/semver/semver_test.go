@@ -0,0 +1,113 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Version
+		ok   bool
+	}{
+		{"v1.2.3", Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3}, true},
+		{"0.10.0", Version{Major: 0, Minor: 10}, true},
+		{"v1.2.3-rc.1", Version{}, false},
+		{"v1.2.3+build", Version{}, false},
+		{"v01.2.3", Version{}, false},
+		{"v1.2", Version{}, false},
+		{"release-1.2.3", Version{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Parse(tt.tag)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.String() != tt.tag {
+			t.Errorf("Parse(%q).String() = %q", tt.tag, got.String())
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.3", "v1.2.4", true},
+		{"v1.2.9", "v1.10.0", true},
+		{"v1.9.9", "v2.0.0", true},
+		{"v1.2.3", "1.2.3", false},
+		{"v2.0.0", "v1.9.9", false},
+	}
+
+	for _, tt := range tests {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		if got := a.Less(b); got != tt.want {
+			t.Errorf("%s.Less(%s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		version string
+		bump    Bump
+		want    string
+	}{
+		{"v1.2.3", BumpMajor, "v2.0.0"},
+		{"v1.2.3", BumpMinor, "v1.3.0"},
+		{"v1.2.3", BumpPatch, "v1.2.4"},
+		{"v1.2.3", BumpNone, "v1.2.3"},
+		{"0.4.1", BumpMajor, "0.5.0"}, // Breaking changes before 1.0.0 bump the minor version
+	}
+
+	for _, tt := range tests {
+		v, _ := Parse(tt.version)
+		if got := v.Next(tt.bump).String(); got != tt.want {
+			t.Errorf("%s.Next(%s) = %s, want %s", tt.version, tt.bump, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     Analysis
+	}{
+		{
+			name:     "fixes",
+			messages: []string{"fix: handle empty input", "perf: avoid copying", "docs: update guide"},
+			want:     Analysis{Commits: 3, Fixes: 2, Bump: BumpPatch},
+		},
+		{
+			name:     "feature",
+			messages: []string{"fix: handle empty input", "feat(api): add tokens"},
+			want:     Analysis{Commits: 2, Features: 1, Fixes: 1, Bump: BumpMinor},
+		},
+		{
+			name:     "breaking header",
+			messages: []string{"feat!: drop v1 tokens", "feat: add tokens"},
+			want:     Analysis{Commits: 2, Breaking: 1, Features: 1, Bump: BumpMajor},
+		},
+		{
+			name:     "breaking footer",
+			messages: []string{"refactor: rework parsing\n\nBREAKING CHANGE: the v1 format is rejected"},
+			want:     Analysis{Commits: 1, Breaking: 1, Bump: BumpMajor},
+		},
+		{
+			name:     "no conventional commits",
+			messages: []string{"Update stuff", "chore: bump linter"},
+			want:     Analysis{Commits: 2, Bump: BumpNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Analyze(tt.messages); got != tt.want {
+				t.Errorf("Analyze() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
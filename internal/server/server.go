@@ -0,0 +1,312 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"git-ac/internal/config"
+	"git-ac/internal/llm"
+	"git-ac/internal/provider"
+	"git-ac/pkg/gitac"
+)
+
+// maxRequestBytes limits the size of a request body, which may contain a diff
+const maxRequestBytes = 16 << 20
+
+// Server answers generation requests over HTTP, reusing one provider (and its
+// connections and caches) for every request
+type Server struct {
+	cfg       *config.Config
+	provider  provider.LLMProvider
+	token     string
+	root      string
+	allowText bool
+}
+
+// Options configures a Server
+type Options struct {
+	// With a token, the server is a gateway for other machines: every request
+	// must present it, and requests bring their diff and project context
+	// rather than name a directory on this machine
+	Token string
+
+	// Without a token, only repositories in this directory may be named
+	Root string
+
+	// Serve POST /text, which runs any prompt with the provider, without a
+	// token. With one, it's always served.
+	AllowText bool
+}
+
+// New creates a server that generates messages with p
+func New(cfg *config.Config, p provider.LLMProvider, opts Options) *Server {
+	return &Server{cfg: cfg, provider: p, token: opts.Token, root: opts.Root, allowText: opts.AllowText}
+}
+
+// GenerateRequest is the body of a POST to /generate
+type GenerateRequest struct {
+	Dir     string              `json:"dir"`               // Directory inside the repository
+	Diff    string              `json:"diff,omitempty"`    // Diff to describe instead of the staged changes
+	Project *llm.ProjectContext `json:"project,omitempty"` // Project context to use instead of the repository's
+
+	// Commit settings to use instead of the server's, as a gateway client sends
+	// its own. The server keeps its settings for diff size, attempts, cleaning,
+	// structured output, and filters, which suit its model and machine.
+	Commit *config.CommitConfig `json:"commit,omitempty"`
+}
+
+// TextResponse is the result of a successful POST to /text, which runs a
+// free-form prompt ({"system": "...", "user": "..."})
+type TextResponse struct {
+	Text string `json:"text"`
+}
+
+// GenerateResponse is the result of a successful POST to /generate
+type GenerateResponse struct {
+	Message    string          `json:"message"`
+	Candidates []llm.Candidate `json:"candidates"`
+	Withheld   []string        `json:"withheld,omitempty"`
+	Redacted   []string        `json:"redacted,omitempty"`
+	Commit     bool            `json:"commit,omitempty"` // The request's commit settings were followed
+}
+
+// HealthResponse is the result of a GET of /health
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Error    string `json:"error,omitempty"`
+}
+
+// errorResponse is the body of any failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the HTTP handler for the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("POST /generate", s.handleGenerate)
+	if s.token != "" || s.allowText {
+		// Any prompt at all is sent with the operator's credentials
+		mux.HandleFunc("POST /text", s.handleText)
+	}
+	handler := requireJSON(mux)
+	if s.token == "" {
+		return localOnly(handler)
+	}
+	return s.requireToken(handler)
+}
+
+// localOnly rejects requests that may come from a web page rather than a
+// program on this machine: those with an Origin header, which browsers add to
+// cross-site requests, and those for a host other than a loopback address,
+// which a page could make by pointing its own domain at 127.0.0.1
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("requests from web pages aren't allowed"))
+			return
+		}
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !provider.IsLocalEndpoint("http://" + net.JoinHostPort(strings.Trim(host, "[]"), "80")) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q isn't this machine - use localhost or a loopback address", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireJSON rejects requests with a body that isn't JSON. Browsers can't
+// send JSON to another site without asking it first, as they can form data.
+func requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("the request body must be JSON, with Content-Type: application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests without the server's bearer token
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{
+		Status:   "ok",
+		Provider: s.cfg.Provider.Type,
+		Model:    s.cfg.Model(),
+	}
+	status := http.StatusOK
+	if err := s.provider.HealthCheck(r.Context()); err != nil {
+		resp.Status = "error"
+		resp.Error = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req GenerateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	opts := gitac.Options{
+		Config:   s.cfg,
+		Dir:      req.Dir,
+		Diff:     req.Diff,
+		Provider: s.provider,
+		// There's no one to ask, so a remote provider needs consent given beforehand
+		RequireConsent: true,
+	}
+	if s.token != "" {
+		// Gateway clients are on other machines: they send everything, and
+		// asked their users before sending their diff here
+		if req.Dir != "" || req.Diff == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("this gateway doesn't read repositories - send the diff and project context"))
+			return
+		}
+		opts.RequireConsent = false
+		opts.Project = &llm.ProjectContext{}
+	} else {
+		dir, err := s.repoDir(req.Dir)
+		if err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		opts.Dir = dir
+	}
+	ctx := r.Context()
+	if req.Commit != nil {
+		cfg, err := s.clientConfig(*req.Commit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts.Config = cfg
+		ctx = provider.WithCommitConfig(ctx, cfg.Commit)
+	}
+	if req.Project != nil {
+		opts.Project = req.Project
+		opts.PreviousMessage = req.Project.PreviousMessage
+		opts.Feedback = req.Project.Feedback
+	}
+
+	result, err := gitac.Generate(ctx, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gitac.ErrNoChanges):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, gitac.ErrNoConsent):
+			status = http.StatusForbidden
+		}
+		log.Printf("generate in %q: %v", req.Dir, err)
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, GenerateResponse{
+		Message:    result.Message,
+		Candidates: result.Candidates,
+		Withheld:   result.Withheld,
+		Redacted:   result.Redacted,
+		Commit:     req.Commit != nil,
+	})
+}
+
+// clientConfig returns the server's configuration with a client's commit
+// settings, keeping the server's where they concern its model or machine
+func (s *Server) clientConfig(commit config.CommitConfig) (*config.Config, error) {
+	server := s.cfg.Commit
+	commit.DiffTokenLimit = server.DiffTokenLimit
+	commit.LargeDiffThreshold = server.LargeDiffThreshold
+	commit.TwoStage = server.TwoStage
+	commit.StructuredOutput = server.StructuredOutput
+	commit.MaxAttempts = server.MaxAttempts
+	commit.Cleaning = server.Cleaning
+	commit.Filters = server.Filters
+	commit.Stream = false
+	commit.Copy = false
+
+	cfg := *s.cfg
+	cfg.Commit = commit
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("can't use these commit settings: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
+	var prompt llm.Prompt
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&prompt); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	text, err := s.provider.GenerateText(r.Context(), prompt)
+	if err != nil {
+		log.Printf("text: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, TextResponse{Text: text})
+}
+
+// repoDir resolves a requested directory, relative to the directory the server
+// was started in, and checks that it's inside it, symbolic links and all
+func (s *Server) repoDir(dir string) (string, error) {
+	root, err := filepath.EvalSymlinks(s.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", s.root, err)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.root, dir)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s isn't inside %s, where the server was started", dir, s.root)
+	}
+	return resolved, nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ok answers every request that reaches it with 200 OK
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestLocalOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   int
+	}{
+		{"localhost", "localhost:7878", "", http.StatusOK},
+		{"loopback address", "127.0.0.1:7878", "", http.StatusOK},
+		{"IPv6 loopback address", "[::1]:7878", "", http.StatusOK},
+		{"host without a port", "localhost", "", http.StatusOK},
+		{"another host", "attacker.example:7878", "", http.StatusForbidden},
+		{"web page", "localhost:7878", "https://attacker.example", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			localOnly(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		want        int
+	}{
+		{"JSON", http.MethodPost, "application/json", http.StatusOK},
+		{"JSON with a charset", http.MethodPost, "application/json; charset=utf-8", http.StatusOK},
+		{"form data", http.MethodPost, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"no content type", http.MethodPost, "", http.StatusUnsupportedMediaType},
+		{"GET without a body", http.MethodGet, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/generate", strings.NewReader("{}"))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			requireJSON(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoDir(t *testing.T) {
+	// Resolved, since the temporary directory may be behind a link, as on macOS
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "repo"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	s := New(nil, nil, Options{Root: root})
+
+	tests := []struct {
+		dir  string
+		want string // "" if the directory is refused
+	}{
+		{"repo", filepath.Join(root, "repo")},
+		{filepath.Join(root, "repo"), filepath.Join(root, "repo")},
+		{"", root},
+		{"..", ""},
+		{"../outside", ""},
+		{outside, ""},
+		{"link", ""},
+		{"missing", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := s.repoDir(tt.dir)
+		if tt.want == "" && err == nil || tt.want != "" && (err != nil || got != tt.want) {
+			t.Errorf("repoDir(%q) = %q, %v, want %q", tt.dir, got, err, tt.want)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	s := New(nil, nil, Options{Token: "s3cret"})
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"token", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"token without Bearer", "s3cret", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			s.requireToken(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
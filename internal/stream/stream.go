@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"fmt"
+	"os"
+
+	"git-ac/internal/color"
+)
+
+// defaultWidth is assumed when the terminal's width can't be determined
+const defaultWidth = 80
+
+// Terminal shows streamed text faintly in a terminal, and erases it again
+type Terminal struct {
+	out   *os.File
+	width int
+	col   int // Column the cursor is in
+	rows  int // Rows written below the first
+}
+
+// NewTerminal returns a Terminal writing to out, which must be a terminal
+func NewTerminal(out *os.File) *Terminal {
+	width := terminalWidth(out)
+	if width <= 0 {
+		width = defaultWidth
+	}
+	return &Terminal{out: out, width: width}
+}
+
+// Write shows text, keeping track of the rows it takes up
+func (t *Terminal) Write(text string) {
+	for _, r := range text {
+		switch r {
+		case '\n':
+			t.rows++
+			t.col = 0
+		case '\r':
+			t.col = 0
+		case '\t':
+			t.advance(8 - t.col%8)
+		default:
+			t.advance(1)
+		}
+	}
+	_, _ = fmt.Fprint(t.out, color.Faint(text))
+}
+
+func (t *Terminal) advance(n int) {
+	for i := 0; i < n; i++ {
+		// The terminal wraps when a character is written past the last column
+		if t.col == t.width {
+			t.rows++
+			t.col = 0
+		}
+		t.col++
+	}
+}
+
+// Clear erases everything written, leaving the cursor where the text began
+func (t *Terminal) Clear() {
+	_, _ = fmt.Fprint(t.out, "\r\033[2K")
+	for i := 0; i < t.rows; i++ {
+		_, _ = fmt.Fprint(t.out, "\033[1A\033[2K")
+	}
+	t.col, t.rows = 0, 0
+}
+
+// Supported reports whether out is a terminal that streamed text can be shown
+// and erased in
+func Supported(out *os.File) bool {
+	return terminalWidth(out) > 0
+}
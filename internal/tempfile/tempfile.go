@@ -0,0 +1,97 @@
+// Package tempfile creates temporary files that are removed even if git-ac is
+// interrupted before it gets to remove them
+package tempfile
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	mu      sync.Mutex
+	paths   = map[string]bool{} // Temporary files and directories not yet removed
+	signals chan os.Signal      // Receives interrupts while there are any
+)
+
+// Create creates a temporary file in dir, named from pattern as with
+// os.CreateTemp. If dir is "" or the file can't be created there, it is created
+// in the system's temporary directory instead. Remove it with Remove.
+func Create(dir, pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil && dir != "" {
+		f, err = os.CreateTemp("", pattern)
+	}
+	if err != nil {
+		return nil, err
+	}
+	track(f.Name())
+	return f, nil
+}
+
+// Dir creates a temporary directory in the system's temporary directory,
+// named from pattern as with os.MkdirTemp. Remove it with Remove.
+func Dir(pattern string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	track(dir)
+	return dir, nil
+}
+
+// Remove removes a temporary file or directory made by Create or Dir
+func Remove(path string) {
+	_ = os.RemoveAll(path)
+
+	mu.Lock()
+	defer mu.Unlock()
+	delete(paths, path)
+	if len(paths) == 0 && signals != nil {
+		signal.Stop(signals)
+		close(signals)
+		signals = nil
+	}
+}
+
+// RemoveAll removes every temporary file and directory not yet removed. main
+// defers it, so they are removed if git-ac panics.
+func RemoveAll() {
+	mu.Lock()
+	pending := make([]string, 0, len(paths))
+	for path := range paths {
+		pending = append(pending, path)
+	}
+	mu.Unlock()
+
+	for _, path := range pending {
+		Remove(path)
+	}
+}
+
+// track records path for removal. While there are temporary files, an
+// interrupt removes them before exiting, as it would without them.
+func track(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	paths[path] = true
+	if signals != nil {
+		return
+	}
+
+	signals = make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func(signals chan os.Signal) {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		RemoveAll()
+		code := 130
+		if sig == syscall.SIGTERM {
+			code = 143
+		}
+		os.Exit(code)
+	}(signals)
+}
@@ -0,0 +1,32 @@
+// Package xdg locates git-ac's files following the XDG Base Directory
+// Specification, or on Windows, where Windows programs keep them
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigHome returns $XDG_CONFIG_HOME, or ~/.config (%APPDATA% on Windows)
+func ConfigHome() (string, error) {
+	return dir("XDG_CONFIG_HOME", defaultConfigHome)
+}
+
+// CacheHome returns $XDG_CACHE_HOME, or ~/.cache (%LOCALAPPDATA% on Windows)
+func CacheHome() (string, error) {
+	return dir("XDG_CACHE_HOME", defaultCacheHome)
+}
+
+// StateHome returns $XDG_STATE_HOME, or ~/.local/state (%LOCALAPPDATA% on Windows)
+func StateHome() (string, error) {
+	return dir("XDG_STATE_HOME", defaultStateHome)
+}
+
+// dir returns the directory in the environment variable env, or else def's.
+// As the specification requires, relative paths in env are ignored.
+func dir(env string, def func() (string, error)) (string, error) {
+	if path := os.Getenv(env); filepath.IsAbs(path) {
+		return path, nil
+	}
+	return def()
+}
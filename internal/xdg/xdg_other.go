@@ -0,0 +1,30 @@
+//go:build !windows
+
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func defaultConfigHome() (string, error) {
+	return underHome(".config")
+}
+
+func defaultCacheHome() (string, error) {
+	return underHome(".cache")
+}
+
+func defaultStateHome() (string, error) {
+	return underHome(filepath.Join(".local", "state"))
+}
+
+// underHome returns path under the user's home directory
+func underHome(path string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path), nil
+}
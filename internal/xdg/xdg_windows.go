@@ -0,0 +1,29 @@
+//go:build windows
+
+package xdg
+
+import (
+	"fmt"
+	"os"
+)
+
+func defaultConfigHome() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the application data directory: %w", err)
+	}
+	return dir, nil
+}
+
+func defaultCacheHome() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the local application data directory: %w", err)
+	}
+	return dir, nil
+}
+
+// defaultStateHome returns %LOCALAPPDATA%, since Windows has no separate place for state
+func defaultStateHome() (string, error) {
+	return defaultCacheHome()
+}
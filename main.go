@@ -1,42 +1,236 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"os/user"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"git-ac/internal/audit"
+	"git-ac/internal/clipboard"
+	"git-ac/internal/color"
 	"git-ac/internal/config"
+	"git-ac/internal/debuglog"
 	"git-ac/internal/editor"
+	"git-ac/internal/gha"
 	"git-ac/internal/git"
+	"git-ac/internal/history"
+	"git-ac/internal/llm"
+	"git-ac/internal/lock"
+	"git-ac/internal/pregen"
+	"git-ac/internal/prompt"
 	"git-ac/internal/provider"
+	"git-ac/internal/rpc"
+	"git-ac/internal/semver"
+	"git-ac/internal/server"
+	"git-ac/internal/stream"
+	"git-ac/internal/tempfile"
+	"git-ac/pkg/gitac"
 )
 
 var version = "<dev>"
 
+// buildDate is when the binary was built, set like version at build time
+var buildDate = ""
+
+var (
+	editFlag       bool
+	allFlag        bool
+	helpFlag       bool
+	versionFlag    bool
+	typeFlag       string
+	scopeFlag      string
+	subjectFlag    string
+	noBodyFlag     bool
+	copyFlag       bool
+	modelFlag      string
+	providerFlag   string
+	colorFlag      string
+	yesFlag        bool
+	debugLogFlag   string
+	candidatesFlag string
+	listenFlag     string
+	rpcFlag        bool
+	ghaFlag        bool
+	ciFlag         bool
+	timeBudgetFlag string
+	hookTypeFlag   string
+	samplesFlag    string
+	countFlag      string
+	authFlag       string
+	tagFlag        bool
+	daysFlag       string
+
+	noHealthCheckFlag  bool
+	allowConflictsFlag bool
+	jsonFlag           bool
+	showDiffFlag       bool
+	statsFlag          bool
+	allowTextFlag      bool
+)
+
+// timeBudget limits how long generation may take; 0 means no limit
+var timeBudget time.Duration
+
+// defaultTimeBudget is the time budget in --ci mode without --time-budget
+const defaultTimeBudget = 5 * time.Minute
+
+// Exit codes in --ci mode, so pipelines can branch on the outcome
+const (
+	exitFailure       = 1 // Any other error
+	exitNoChanges     = 2 // Nothing is staged
+	exitUnreachable   = 3 // The provider couldn't be reached
+	exitInvalidOutput = 4 // The model didn't produce a valid message
+	exitCommitFailed  = 5 // git commit failed
+	exitTimeBudget    = 6 // Generation didn't finish within the time budget
+	exitTimeout       = 7 // The provider didn't answer a request within provider.timeout
+)
+
 var (
-	editFlag    bool
-	allFlag     bool
-	helpFlag    bool
-	versionFlag bool
+	errCommitFailed = errors.New("failed to commit")
+	errTimeBudget   = errors.New("time budget exceeded")
 )
 
-// parseFlags handles custom flag parsing to support combined flags like -ae
+// defaultListen is the address git-ac serve listens on without --listen
+const defaultListen = "127.0.0.1:7687"
+
+// command is the subcommand to run, or "" to generate a commit message
+var command string
+
+// commandArgs are the subcommand's positional arguments
+var commandArgs []string
+
+// commands lists the subcommands
+var commands = map[string]bool{
+	"serve": true,
+	"hook":  true,
+	"stats": true,
+	"bench": true,
+	"eval":  true,
+	"warm":  true,
+
+	"next-version":  true,
+	"explain":       true,
+	"describe-last": true,
+	"review":        true,
+	"why":           true,
+	"standup":       true,
+	"release-pr":    true,
+	"watch":         true,
+}
+
+// valueFlags maps long flags that take a value to the variable receiving it
+var valueFlags = map[string]*string{
+	"--type":        &typeFlag,
+	"--scope":       &scopeFlag,
+	"--subject":     &subjectFlag,
+	"--model":       &modelFlag,
+	"--provider":    &providerFlag,
+	"--color":       &colorFlag,
+	"--debug-log":   &debugLogFlag,
+	"--candidates":  &candidatesFlag,
+	"--listen":      &listenFlag,
+	"--time-budget": &timeBudgetFlag,
+	"--hook-type":   &hookTypeFlag,
+	"--samples":     &samplesFlag,
+	"--count":       &countFlag,
+	"--auth":        &authFlag,
+	"--days":        &daysFlag,
+}
+
+// parseFlags handles custom flag parsing to support combined flags like -ae.
+// As with git, "--" ends the flags, so arguments after it may start with "-".
 func parseFlags(args []string) error {
+	flagsDone := false
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
-		if !strings.HasPrefix(arg, "-") {
+		if arg == "--" && !flagsDone {
+			flagsDone = true
+			continue
+		}
+
+		if flagsDone || !strings.HasPrefix(arg, "-") {
+			if command == "" && commands[arg] {
+				command = arg
+				continue
+			}
+			if command == "hook" || command == "bench" || command == "explain" || command == "why" || command == "release-pr" {
+				commandArgs = append(commandArgs, arg)
+				continue
+			}
 			return fmt.Errorf("unexpected argument: %s", arg)
 		}
 
-		// Handle long flags like --version
+		// Handle long flags like --version, and valued flags like --type fix or --type=fix
 		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg, "=")
+
+			if target, ok := valueFlags[name]; ok {
+				if !hasValue {
+					if i+1 >= len(args) {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+					i++
+					value = args[i]
+				}
+				*target = value
+				continue
+			}
+
+			if hasValue {
+				return fmt.Errorf("flag %s does not take a value", name)
+			}
+
 			switch arg {
+			case "--all":
+				allFlag = true
+			case "--edit":
+				editFlag = true
 			case "--version":
 				versionFlag = true
 			case "--help":
 				helpFlag = true
+			case "--no-body":
+				noBodyFlag = true
+			case "--copy":
+				copyFlag = true
+			case "--yes":
+				yesFlag = true
+			case "--rpc":
+				rpcFlag = true
+			case "--gha":
+				ghaFlag = true
+			case "--ci":
+				ciFlag = true
+			case "--tag":
+				tagFlag = true
+			case "--no-health-check":
+				noHealthCheckFlag = true
+			case "--allow-conflict-markers":
+				allowConflictsFlag = true
+			case "--json":
+				jsonFlag = true
+			case "--show-diff":
+				showDiffFlag = true
+			case "--stats":
+				statsFlag = true
+			case "--allow-text":
+				allowTextFlag = true
 			default:
 				return fmt.Errorf("unknown flag: %s", arg)
 			}
@@ -56,6 +250,8 @@ func parseFlags(args []string) error {
 				helpFlag = true
 			case 'v':
 				versionFlag = true
+			case 'y':
+				yesFlag = true
 			default:
 				return fmt.Errorf("unknown flag: -%c", char)
 			}
@@ -65,6 +261,9 @@ func parseFlags(args []string) error {
 }
 
 func main() {
+	// Remove temporary files even if git-ac panics
+	defer tempfile.RemoveAll()
+
 	// Parse flags manually to support combined flags
 	if err := parseFlags(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -72,104 +271,1493 @@ func main() {
 		os.Exit(1)
 	}
 
+	if colorFlag != "" {
+		if err := color.SetMode(colorFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if timeBudgetFlag != "" {
+		d, err := time.ParseDuration(timeBudgetFlag)
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --time-budget '%s' (e.g. 90s or 5m)\n", timeBudgetFlag)
+			os.Exit(1)
+		}
+		timeBudget = d
+	}
+
+	// CI mode never prompts: it commits without confirmation, or fails
+	if ciFlag {
+		if editFlag {
+			fmt.Fprintf(os.Stderr, "Error: -e cannot be used with --ci\n")
+			os.Exit(1)
+		}
+		yesFlag = true
+		if colorFlag == "" {
+			_ = color.SetMode(color.ModeNever)
+		}
+		if timeBudget == 0 {
+			timeBudget = defaultTimeBudget
+		}
+	}
+
 	if helpFlag {
 		showHelp()
 		return
 	}
 
 	if versionFlag {
+		if jsonFlag {
+			if err := printBuildInfo(); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			os.Exit(0)
+		}
 		fmt.Println(version)
 		os.Exit(0)
 	}
 
-	if err := run(); err != nil {
+	if ghaFlag {
+		// Failures are reported as annotations on the workflow run
+		if err := runGHA(); err != nil {
+			gha.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "hook" {
+		// A failing hook would block the commit, so problems are only reported
+		if err := runHook(); err != nil && !errors.Is(err, gitac.ErrNoChanges) {
+			fmt.Fprintf(os.Stderr, "git-ac: %v\n", err)
+		}
+		return
+	}
+
+	var err error
+	switch {
+	case rpcFlag:
+		err = runRPC()
+	case command == "serve":
+		err = runServe()
+	case command == "stats":
+		err = runStats()
+	case command == "bench":
+		err = runBench()
+	case command == "eval":
+		err = runEval()
+	case command == "warm":
+		err = runWarm()
+	case command == "next-version":
+		err = runNextVersion()
+	case command == "explain":
+		err = runExplain()
+	case command == "describe-last":
+		err = runDescribeLast()
+	case command == "review":
+		err = runReview()
+	case command == "why":
+		err = runWhy()
+	case command == "standup":
+		err = runStandup()
+	case command == "release-pr":
+		err = runReleasePR()
+	case command == "watch":
+		err = runWatch()
+	default:
+		err = run()
+	}
+	if err != nil {
+		if ciFlag {
+			log.Printf("Error: %v", err)
+			os.Exit(ciExitCode(err))
+		}
 		log.Fatalf("Error: %v", err)
 	}
 }
 
-func run() error {
-	// Load configuration
+// ciExitCode returns the --ci exit code for err
+func ciExitCode(err error) int {
+	var invalid *llm.ValidationError
+	switch {
+	case errors.Is(err, errTimeBudget):
+		return exitTimeBudget
+	case errors.Is(err, gitac.ErrNoChanges):
+		return exitNoChanges
+	case errors.Is(err, provider.ErrUnreachable):
+		return exitUnreachable
+	case errors.Is(err, provider.ErrTimeout):
+		return exitTimeout
+	case errors.As(err, &invalid):
+		return exitInvalidOutput
+	case errors.Is(err, errCommitFailed):
+		return exitCommitFailed
+	default:
+		return exitFailure
+	}
+}
+
+// applySubject has the model write only the body, for the subject line the
+// user wrote. The subject must follow the convention generated ones do, and
+// sets the type and scope the body is written for.
+func applySubject(cfg *config.Config, subject string) error {
+	if typeFlag != "" || scopeFlag != "" {
+		return fmt.Errorf("--subject cannot be used with --type or --scope - put the type and scope in the subject")
+	}
+	if noBodyFlag {
+		return fmt.Errorf("--subject cannot be used with --no-body - there would be nothing left to generate")
+	}
+	subject = strings.TrimSpace(subject)
+	if err := llm.ValidateMessage(subject, cfg.Commit); err != nil {
+		var invalid *llm.ValidationError
+		if errors.As(err, &invalid) {
+			return fmt.Errorf("invalid --subject: %s", invalid.Reason)
+		}
+		return err
+	}
+
+	h, _ := llm.ParseHeader(subject)
+	cfg.Commit.Subject = subject
+	cfg.Commit.Type = h.Type
+	cfg.Commit.Scope = h.Scope
+	cfg.Commit.IncludeBody = "true"
+	return nil
+}
+
+// interactive reports whether the user can be asked questions
+func interactive() bool {
+	return !ciFlag && prompt.IsInteractive()
+}
+
+// loadConfig loads the configuration and applies command-line overrides
+func loadConfig() (*config.Config, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, warning := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: config file %s\n", warning)
 	}
 
+	// Apply command-line overrides
+	if typeFlag != "" {
+		if !cfg.Commit.HasType(typeFlag) {
+			return nil, fmt.Errorf("invalid commit type '%s' (valid: %s)", typeFlag, strings.Join(cfg.Commit.TypeNames(), ", "))
+		}
+		cfg.Commit.Type = typeFlag
+	}
+	if scopeFlag != "" {
+		if err := cfg.Commit.CheckScope(scopeFlag); err != nil {
+			return nil, fmt.Errorf("invalid --scope: %w", err)
+		}
+		cfg.Commit.Scope = scopeFlag
+	}
+	if subjectFlag != "" {
+		if err := applySubject(cfg, subjectFlag); err != nil {
+			return nil, err
+		}
+	}
+	if noBodyFlag {
+		cfg.Commit.IncludeBody = "false"
+	}
+	if copyFlag {
+		cfg.Commit.Copy = true
+	}
+	if providerFlag != "" {
+		if err := cfg.SelectProvider(providerFlag); err != nil {
+			return nil, fmt.Errorf("invalid --provider: %w", err)
+		}
+	}
+	if modelFlag != "" {
+		cfg.SetModel(modelFlag)
+	}
+	if debugLogFlag != "" {
+		cfg.DebugLog = debugLogFlag
+	}
+	if noHealthCheckFlag && cfg.Provider.Ollama != nil {
+		cfg.Provider.Ollama.SkipHealthCheck = true
+	}
+	if candidatesFlag != "" {
+		n, err := strconv.Atoi(candidatesFlag)
+		if err != nil || n < 1 || n > 9 {
+			return nil, fmt.Errorf("invalid --candidates '%s' (must be 1 to 9)", candidatesFlag)
+		}
+		cfg.Commit.Candidates = n
+	}
+
+	return cfg, nil
+}
+
+func run() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if timeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeBudget)
+		defer cancel()
+	}
+	stats := &provider.Stats{}
+	ctx = provider.WithStats(ctx, stats)
+
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	// Check the provider and load the model while the diff is gathered and the
+	// user answers any questions, so the wait for them overlaps
+	warmedUp := warmUpInBackground(ctx, llmProvider)
+	repo := git.Repo{}
+
 	// Validate we're in a git repository
-	if err := git.ValidateRepository(); err != nil {
+	if err := repo.ValidateRepository(); err != nil {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
 
+	// Keep another git-ac, such as an editor plugin's, from staging or
+	// committing in the repository at the same time
+	runLock, err := lockRepository(repo)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = runLock.Release()
+	}()
+
 	// Stage all changes if -a flag is provided
 	if allFlag {
-		if err := git.StageAllChanges(); err != nil {
+		if err := repo.StageAllChanges(); err != nil {
 			return fmt.Errorf("failed to stage all changes: %w", err)
 		}
 	}
 
-	// Check for staged changes
-	diff, err := git.GetStagedDiff()
-	if err != nil {
-		return fmt.Errorf("failed to get staged changes: %w", err)
+	// Conclude a merge with a merge commit's message, and leave other operations
+	// in progress to git, rather than describe them as ordinary commits
+	switch op := repo.GetOperation(); op {
+	case git.OperationNone:
+	case git.OperationMerge:
+		return commitMerge(ctx, repo, cfg, llmProvider, warmedUp)
+	default:
+		return fmt.Errorf("a %s is in progress - finish it with 'git %s --continue', which uses the message git prepared, or stop it with 'git %s --abort'", op, op, op)
+	}
+
+	// Collect the staged changes while gathering project context: the relevant
+	// parts of README.md (if it exists), any context files, and the team's own
+	// instructions. Nothing is sent anywhere yet.
+	var (
+		diff    string
+		diffErr error
+		project gitac.ProjectContext
+		wg      sync.WaitGroup
+	)
+	wg.Go(func() { diff, diffErr = gitac.StagedDiff(repo.Dir, cfg) })
+	wg.Go(func() { project = gitac.LoadProjectContext(repo.Dir, cfg) })
+	wg.Wait()
+	if diffErr != nil {
+		// Report a problem with the provider too, rather than one at a time
+		return errors.Join(diffErr, <-warmedUp)
 	}
 
 	if diff == "" {
 		if allFlag {
-			return fmt.Errorf("no changes to stage")
+			return fmt.Errorf("%w after staging modified files", gitac.ErrNoChanges)
 		}
-		return fmt.Errorf("no staged changes found (use -a to stage modified files)")
+		return fmt.Errorf("%w (use -a to stage modified files)", gitac.ErrNoChanges)
 	}
 
-	// Get README.md content for context (if it exists)
-	readme := git.GetReadmeContent()
+	if err := checkConflictMarkers(diff); err != nil {
+		return err
+	}
 
-	// Generate commit message using configured provider
-	llmProvider, err := provider.NewProvider(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create LLM provider: %w", err)
+	if showDiffFlag || (cfg.Diff.Show && interactive()) {
+		ok, err := showStagedChanges(repo)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Commit aborted.")
+			return nil
+		}
 	}
 
-	commitMsg, err := llmProvider.GenerateCommitMessage(diff, readme)
+	// Describe LFS assets, collapse moved code, and keep secrets and sensitive files out of the prompt
+	prepared, err := gitac.PrepareDiff(diff, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to generate commit message: %w", err)
+		return err
+	}
+	diff = prepared.Diff
+	if prepared.MovedBlocks > 0 {
+		color.FaintPrintf("Collapsed %d moved blocks of code.\n", prepared.MovedBlocks)
+	}
+	if prepared.LFSAssets > 0 {
+		color.FaintPrintf("Described %d Git LFS assets by name and size.\n", prepared.LFSAssets)
+	}
+	if prepared.Transcoded > 0 {
+		color.FaintPrintf("Converted %d lines that weren't UTF-8 text.\n", prepared.Transcoded)
+	}
+	if len(prepared.Withheld) > 0 {
+		color.FaintPrintf("Withheld the contents of %d files matching privacy.redact_paths.\n", len(prepared.Withheld))
+	}
+	if len(prepared.Redacted) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: redacted possible secrets from the diff before sending it:\n")
+		for _, f := range prepared.Redacted {
+			fmt.Fprintf(os.Stderr, "  - %s\n", f)
+		}
+	}
+
+	// Make sure the diff may leave this machine before sending it anywhere
+	if err := checkRemotePolicy(repo, cfg); err != nil {
+		return err
+	}
+
+	// Tell the model what the changes are for, from the Linear issue the branch is named after
+	issue := gitac.FindIssue(ctx, repo.Dir, cfg)
+	if issue != nil {
+		project.Issue = issue.String()
+		color.FaintPrintf("Linear issue %s\n", issue)
+	}
+
+	// Use the messages git-ac watch generated for these changes, if it has.
+	// Otherwise, generate them using the configured provider, once it's ready.
+	started, usageBefore := time.Now(), llmProvider.Usage()
+	candidates, pregenerated := pregen.Load(diff, cfg)
+	var generating time.Duration
+	if pregenerated {
+		color.FaintPrintf("Using the message git-ac watch generated for these changes.\n")
+	} else {
+		// Without a message, the user may write one, as provider.on_failure asks
+		failed := func(err error) error {
+			if !cfg.Commit.Copy && interactive() {
+				if draft, ok := fallbackMessage(diff, cfg, project.CommitConfig(cfg.Commit), err); ok {
+					if draft != "" {
+						draft = gitac.AddIssueFooter(draft, issue, cfg)
+					}
+					return commitByHand(repo, draft, err)
+				}
+			}
+			return fmt.Errorf("failed to generate commit message: %w", err)
+		}
+
+		if err := <-warmedUp; err != nil {
+			return failed(err)
+		}
+		generatingStarted := time.Now()
+
+		// Show the message as it's generated, erasing it once the cleaned-up
+		// message can be shown instead
+		generateCtx := ctx
+		if cfg.Commit.Stream && interactive() && color.Enabled() && stream.Supported(os.Stdout) {
+			generateCtx = provider.WithStream(ctx, stream.NewTerminal(os.Stdout))
+		}
+
+		candidates, err = llmProvider.GenerateCandidates(generateCtx, diff, project)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: no commit message within %v", errTimeBudget, timeBudget)
+			}
+			return failed(err)
+		}
+		generating = time.Since(generatingStarted)
+	}
+	usage := llmProvider.Usage().Sub(usageBefore)
+	latency := time.Since(started)
+	if statsFlag && !pregenerated {
+		printStats(stats, usage, generating)
+	}
+
+	// Finish the messages: count the changes, link the Linear issue, then
+	// apply the team's filters
+	for i := range candidates {
+		candidates[i].Message = gitac.AddDiffStatFooter(candidates[i].Message, repo.Dir, cfg)
+		candidates[i].Message = gitac.AddIssueFooter(candidates[i].Message, issue, cfg)
+	}
+	if err := gitac.FilterCandidates(ctx, candidates, repo.Dir, cfg); err != nil {
+		return err
+	}
+
+	// With several candidates, let the user pick; otherwise take the best-ranked one
+	commitMsg := candidates[0].Message
+	if len(candidates) > 1 && !yesFlag && interactive() {
+		commitMsg, err = chooseCandidate(candidates)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record what became of the message, for git-ac stats and the audit log
+	generated := commitMsg
+	record := func(outcome, final string) {
+		if cfg.History.Enabled {
+			recordHistory(history.Entry{
+				Time:             started,
+				Provider:         cfg.Provider.Type,
+				Model:            cfg.Model(),
+				Outcome:          outcome,
+				Generated:        generated,
+				Final:            final,
+				LatencyMS:        latency.Milliseconds(),
+				PromptTokens:     usage.Prompt,
+				CompletionTokens: usage.Completion,
+			})
+		}
+
+		event := audit.Event{Generated: generated, Message: final, Redacted: len(prepared.Redacted), Withheld: prepared.Withheld}
+		switch outcome {
+		case history.OutcomeCommitted:
+			event.Action = audit.ActionCommit
+			event.Commit, _ = repo.GetHead()
+		case history.OutcomeCopied:
+			event.Action = audit.ActionCopy
+		default:
+			return
+		}
+		recordAudit(ctx, repo, cfg, event)
 	}
 
 	// If edit flag is set, open editor
 	if editFlag {
-		editedMsg, err := editor.Edit(commitMsg)
+		editedMsg, err := editor.Edit(commitMsg, editorComment())
 		if err != nil {
 			return fmt.Errorf("failed to edit commit message: %w", err)
 		}
+		if editedMsg == "" {
+			record(history.OutcomeAborted, "")
+			fmt.Fprintln(os.Stderr, "Aborting commit due to empty commit message.")
+			return nil
+		}
 		commitMsg = editedMsg
 	}
 
+	// Copy to the clipboard instead of committing if requested
+	if cfg.Commit.Copy {
+		if err := clipboard.Copy(commitMsg); err != nil {
+			return fmt.Errorf("failed to copy commit message to clipboard: %w", err)
+		}
+		record(history.OutcomeCopied, commitMsg)
+		fmt.Printf("Copied commit message to clipboard:\n%s\n", renderMessage(commitMsg))
+		return nil
+	}
+
+	// Ask before committing unless the user already reviewed the message in the editor
+	if !editFlag && !yesFlag {
+		commitMsg, err = confirmCommit(commitMsg, stagedDiffStat(repo))
+		if err != nil {
+			return err
+		}
+		if commitMsg == "" {
+			record(history.OutcomeAborted, "")
+			fmt.Fprintln(os.Stderr, "Commit aborted.")
+			return nil
+		}
+	}
+
 	// Perform the commit
-	if err := git.Commit(commitMsg); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+	if err := repo.Commit(commitMsg); err != nil {
+		return fmt.Errorf("%w: %w", errCommitFailed, err)
 	}
+	record(history.OutcomeCommitted, commitMsg)
 
-	fmt.Printf("Successfully committed with message:\n%s\n", commitMsg)
+	printCommitted(repo, "Successfully committed with message", commitMsg)
 	return nil
 }
 
+// fallbackMessage returns the message the user starts from when writing the
+// one that couldn't be generated, and whether they write one at all. With
+// provider.on_failure: edit it's empty; with template, it's a template of the
+// changes. If the provider couldn't be reached, the template is offered.
+func fallbackMessage(diff string, cfg *config.Config, commitConfig config.CommitConfig, cause error) (string, bool) {
+	switch {
+	case cfg.Provider.OnFailure == "edit":
+		return "", true
+	case cfg.Provider.OnFailure == "template":
+	case (errors.Is(cause, provider.ErrUnreachable) || errors.Is(cause, provider.ErrTimeout)) && !yesFlag:
+		problem := "Couldn't reach %s."
+		if errors.Is(cause, provider.ErrTimeout) {
+			problem = "%s didn't answer in time."
+		}
+		ok, err := prompt.Confirm(fmt.Sprintf(problem+" Write the message from a template of the changes instead?", cfg.Provider.Type), true)
+		if err != nil || !ok {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return llm.TemplateMessage(diff, commitConfig), true
+}
+
+// commitByHand opens the editor for the user to write the message that
+// couldn't be generated, starting from draft, and commits with it
+func commitByHand(repo git.Repo, draft string, cause error) error {
+	fmt.Fprintf(os.Stderr, "Failed to generate a commit message: %v\n", cause)
+	if draft != "" {
+		fmt.Fprintln(os.Stderr, "Opening the editor with a template of the changes to finish...")
+	} else {
+		fmt.Fprintln(os.Stderr, "Opening the editor to write one yourself...")
+	}
+
+	comment := "git-ac couldn't generate a message: " + cause.Error() + "\n\n" + editorComment()
+	commitMsg, err := editor.Edit(draft, comment)
+	if err != nil {
+		return fmt.Errorf("failed to edit commit message: %w", err)
+	}
+	if commitMsg == "" {
+		fmt.Fprintln(os.Stderr, "Aborting commit due to empty commit message.")
+		return nil
+	}
+
+	if err := repo.Commit(commitMsg); err != nil {
+		return fmt.Errorf("%w: %w", errCommitFailed, err)
+	}
+	printCommitted(repo, "Successfully committed with message", commitMsg)
+	return nil
+}
+
+// printStats prints a one-line breakdown of where the time and tokens of
+// generating the message went, to compare models and find what's slow
+func printStats(stats *provider.Stats, usage provider.TokenUsage, generating time.Duration) {
+	parts := []string{}
+	if d := stats.HealthCheck(); d > 0 {
+		parts = append(parts, fmt.Sprintf("health check %dms", d.Milliseconds()))
+	}
+	parts = append(parts,
+		fmt.Sprintf("prompt %d tokens", usage.Prompt),
+		fmt.Sprintf("generation %dms", generating.Milliseconds()))
+	if usage.Completion > 0 && generating > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f tokens/s", float64(usage.Completion)/generating.Seconds()))
+	}
+	parts = append(parts, fmt.Sprintf("%d retries", stats.Retries()))
+	fmt.Fprintf(os.Stderr, "Stats: %s\n", strings.Join(parts, ", "))
+}
+
+// printCommitted reports the commit just made: its message, then which files
+// it changed and by how much, as git commit's summary does
+func printCommitted(repo git.Repo, heading, commitMsg string) {
+	fmt.Printf("%s:\n%s\n", heading, renderMessage(commitMsg))
+	if stat, err := repo.GetCommitStat("HEAD"); err == nil && stat != "" {
+		fmt.Printf("\n%s\n", color.Faint(stat))
+	}
+}
+
+// buildInfo describes the build, for --version --json
+type buildInfo struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	Modified  bool     `json:"modified,omitempty"` // Built with uncommitted changes
+	BuildDate string   `json:"build_date,omitempty"`
+	GoVersion string   `json:"go_version"`
+	Platform  string   `json:"platform"`
+	Providers []string `json:"providers"`
+}
+
+// printBuildInfo prints the version and build details as JSON, for bug reports
+// and inventories of installed versions
+func printBuildInfo() error {
+	info := buildInfo{
+		Version:   version,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Providers: config.ProviderTypes,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(info)
+}
+
+// lockRepository takes the repository's git-ac.lock, failing with an
+// explanation if another git-ac holds it
+func lockRepository(repo git.Repo) (*lock.Lock, error) {
+	path, err := repo.GetGitPath("git-ac.lock")
+	if err != nil {
+		return nil, err
+	}
+	runLock, err := lock.Acquire(path)
+	if errors.Is(err, lock.ErrHeld) {
+		return nil, fmt.Errorf("another git-ac is running in this repository: %w - wait for it to finish", err)
+	}
+	return runLock, err
+}
+
+// checkConflictMarkers fails if the staged diff adds conflict markers, so an
+// unresolved conflict isn't committed under a confident message, unless
+// --allow-conflict-markers says they are meant to be there
+func checkConflictMarkers(diff string) error {
+	markers := llm.FindConflictMarkers(diff)
+	if len(markers) == 0 {
+		return nil
+	}
+	if !allowConflictsFlag {
+		return fmt.Errorf("the staged changes add conflict markers at %s - resolve the conflicts, or use --allow-conflict-markers if the markers are meant to be there", strings.Join(markers, ", "))
+	}
+	fmt.Fprintf(os.Stderr, "Warning: the staged changes add conflict markers at %s\n", strings.Join(markers, ", "))
+	return nil
+}
+
+// showStagedChanges shows the staged diff, paged and colored as git diff
+// would, and when the user can be asked, whether to go on to describe it
+func showStagedChanges(repo git.Repo) (bool, error) {
+	if err := repo.ShowStagedDiff(color.Enabled()); err != nil {
+		return false, err
+	}
+	if !interactive() || yesFlag {
+		return true, nil
+	}
+	return prompt.Confirm("Generate a commit message for these changes?", true)
+}
+
+// commitMerge concludes the merge in progress. The message keeps the subject
+// git prepared, such as "Merge branch 'feature'", and adds a body summarizing
+// the commits the merge brings in.
+func commitMerge(ctx context.Context, repo git.Repo, cfg *config.Config, llmProvider provider.LLMProvider, warmedUp <-chan error) error {
+	subject, err := repo.GetMergeSubject()
+	if err != nil {
+		return err
+	}
+	commits, err := repo.GetRangeCommits("HEAD", "MERGE_HEAD")
+	if err != nil {
+		return err
+	}
+	diff, err := gitac.StagedDiff(repo.Dir, cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkConflictMarkers(diff); err != nil {
+		return err
+	}
+
+	commitMsg := subject
+	if len(commits) > 0 {
+		if err := checkRemotePolicy(repo, cfg); err != nil {
+			return err
+		}
+		project := gitac.LoadProjectContext(repo.Dir, cfg)
+		if err := <-warmedUp; err != nil {
+			return fmt.Errorf("failed to generate merge message: %w", err)
+		}
+
+		color.FaintPrintf("A merge is in progress; describing the commits it brings in (%d)...\n", len(commits))
+		body, err := llmProvider.GenerateText(ctx, llm.BuildMergePrompt(subject, commitLog(commits, cfg.Commit.DiffTokenLimit), project))
+		if err != nil {
+			return fmt.Errorf("failed to generate merge message: %w", err)
+		}
+		if body = strings.TrimSpace(body); body != "" {
+			commitMsg += "\n\n" + body
+		}
+	}
+	generated := commitMsg
+
+	if editFlag {
+		commitMsg, err = editor.Edit(commitMsg, editorComment())
+		if err != nil {
+			return fmt.Errorf("failed to edit commit message: %w", err)
+		}
+		if commitMsg == "" {
+			fmt.Fprintln(os.Stderr, "Aborting commit due to empty commit message.")
+			return nil
+		}
+	} else if !yesFlag {
+		commitMsg, err = confirmCommit(commitMsg, stagedDiffStat(repo))
+		if err != nil {
+			return err
+		}
+		if commitMsg == "" {
+			fmt.Fprintln(os.Stderr, "Commit aborted.")
+			return nil
+		}
+	}
+
+	if err := repo.Commit(commitMsg); err != nil {
+		return fmt.Errorf("%w: %w", errCommitFailed, err)
+	}
+	head, _ := repo.GetHead()
+	recordAudit(ctx, repo, cfg, audit.Event{Action: audit.ActionCommit, Generated: generated, Message: commitMsg, Commit: head})
+
+	printCommitted(repo, "Successfully committed merge with message", commitMsg)
+	return nil
+}
+
+// recordHistory appends an entry to the history log. A log that can't be
+// written only gets a notice.
+func recordHistory(entry history.Entry) {
+	path, err := history.DefaultPath()
+	if err == nil {
+		err = history.Append(path, entry)
+	}
+	if err != nil {
+		color.FaintPrintf("Couldn't record history: %v\n", err)
+	}
+}
+
+// recordAudit completes an audit event with who used which provider, and
+// delivers it to the configured audit sinks, if any. A failure only gets a
+// warning, since the message has already been used.
+func recordAudit(ctx context.Context, repo git.Repo, cfg *config.Config, event audit.Event) {
+	logger := audit.New(cfg.Audit)
+	if !logger.Enabled() {
+		return
+	}
+
+	event.Time = time.Now()
+	event.User = fmt.Sprintf("%s <%s>", repo.GetConfig("user.name"), repo.GetConfig("user.email"))
+	if u, err := user.Current(); err == nil {
+		event.Login = u.Username
+	}
+	event.Hostname, _ = os.Hostname()
+	event.Repository, _ = repo.GetRepositoryRoot()
+	event.Remote = audit.StripCredentials(repo.GetConfig("remote.origin.url"))
+	event.Provider = cfg.Provider.Type
+	event.Model = cfg.Model()
+	event.Endpoint = audit.StripCredentials(provider.Endpoint(cfg))
+	event.Version = version
+
+	if err := logger.Record(ctx, event); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record audit event: %v\n", err)
+	}
+}
+
+// runStats summarizes the history log per provider and model
+func runStats() error {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+	entries, err := history.Read(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history yet. git-ac records each generated message and what became of it (history.enabled).")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tMODEL\tGENERATED\tACCEPTED\tAVG EDIT\tAVG LATENCY\tTOKENS IN\tTOKENS OUT")
+	for _, s := range history.Aggregate(entries) {
+		editDistance := "-"
+		if s.Used > 0 {
+			editDistance = fmt.Sprintf("%.1f", s.EditDistance)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.0f%%\t%s\t%v\t%d\t%d\n",
+			s.Provider, s.Model, s.Generations, s.AcceptanceRate()*100, editDistance,
+			s.Latency.Round(100*time.Millisecond), s.PromptTokens, s.CompletionTokens)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println(color.Faint("ACCEPTED: messages committed or copied unchanged. AVG EDIT: characters changed"))
+	fmt.Println(color.Faint("before committing or copying, on average. From " + path))
+	return nil
+}
+
+// warmUpInBackground starts checking the provider and loading the model, so
+// it's ready by the time the prompt is. The requests hold nothing from the
+// repository. The returned channel delivers the result once it's done.
+func warmUpInBackground(ctx context.Context, llmProvider provider.LLMProvider) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := llmProvider.WarmUp(ctx)
+		done <- err
+	}()
+	return done
+}
+
+// runWarm loads the model and keeps it loaded for ollama.keep_alive
+func runWarm() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	started := time.Now()
+	loaded, err := llmProvider.WarmUp(ctx)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		fmt.Printf("Nothing to load: %s providers load models on demand.\n", cfg.Provider.Type)
+		return nil
+	}
+	fmt.Printf("Loaded %s in %v.\n", cfg.Model(), time.Since(started).Round(100*time.Millisecond))
+	return nil
+}
+
+// runNextVersion suggests the next semantic version from the conventional
+// commits since the latest version tag, and creates it as a tag with --tag.
+// The version alone is printed to stdout, for scripts.
+func runNextVersion() error {
+	repo := git.Repo{}
+	if err := repo.ValidateRepository(); err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	tags, err := repo.GetMergedTags()
+	if err != nil {
+		return err
+	}
+	var latest semver.Version
+	latestTag := ""
+	for _, tag := range tags {
+		if v, ok := semver.Parse(tag); ok && (latestTag == "" || latest.Less(v)) {
+			latest, latestTag = v, tag
+		}
+	}
+	if latestTag == "" {
+		latest.Prefix = "v"
+	}
+
+	messages, err := repo.GetCommitMessagesSince(latestTag)
+	if err != nil {
+		return err
+	}
+	analysis := semver.Analyze(messages)
+
+	since := "since " + latestTag
+	if latestTag == "" {
+		since = "and no version tags"
+	}
+	color.FaintPrintf("%d commits %s: %d breaking, %d features, %d fixes\n",
+		analysis.Commits, since, analysis.Breaking, analysis.Features, analysis.Fixes)
+	if analysis.Bump == semver.BumpNone {
+		fmt.Fprintf(os.Stderr, "No release needed: no breaking changes, features, or fixes %s.\n", since)
+		return nil
+	}
+
+	next := latest.Next(analysis.Bump)
+	fmt.Println(next)
+
+	if tagFlag {
+		if err := repo.CreateTag(next.String(), next.String()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created tag %s.\n", next)
+	}
+	return nil
+}
+
+// newProvider creates the configured provider, with the debug log if one is
+// configured, and checks that a --model override exists. The caller closes the
+// returned log, which may be nil.
+func newProvider(ctx context.Context, cfg *config.Config) (provider.LLMProvider, *debuglog.Logger, error) {
+	// Record requests and responses if asked to
+	var debugLog *debuglog.Logger
+	if cfg.DebugLog != "" {
+		var err error
+		debugLog, err = debuglog.Open(cfg.DebugLog, fmt.Sprintf("git-ac %s, provider %s, model %s", version, cfg.Provider.Type, cfg.Model()))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	llmProvider, err := provider.NewProvider(cfg, debugLog)
+	if err != nil {
+		_ = debugLog.Close()
+		return nil, nil, fmt.Errorf("failed to create LLM provider: %w", err)
+	}
+
+	if modelFlag != "" {
+		if err := provider.ValidateModel(ctx, llmProvider, modelFlag); err != nil {
+			_ = debugLog.Close()
+			return nil, nil, fmt.Errorf("invalid --model: %w", err)
+		}
+	}
+
+	return llmProvider, debugLog, nil
+}
+
+// watchInterval is how often git-ac watch looks at the index
+const watchInterval = time.Second
+
+// runWatch pre-generates a message whenever the staged changes have stayed the
+// same for watch.settle, and stores it for git-ac to use. It runs until
+// interrupted.
+func runWatch() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	repo := git.Repo{}
+	if err := repo.ValidateRepository(); err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	// Ask now, while someone is watching, rather than when changes are staged
+	if err := checkRemotePolicy(repo, cfg); err != nil {
+		return err
+	}
+
+	index, err := repo.GetGitPath("index")
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	color.FaintPrintf("Watching the staged changes (Ctrl-C to stop)...\n")
+
+	// The index changes whenever something is staged. Wait for it to stay the
+	// same for a while, then generate a message once for that state.
+	var seen, done string
+	var changed time.Time
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		if state := fileState(index); state != seen {
+			seen, changed = state, time.Now()
+		} else if state != done && time.Since(changed) >= cfg.Watch.Settle {
+			done = state
+			if err := pregenerate(ctx, repo, cfg, llmProvider); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				color.FaintPrintf("Couldn't pre-generate a message: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fileState identifies the current contents of a file by its size and
+// modification time, or is "" if the file doesn't exist
+func fileState(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d %d", info.Size(), info.ModTime().UnixNano())
+}
+
+// pregenerate generates and stores the messages for the staged changes,
+// unless there are none or they have already been generated
+func pregenerate(ctx context.Context, repo git.Repo, cfg *config.Config, llmProvider provider.LLMProvider) error {
+	diff, err := gitac.StagedDiff(repo.Dir, cfg)
+	if err != nil || diff == "" {
+		return err
+	}
+	prepared, err := gitac.PrepareDiff(diff, cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := pregen.Load(prepared.Diff, cfg); ok {
+		return nil
+	}
+
+	// Gather the same context git-ac would, so the messages are the same
+	project := gitac.LoadProjectContext(repo.Dir, cfg)
+	if issue := gitac.FindIssue(ctx, repo.Dir, cfg); issue != nil {
+		project.Issue = issue.String()
+	}
+
+	started := time.Now()
+	candidates, err := llmProvider.GenerateCandidates(ctx, prepared.Diff, project)
+	if err != nil {
+		return err
+	}
+	if err := pregen.Save(prepared.Diff, cfg, candidates); err != nil {
+		return fmt.Errorf("failed to store the message: %w", err)
+	}
+	color.FaintPrintf("Pre-generated a message in %v.\n", time.Since(started).Round(100*time.Millisecond))
+	return nil
+}
+
+// runServe serves the HTTP API until interrupted
+func runServe() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	// Check the provider and load the model now, so the first request doesn't pay for it
+	if err := llmProvider.HealthCheck(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: provider health check failed: %v\n", err)
+	} else if _, err := llmProvider.WarmUp(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load the model: %v\n", err)
+	}
+
+	listen := listenFlag
+	if listen == "" {
+		listen = defaultListen
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+
+	// With a token, serve as a gateway for other machines
+	token := authFlag
+	if token == "" {
+		token = os.Getenv("GIT_AC_AUTH_TOKEN")
+	}
+	// Without one, only repositories under the current directory are served
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get the current directory: %w", err)
+	}
+	if token == "" && !provider.IsLocalEndpoint("http://"+listener.Addr().String()) {
+		fmt.Fprintf(os.Stderr, "Warning: listening on %s without --auth; anyone who can reach it can use the provider, and read repositories in %s\n", listener.Addr(), root)
+	}
+
+	httpServer := &http.Server{
+		Handler:           server.New(cfg, llmProvider, server.Options{Token: token, Root: root, AllowText: allowTextFlag}).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = httpServer.Shutdown(context.Background())
+	}()
+
+	mode := ""
+	if token != "" {
+		mode = ", gateway mode"
+	}
+	fmt.Fprintf(os.Stderr, "Listening on http://%s (provider %s, model %s%s)\n", listener.Addr(), cfg.Provider.Type, cfg.Model(), mode)
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// runRPC answers JSON-RPC requests on stdin and stdout until stdin is closed
+func runRPC() error {
+	if colorFlag == "" {
+		_ = color.SetMode(color.ModeNever)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	llmProvider, debugLog, err := newProvider(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	return rpc.New(cfg, llmProvider).Serve(os.Stdin, os.Stdout)
+}
+
+// runHook runs as git's prepare-commit-msg hook, directly or through the
+// pre-commit framework, writing a generated message into the message file. It
+// leaves messages given with -m, -F, or -c, and merge or squash messages, alone.
+func runHook() error {
+	if hookTypeFlag != "" && hookTypeFlag != "prepare-commit-msg" {
+		return fmt.Errorf("unsupported --hook-type '%s' - git-ac only runs as a prepare-commit-msg hook", hookTypeFlag)
+	}
+	if len(commandArgs) == 0 {
+		return fmt.Errorf("hook needs the commit message file as an argument")
+	}
+
+	file := commandArgs[0]
+	// The pre-commit framework passes the message source in the environment
+	source := os.Getenv("PRE_COMMIT_COMMIT_MSG_SOURCE")
+	if len(commandArgs) > 1 {
+		source = commandArgs[1]
+	}
+	if source != "" && source != "template" {
+		return nil
+	}
+
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+	if source == "" && editor.StripComments(string(existing)) != "" {
+		return nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if timeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeBudget)
+		defer cancel()
+	}
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	// Generating checks the provider again if the warm-up failed, and reports why
+	warmUpInBackground(ctx, llmProvider)
+
+	// Hooks can't ask questions, so a remote provider needs consent given beforehand
+	result, err := gitac.Generate(ctx, gitac.Options{Config: cfg, Provider: llmProvider, RequireConsent: true})
+	if err != nil {
+		return err
+	}
+	for _, f := range result.Redacted {
+		fmt.Fprintf(os.Stderr, "git-ac: redacted a possible secret from the diff before sending it: %s\n", f)
+	}
+
+	// Keep git's comments (and any template) below the message
+	if err := os.WriteFile(file, []byte(result.Message+"\n"+string(existing)), 0o644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+
+	recordAudit(ctx, git.Repo{}, cfg, audit.Event{
+		Action:    audit.ActionHook,
+		Generated: result.Message,
+		Message:   result.Message,
+		Redacted:  len(result.Redacted),
+		Withheld:  result.Withheld,
+	})
+	return nil
+}
+
+// runGHA generates a commit message for a push, or a description for a pull
+// request, in GitHub Actions, and publishes it as step outputs and in the job
+// summary. It never prompts: running the action is consent to use the
+// configured provider, though privacy.allow_remote still applies.
+func runGHA() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	event, err := gha.LoadEvent()
+	if err != nil {
+		return err
+	}
+
+	diff, err := gitac.RangeDiff("", event.Base, event.Head, event.MergeBase, cfg)
+	if err != nil {
+		return fmt.Errorf("%w - check out the repository with fetch-depth: 0 so both commits are available", err)
+	}
+	if diff == "" {
+		return fmt.Errorf("no changes between %s and %s", event.Base, event.Head)
+	}
+
+	ctx := context.Background()
+	llmProvider, debugLog, err := newProvider(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = debugLog.Close()
+	}()
+
+	opts := gitac.Options{Config: cfg, Diff: diff, Provider: llmProvider}
+	var result *gitac.Result
+	if event.PullRequest {
+		result, err = gitac.DescribePullRequest(ctx, opts)
+	} else {
+		result, err = gitac.Generate(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, f := range result.Redacted {
+		gha.Warning("redacted a possible secret from the diff before sending it: " + f)
+	}
+
+	subject, body, _ := strings.Cut(result.Message, "\n")
+	outputs := [][2]string{
+		{"message", result.Message},
+		{"subject", subject},
+		{"body", strings.TrimSpace(body)},
+	}
+	for _, output := range outputs {
+		if err := gha.SetOutput(output[0], output[1]); err != nil {
+			return err
+		}
+	}
+
+	summary := "### Suggested commit message\n\n```\n" + result.Message + "\n```"
+	if event.PullRequest {
+		summary = "### Suggested pull request description\n\n#### " + subject + "\n\n" + strings.TrimSpace(body)
+	}
+	if err := gha.AppendSummary(summary); err != nil {
+		return err
+	}
+
+	fmt.Println(result.Message)
+	return nil
+}
+
+// checkRemotePolicy enforces privacy.allow_remote (or the repository's
+// git-ac.allowRemote) and asks for one-time consent before a repository's diff
+// is first sent to a remote provider
+func checkRemotePolicy(repo git.Repo, cfg *config.Config) error {
+	if err := gitac.CheckRemoteAllowed(repo.Dir, cfg); err != nil {
+		return err
+	}
+
+	host := gitac.RemoteConsentNeeded(repo.Dir, cfg)
+	if host == "" {
+		return nil
+	}
+
+	if !interactive() {
+		return fmt.Errorf("sending this repository's diff to %s needs one-time confirmation, but stdin is not a terminal - run git-ac interactively once, or run: git config git-ac.remoteConsent %s", host, host)
+	}
+
+	ok, err := prompt.Confirm(fmt.Sprintf("This will send the staged changes in this repository to %s. Allow this now and in future?", host), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("not sending the diff to %s", host)
+	}
+
+	if err := repo.AddLocalConfig("git-ac.remoteConsent", host); err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	return nil
+}
+
+// chooseCandidate lists the ranked candidates and asks which one to use
+func chooseCandidate(candidates []llm.Candidate) (string, error) {
+	options := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		options[i] = strconv.Itoa(i + 1)
+		fmt.Println()
+		fmt.Println(color.Faint(fmt.Sprintf("[%d]", i+1)))
+		fmt.Println(renderMessage(candidate.Message))
+	}
+	fmt.Println()
+
+	answer, err := prompt.Choice("Use which message?", options, "1")
+	if err != nil {
+		return "", err
+	}
+	index, _ := strconv.Atoi(answer)
+	return candidates[index-1].Message, nil
+}
+
+// stagedDiffStat returns the diffstat of the staged changes, or "" if it can't be read
+func stagedDiffStat(repo git.Repo) string {
+	diffStat, _ := repo.GetStagedDiffStat()
+	return diffStat
+}
+
+// confirmCommit shows the message, with the diffstat of the changes it
+// describes, and asks whether to commit it, returning the (possibly edited)
+// message, or "" if the user declined
+func confirmCommit(commitMsg, diffStat string) (string, error) {
+	if !interactive() {
+		return "", fmt.Errorf("cannot ask for confirmation because stdin is not a terminal (use --yes to commit without confirmation)")
+	}
+
+	for {
+		fmt.Println()
+		fmt.Println(renderMessage(commitMsg))
+		if diffStat != "" {
+			fmt.Println()
+			fmt.Println(color.Faint(diffStat))
+		}
+		fmt.Println()
+
+		answer, err := prompt.Choice("Commit with this message? (yes/no/edit)", []string{"y", "n", "e"}, "y")
+		if err != nil {
+			return "", err
+		}
+
+		switch answer {
+		case "y":
+			return commitMsg, nil
+		case "n":
+			return "", nil
+		case "e":
+			editedMsg, err := editor.Edit(commitMsg, editorComment())
+			if err != nil {
+				return "", fmt.Errorf("failed to edit commit message: %w", err)
+			}
+			if editedMsg == "" {
+				return "", nil
+			}
+			commitMsg = editedMsg
+		}
+	}
+}
+
+// renderMessage styles a commit message for display: bold subject, plain body, faint trailers
+func renderMessage(commitMsg string) string {
+	subject, body, trailers := llm.SplitMessage(commitMsg)
+
+	rendered := color.Strong(subject)
+	if body != "" {
+		rendered += "\n\n" + body
+	}
+	if trailers != "" {
+		rendered += "\n\n" + color.Faint(trailers)
+	}
+	return rendered
+}
+
+// editorComment builds the git-style comment shown below the message in the editor
+func editorComment() string {
+	var b strings.Builder
+	b.WriteString("Please enter the commit message for your changes. Comment lines\n")
+	b.WriteString("like these will be ignored, and an empty message aborts the commit.\n")
+
+	files, err := git.Repo{}.GetStagedFiles()
+	if err == nil && len(files) > 0 {
+		b.WriteString("\nChanges to be committed:\n")
+		for _, file := range files {
+			b.WriteString("\t" + file.Description() + "\n")
+		}
+	}
+
+	return b.String()
+}
+
 func showHelp() {
 	fmt.Println("git-ac - AI-powered commit message generator")
 	fmt.Println()
 	fmt.Println("USAGE:")
 	fmt.Println("  git-ac [flags]")
+	fmt.Println("  git-ac serve [--listen ADDR] [--auth TOKEN] [--allow-text] [flags]")
+	fmt.Println("  git-ac hook [--hook-type prepare-commit-msg] FILE [SOURCE [SHA]]")
+	fmt.Println("  git-ac stats")
+	fmt.Println("  git-ac bench [--samples N] [PROVIDER[@MODEL]...]")
+	fmt.Println("  git-ac eval [--count N] [flags]")
+	fmt.Println("  git-ac warm [flags]")
+	fmt.Println("  git-ac next-version [--tag]")
+	fmt.Println("  git-ac explain [COMMIT] [flags]")
+	fmt.Println("  git-ac review [-a] [flags]")
+	fmt.Println("  git-ac why [--count N] PATH [flags]")
+	fmt.Println("  git-ac standup [--days N] [flags]")
+	fmt.Println("  git-ac release-pr BASE..HEAD [flags]")
+	fmt.Println("  git-ac watch [flags]")
+	fmt.Println()
+	fmt.Println("COMMANDS:")
+	fmt.Println("  serve          Serve a local HTTP API (POST /generate, GET /health) for editor plugins")
+	fmt.Println("                 and scripts, keeping the provider connection and caches warm")
+	fmt.Println("  hook           Run as a prepare-commit-msg hook, writing the message into FILE")
+	fmt.Println("  stats          Compare models by acceptance rate, edits, latency, and tokens used")
+	fmt.Println("  bench          Compare models' validation pass rate and latency on the same diffs")
+	fmt.Println("  eval           Regenerate recent commits' messages and score them against the real ones")
+	fmt.Println("  warm           Load the Ollama model now, so the next run doesn't wait for it")
+	fmt.Println("  next-version   Suggest the next semantic version from the conventional commits since the last tag")
+	fmt.Println("  explain        Explain in plain language what COMMIT (default: HEAD) does and why")
+	fmt.Println("  describe-last  Generate a body for HEAD's subject line and amend HEAD with it")
+	fmt.Println("  review         Flag likely bugs, leftover debugging code, TODOs, and missing tests in the staged changes")
+	fmt.Println("  why            Tell how and why the file at PATH evolved, from the commits that changed it")
+	fmt.Println("  standup        Summarize your commits since yesterday (or in the last --days N) as a standup update")
+	fmt.Println("  release-pr     Write a release pull request body (highlights, breaking changes, upgrade notes) for BASE..HEAD")
+	fmt.Println("  watch          Pre-generate a message whenever the staged changes settle, so git-ac can use it at once")
 	fmt.Println()
 	fmt.Println("FLAGS:")
-	fmt.Println("  -a    Stage modified files before generating commit message")
-	fmt.Println("  -e    Edit the generated commit message in $EDITOR before committing")
-	fmt.Println("  -h    Show this help message")
-	fmt.Println("  -v    Show version")
+	fmt.Println("  -a, --all      Stage modified files before generating commit message")
+	fmt.Println("  -e, --edit     Edit the generated commit message in your git editor before committing")
+	fmt.Println("  -h, --help     Show this help message")
+	fmt.Println("  -v, --version  Show version")
+	fmt.Println("  -y, --yes      Commit without asking for confirmation")
+	fmt.Println()
+	fmt.Println("  --type TYPE    Use TYPE as the commit type (e.g., fix) instead of letting the model choose")
+	fmt.Println("  --scope SCOPE  Use SCOPE as the commit scope instead of letting the model choose")
+	fmt.Println("  --no-body      Generate a subject line only, without an extended description")
+	fmt.Println("  --subject S    Use S as the subject line; the model only writes the extended description")
+	fmt.Println("  --copy         Copy the message to the clipboard instead of committing")
+	fmt.Println("  --model MODEL  Use MODEL instead of the configured model for this run")
+	fmt.Println("  --provider P   Use provider type P (ollama, openai, gateway) or the profile named P for this run")
+	fmt.Println("  --color WHEN   Color output: auto (default), always, or never")
+	fmt.Println("  --debug-log F  Append the LLM requests and raw responses to file F")
+	fmt.Println("  --candidates N Generate N messages (1-9) and choose between them, best-ranked first")
+	fmt.Println("  --listen ADDR  Address for serve to listen on (default: " + defaultListen + ")")
+	fmt.Println("  --auth TOKEN   Serve as a team gateway that requires TOKEN (or $GIT_AC_AUTH_TOKEN)")
+	fmt.Println("  --allow-text   Let serve run free-form prompts on POST /text without --auth")
+	fmt.Println("  --rpc          Answer JSON-RPC requests on stdin/stdout, for editor extensions")
+	fmt.Println("  --gha          Run in GitHub Actions: describe the push or pull request as step outputs")
+	fmt.Println("  --ci           Never prompt, commit without confirmation, and exit with a distinct code per failure")
+	fmt.Println("  --time-budget D  Fail if generation takes longer than D (e.g. 90s; default 5m with --ci)")
+	fmt.Println("  --hook-type T  The hook that hook runs as (only prepare-commit-msg)")
+	fmt.Println("  --samples N    Number of recent commits bench uses when nothing is staged (default: 5)")
+	fmt.Println("  --count N      Number of recent commits eval (default: 20) or why (default: 30) reads")
+	fmt.Println("  --days N       Number of days standup looks back (default: standup.days, 1)")
+	fmt.Println("  --tag          Create the version next-version suggests as an annotated tag")
+	fmt.Println("  --no-health-check  Don't check that Ollama is running and has the model before generating")
+	fmt.Println("  --allow-conflict-markers  Generate a message even though the staged changes add conflict markers")
+	fmt.Println("  --json         With -v, print the version and build details as JSON")
+	fmt.Println("  --show-diff    Show the staged changes and confirm them before generating")
+	fmt.Println("  --stats        Print the health check time, tokens, generation time, and retries after generating")
+	fmt.Println()
+	fmt.Println("Short FLAGS may be combined (e.g., -ae is equivalent to -a -e), and -- ends the FLAGS")
 	fmt.Println()
-	fmt.Println("FLAGS may be combined (e.g., -ae is equivalent to -a -e)")
+	fmt.Println("EXIT CODES (with --ci):")
+	fmt.Println("  0  Committed")
+	fmt.Println("  1  Any other error, e.g. invalid configuration")
+	fmt.Println("  2  No staged changes")
+	fmt.Println("  3  The provider couldn't be reached (connection failure or server error)")
+	fmt.Println("  4  The model didn't produce a valid message within commit.max_attempts")
+	fmt.Println("  5  git commit failed")
+	fmt.Println("  6  Generation didn't finish within the time budget")
+	fmt.Println("  7  The provider didn't answer a request within provider.timeout")
 	fmt.Println()
 	fmt.Println("DESCRIPTION:")
-	fmt.Println("  git-ac generates commit messages for staged changes using Ollama.")
-	fmt.Println("  It analyzes git diff output and optionally includes README.md context.")
+	fmt.Println("  git-ac generates commit messages for staged changes using Ollama, an")
+	fmt.Println("  OpenAI-compatible API, or a team gateway.")
+	fmt.Println("  It analyzes git diff output and optionally includes README context.")
 	fmt.Println()
 	fmt.Println("CONFIGURATION:")
-	fmt.Println("  Configuration is read from ~/.config/git-ac.yaml")
+	fmt.Println("  Configuration is read from $XDG_CONFIG_HOME/git-ac/config.yaml")
+	fmt.Println("  (~/.config/git-ac/config.yaml by default; %APPDATA%\\git-ac\\config.yaml on")
+	fmt.Println("  Windows), or ~/.config/git-ac.yaml")
 	fmt.Println("  See git-ac.yaml.sample for an example configuration.")
 }
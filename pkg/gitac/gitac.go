@@ -0,0 +1,549 @@
+// Package gitac generates conventional commit messages for staged Git changes.
+// It is the engine behind the git-ac command, for Go tools (TUIs, bots, servers)
+// that want to generate messages without running git-ac as a subprocess.
+//
+// Most callers only need Generate:
+//
+//	cfg, err := gitac.LoadConfig()
+//	...
+//	result, err := gitac.Generate(ctx, gitac.Options{Config: cfg, Dir: repoDir})
+//	...
+//	fmt.Println(result.Message)
+package gitac
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"git-ac/internal/color"
+	"git-ac/internal/config"
+	"git-ac/internal/filter"
+	"git-ac/internal/git"
+	"git-ac/internal/linear"
+	"git-ac/internal/llm"
+	"git-ac/internal/provider"
+	"git-ac/internal/redact"
+)
+
+// Config is git-ac's configuration, as read from ~/.config/git-ac/config.yaml
+type Config = config.Config
+
+// Provider is a language model provider that generates commit messages
+type Provider = provider.LLMProvider
+
+// ProjectContext is the background about the project that is sent with the diff
+type ProjectContext = llm.ProjectContext
+
+// Candidate is a generated commit message and its ranking score
+type Candidate = llm.Candidate
+
+// Issue is a Linear issue that a branch is for
+type Issue = linear.Issue
+
+// Prompt is a prompt split into instructions and content
+type Prompt = llm.Prompt
+
+// ErrNoChanges is returned by Generate when there are no staged changes to describe
+var ErrNoChanges = errors.New("no staged changes found")
+
+// ErrNoConsent is returned by Generate with RequireConsent when the repository
+// hasn't consented to its diff being sent to the remote provider
+var ErrNoConsent = errors.New("this repository hasn't confirmed sending its diff to the remote provider")
+
+// LoadConfig reads the configuration file, applying defaults for anything it
+// doesn't set
+func LoadConfig() (*Config, error) {
+	return config.Load()
+}
+
+// NewProvider creates the provider selected in cfg
+func NewProvider(cfg *Config) (Provider, error) {
+	return provider.NewProvider(cfg, nil)
+}
+
+// Options controls Generate. Everything but Config is optional.
+type Options struct {
+	Config   *Config         // Configuration; LoadConfig's result if nil
+	Dir      string          // Directory inside the repository; "" for the current directory
+	Diff     string          // Diff to describe, in the configured diff format; the staged changes if ""
+	Project  *ProjectContext // Project context; gathered from the repository if nil
+	Provider Provider        // Provider to use; created from Config if nil
+
+	// Fail with ErrNoConsent rather than send the diff to a remote provider
+	// without the repository's recorded consent (privacy.confirm_remote)
+	RequireConsent bool
+
+	// A previously generated message and the user's feedback on it, to
+	// generate a revised message
+	PreviousMessage string
+	Feedback        string
+}
+
+// Result is a generated commit message and what was done to the diff to produce it
+type Result struct {
+	Message     string      // The best-ranked message
+	Candidates  []Candidate // All generated messages, best first
+	MovedBlocks int         // Number of moved blocks of code described in one line
+	Withheld    []string    // Files whose contents were withheld by privacy.redact_paths
+	Redacted    []string    // Descriptions of the possible secrets that were redacted
+}
+
+// Generate generates a commit message for the staged changes in opts.Dir, or
+// for opts.Diff. The diff is prepared the same way as by the git-ac command,
+// and privacy.allow_remote is enforced. Generate never prompts, so callers
+// with a user interface are responsible for asking before a diff is first sent
+// to a remote provider.
+func Generate(ctx context.Context, opts Options) (*Result, error) {
+	req, err := prepare(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := req.provider.GenerateCandidates(ctx, req.prepared.Diff, req.project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	// Only the staged changes can be counted; a caller's diff may be of anything
+	if opts.Diff == "" {
+		for i := range candidates {
+			candidates[i].Message = AddDiffStatFooter(candidates[i].Message, opts.Dir, req.cfg)
+		}
+	}
+	result := req.result(candidates)
+	if err := FilterCandidates(ctx, result.Candidates, opts.Dir, req.cfg); err != nil {
+		return nil, err
+	}
+	result.Message = result.Candidates[0].Message
+	return result, nil
+}
+
+// DescribePullRequest generates a pull request title and Markdown description
+// for opts.Diff (or the staged changes), prepared like Generate's. The title is
+// the first line of Result.Message.
+func DescribePullRequest(ctx context.Context, opts Options) (*Result, error) {
+	req, err := prepare(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, _ := llm.FitDiff(req.prepared.Diff, req.cfg.Commit.DiffTokenLimit)
+	description, err := req.provider.GenerateText(ctx, llm.BuildPRDescriptionPrompt(diff, req.project))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pull request description: %w", err)
+	}
+
+	return req.result([]Candidate{{Message: description}}), nil
+}
+
+// request is a prepared diff with everything needed to send it to the provider
+type request struct {
+	cfg      *Config
+	prepared *PreparedDiff
+	project  ProjectContext
+	issue    *Issue
+	provider Provider
+}
+
+// prepare gets and prepares the diff for opts, and checks it may be sent to
+// the provider
+func prepare(ctx context.Context, opts Options) (*request, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		var err error
+		if cfg, err = LoadConfig(); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	diff := opts.Diff
+	if diff == "" {
+		if err := (git.Repo{Dir: opts.Dir}).ValidateRepository(); err != nil {
+			return nil, fmt.Errorf("not in a git repository: %w", err)
+		}
+
+		var err error
+		if diff, err = StagedDiff(opts.Dir, cfg); err != nil {
+			return nil, err
+		}
+		if diff == "" {
+			return nil, ErrNoChanges
+		}
+	}
+
+	prepared, err := PrepareDiff(diff, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckRemoteAllowed(opts.Dir, cfg); err != nil {
+		return nil, err
+	}
+	if opts.RequireConsent {
+		if host := RemoteConsentNeeded(opts.Dir, cfg); host != "" {
+			return nil, fmt.Errorf("%w at %s - run git-ac in the repository once, or run: git config git-ac.remoteConsent %s", ErrNoConsent, host, host)
+		}
+	}
+
+	var project ProjectContext
+	var issue *Issue
+	if opts.Project != nil {
+		project = *opts.Project
+	} else {
+		project = LoadProjectContext(opts.Dir, cfg)
+		if issue = FindIssue(ctx, opts.Dir, cfg); issue != nil {
+			project.Issue = issue.String()
+		}
+	}
+	project.PreviousMessage = opts.PreviousMessage
+	project.Feedback = opts.Feedback
+
+	p := opts.Provider
+	if p == nil {
+		if p, err = NewProvider(cfg); err != nil {
+			return nil, fmt.Errorf("failed to create LLM provider: %w", err)
+		}
+	}
+
+	return &request{cfg: cfg, prepared: prepared, project: project, issue: issue, provider: p}, nil
+}
+
+// result reports the generated candidates, best first
+func (r *request) result(candidates []Candidate) *Result {
+	for i := range candidates {
+		candidates[i].Message = AddIssueFooter(candidates[i].Message, r.issue, r.cfg)
+	}
+	return &Result{
+		Message:     candidates[0].Message,
+		Candidates:  candidates,
+		MovedBlocks: r.prepared.MovedBlocks,
+		Withheld:    r.prepared.Withheld,
+		Redacted:    r.prepared.Redacted,
+	}
+}
+
+// StagedDiff returns the staged changes in the repository containing dir, in
+// the format set by cfg.Diff. It returns "" if nothing is staged. Changes
+// larger than diff.max_bytes are replaced with a summary of each file's changes.
+func StagedDiff(dir string, cfg *Config) (string, error) {
+	repo := git.Repo{Dir: dir}
+	diff, err := repo.GetStagedDiff(git.DiffOptions{
+		NoContext: !cfg.Diff.ContextLines,
+		Unified:   cfg.Diff.Format == "unified",
+		MaxBytes:  cfg.Diff.MaxBytes,
+	})
+	if errors.Is(err, git.ErrDiffTooLarge) {
+		color.FaintPrintf("The staged diff is over diff.max_bytes (%d bytes); describing it from a summary of each file's changes.\n", cfg.Diff.MaxBytes)
+		summary, err := repo.GetStagedDiffSummary()
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize staged changes: %w", err)
+		}
+		return "The full diff is too large to include. A summary of the changes to each file follows.\n\n" + summary, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged changes: %w", err)
+	}
+	return diff, nil
+}
+
+// RangeDiff returns the changes between two revisions in the repository
+// containing dir, in the format set by cfg.Diff. With mergeBase, the changes
+// are taken from where head branched off base, as for a pull request.
+func RangeDiff(dir, base, head string, mergeBase bool, cfg *Config) (string, error) {
+	return git.Repo{Dir: dir}.GetRangeDiff(base, head, mergeBase, git.DiffOptions{
+		NoContext: !cfg.Diff.ContextLines,
+		Unified:   cfg.Diff.Format == "unified",
+	})
+}
+
+// PreparedDiff is a diff that is ready to be sent to the provider
+type PreparedDiff struct {
+	Diff        string
+	MovedBlocks int      // Number of moved blocks of code described in one line
+	LFSAssets   int      // Number of Git LFS pointer files described as asset changes
+	Transcoded  int      // Number of lines that weren't valid UTF-8 or had control characters
+	Withheld    []string // Files whose contents were withheld by privacy.redact_paths
+	Redacted    []string // Descriptions of the possible secrets that were redacted
+}
+
+// PrepareDiff describes LFS assets, collapses moved code, and removes sensitive files and secrets from
+// diff, as configured in cfg
+func PrepareDiff(diff string, cfg *Config) (*PreparedDiff, error) {
+	prepared := &PreparedDiff{}
+
+	// Send text the provider can encode, and measure it in characters
+	diff, prepared.Transcoded = llm.ToUTF8(diff)
+
+	// Describe LFS assets by name and size rather than by their pointer files
+	diff, prepared.LFSAssets = llm.DescribeLFSPointers(diff)
+
+	// Describe moved code in one line rather than as a removal and an addition
+	diff, prepared.MovedBlocks = llm.CollapseMoves(diff, cfg.Diff.MinMoved)
+
+	// Keep secrets and sensitive files out of the prompt
+	redactor, err := redact.New(cfg.Privacy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up redaction: %w", err)
+	}
+	diff, prepared.Withheld = redactor.WithholdPaths(diff)
+	if cfg.Privacy.RedactSecrets {
+		var findings []redact.Finding
+		diff, findings = redactor.Redact(diff)
+		prepared.Redacted = redact.Summary(findings)
+	}
+
+	prepared.Diff = diff
+	return prepared, nil
+}
+
+// CheckRemoteAllowed returns an error if the configured provider is remote and
+// privacy.allow_remote, or the repository's git-ac.allowRemote, forbids that
+func CheckRemoteAllowed(dir string, cfg *Config) error {
+	endpoint := provider.Endpoint(cfg)
+	if provider.IsLocalEndpoint(endpoint) {
+		return nil
+	}
+
+	allowRemote := cfg.Privacy.AllowRemote
+	if repoAllow, ok := (git.Repo{Dir: dir}).GetConfigBool("git-ac.allowRemote"); ok {
+		allowRemote = repoAllow
+	}
+	if !allowRemote {
+		return fmt.Errorf("this repository only allows local providers, but %s is remote - use a local provider, or change git-ac.allowRemote / privacy.allow_remote", endpoint)
+	}
+	return nil
+}
+
+// RemoteConsentNeeded returns the host of the configured provider if the
+// repository containing dir still needs to consent to its diff being sent
+// there (privacy.confirm_remote), or "" if it doesn't. Consent is recorded in
+// the repository's git-ac.remoteConsent setting.
+func RemoteConsentNeeded(dir string, cfg *Config) string {
+	endpoint := provider.Endpoint(cfg)
+	if provider.IsLocalEndpoint(endpoint) || !cfg.Privacy.ConfirmRemote {
+		return ""
+	}
+
+	host := provider.EndpointHost(endpoint)
+	for _, consented := range (git.Repo{Dir: dir}).GetConfigAll("git-ac.remoteConsent") {
+		if consented == host {
+			return ""
+		}
+	}
+	return host
+}
+
+// LoadProjectContext gathers the project context for the repository containing
+// dir: the relevant parts of its README, any context files, and the configured
+// instructions
+func LoadProjectContext(dir string, cfg *Config) ProjectContext {
+	repo := git.Repo{Dir: dir}
+	readmeName, readme := repo.GetReadme()
+	project := ProjectContext{
+		Readme:            llm.ExtractReadme(readmeName, readme, cfg.Prompt.Readme),
+		Files:             loadContextFiles(repo, cfg.Prompt),
+		SystemPrefix:      cfg.Prompt.SystemPrefix,
+		ExtraInstructions: cfg.Prompt.ExtraInstructions,
+		Conventions:       loadConventions(repo, cfg.Prompt),
+	}
+	// The types the conventions list replace the default ones, but not the
+	// user's own commit.types
+	if project.Conventions != nil && reflect.DeepEqual(cfg.Commit.Types, config.DefaultCommitTypes()) {
+		project.Types = llm.ConventionTypes(project.Conventions.Content)
+	}
+	// Scopes from history are only a guide for teams that haven't listed theirs
+	if cfg.Prompt.LearnScopes && len(cfg.Commit.Scopes) == 0 {
+		project.Scopes = learnScopes(repo)
+	}
+	return project
+}
+
+const (
+	scopeHistory = 1000               // Commit subjects scopes are learned from
+	scopeRefresh = 7 * 24 * time.Hour // How long learned scopes are used before they're learned again
+)
+
+// learnedScopes is the cache of the scopes learned from a repository's history,
+// kept in .git/git-ac/scopes.json
+type learnedScopes struct {
+	Learned time.Time `json:"learned"`
+	Scopes  []string  `json:"scopes"`
+}
+
+// learnScopes returns the scopes the repository's recent commits use. They're
+// learned on the first run in the repository and cached in its git directory,
+// then learned again once the cache is a week old.
+func learnScopes(repo git.Repo) []string {
+	gitDir, err := repo.GetGitDir()
+	if err != nil {
+		return nil
+	}
+	path := filepath.Join(gitDir, "git-ac", "scopes.json")
+
+	var cached learnedScopes
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cached) == nil &&
+		time.Since(cached.Learned) < scopeRefresh {
+		return cached.Scopes
+	}
+
+	subjects, err := repo.GetRecentSubjects(scopeHistory)
+	if err != nil {
+		// Likely no commits yet
+		return nil
+	}
+	learned := learnedScopes{Learned: time.Now(), Scopes: llm.LearnScopes(subjects)}
+	if data, err := json.Marshal(learned); err == nil && os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+		// Without the cache, they're learned again next time
+		_ = os.WriteFile(path, data, 0o644)
+	}
+	return learned.Scopes
+}
+
+// loadConventions reads the first of llm.ConventionFiles at the repository
+// root, if prompt.conventions is on and there is one
+func loadConventions(repo git.Repo, promptConfig config.PromptConfig) *llm.ContextFile {
+	if !promptConfig.Conventions {
+		return nil
+	}
+	root, err := repo.GetRepositoryRoot()
+	if err != nil {
+		root = cmp.Or(repo.Dir, ".")
+	}
+	for _, name := range llm.ConventionFiles {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		if content := strings.TrimSpace(string(content)); content != "" {
+			return &llm.ContextFile{Path: name, Content: llm.LimitLines(content, promptConfig.ContextFileLines)}
+		}
+	}
+	return nil
+}
+
+// FindIssue returns the Linear issue named in the checked-out branch of the
+// repository containing dir, if linear.enabled is set. The issue's title is
+// looked up when there is an API key; without one, any ID in the branch name
+// is trusted. It returns nil if there is no issue, or if the lookup failed and
+// the ID isn't from one of linear.teams.
+func FindIssue(ctx context.Context, dir string, cfg *Config) *Issue {
+	if !cfg.Linear.Enabled {
+		return nil
+	}
+	id := linear.IssueID((git.Repo{Dir: dir}).GetCurrentBranch(), cfg.Linear.Teams)
+	if id == "" {
+		return nil
+	}
+
+	apiKey := cmp.Or(cfg.Linear.APIKey, os.Getenv("LINEAR_API_KEY"))
+	if apiKey == "" {
+		return &Issue{ID: id}
+	}
+	issue, err := linear.NewClient(apiKey).Issue(ctx, id)
+	if err != nil {
+		color.FaintPrintf("Couldn't look up Linear issue %s: %v\n", id, err)
+		if len(cfg.Linear.Teams) > 0 {
+			return &Issue{ID: id}
+		}
+		return nil
+	}
+	return issue
+}
+
+// AddIssueFooter ends message with linear.magic_word and the issue's ID, so
+// Linear links the commit to the issue. Messages that already mention the
+// issue are left alone.
+func AddIssueFooter(message string, issue *Issue, cfg *Config) string {
+	if issue == nil || cfg.Linear.MagicWord == "none" || strings.Contains(strings.ToUpper(message), issue.ID) {
+		return message
+	}
+	return llm.InsertBeforeTrailers(message, cfg.Linear.MagicWord+" "+issue.ID)
+}
+
+// AddDiffStatFooter ends message with a line counting the staged changes in
+// the repository containing dir, such as "3 files changed, +120/-45", when
+// commit.diffstat_footer is on
+func AddDiffStatFooter(message, dir string, cfg *Config) string {
+	if !cfg.Commit.DiffStatFooter {
+		return message
+	}
+	files, added, removed, err := git.Repo{Dir: dir}.GetStagedLineCounts()
+	if err != nil || files == 0 {
+		return message
+	}
+	noun := "files"
+	if files == 1 {
+		noun = "file"
+	}
+	return llm.InsertBeforeTrailers(message, fmt.Sprintf("%d %s changed, +%d/-%d", files, noun, added, removed))
+}
+
+// FilterCandidates passes each candidate's message through the commands in
+// commit.filters, which run in dir
+func FilterCandidates(ctx context.Context, candidates []Candidate, dir string, cfg *Config) error {
+	for i := range candidates {
+		message, err := filter.Run(ctx, candidates[i].Message, cfg.Commit.Filters, dir)
+		if err != nil {
+			return err
+		}
+		candidates[i].Message = message
+	}
+	return nil
+}
+
+// loadContextFiles reads the configured context files from the repository root,
+// skipping (with a notice) any that can't be read
+func loadContextFiles(repo git.Repo, promptConfig config.PromptConfig) []llm.ContextFile {
+	if len(promptConfig.ContextFiles) == 0 {
+		return nil
+	}
+
+	root, err := repo.GetRepositoryRoot()
+	if err != nil {
+		root = repo.Dir
+	}
+
+	var files []llm.ContextFile
+	for _, path := range promptConfig.ContextFiles {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			color.FaintPrintf("Skipping context file %s: %v\n", path, err)
+			continue
+		}
+		files = append(files, llm.ContextFile{
+			Path:    path,
+			Content: llm.LimitLines(strings.TrimSpace(string(content)), promptConfig.ContextFileLines),
+		})
+	}
+	return files
+}
+
+// BuildPrompt returns the prompt git-ac sends to the model for diff
+func BuildPrompt(diff string, project ProjectContext, cfg *Config) Prompt {
+	return llm.BuildCommitPrompt(llm.PromptInput{
+		Content:         diff,
+		Project:         project,
+		BreakingChanges: llm.DetectBreakingChanges(diff),
+		Release:         llm.DetectRelease(diff),
+	}, cfg.Commit)
+}
+
+// CleanMessage turns a raw model response into a commit message, removing
+// reasoning and commentary and applying the configured formatting
+func CleanMessage(response string, cfg *Config) (string, error) {
+	return llm.FinishMessage(response, llm.PromptInput{}, cfg.Commit)
+}
+
+// ValidateMessage checks message against the configured commit conventions.
+// The error describes what is wrong with it.
+func ValidateMessage(message string, cfg *Config) error {
+	return llm.ValidateMessage(message, cfg.Commit)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"git-ac/internal/color"
+	"git-ac/internal/llm"
+	"git-ac/pkg/gitac"
+)
+
+// runReleasePR writes the Markdown body of a pull request that releases the
+// commits in BASE..HEAD: highlights, breaking changes, and upgrade notes
+func runReleasePR() error {
+	if len(commandArgs) != 1 {
+		return fmt.Errorf("release-pr takes one range, BASE..HEAD")
+	}
+	base, head, found := strings.Cut(commandArgs[0], "..")
+	if !found {
+		head = "HEAD"
+	}
+	if base == "" || strings.HasPrefix(head, ".") {
+		return fmt.Errorf("invalid range '%s' (expected BASE..HEAD, e.g. v1.4.0..release/1.5)", commandArgs[0])
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	commits, err := repo.GetRangeCommits(base, head)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits in %s..%s", base, head)
+	}
+
+	// Only declarations are taken from the diff, but they're sent to the
+	// provider, so sensitive files and secrets are kept out of them as usual
+	diff, err := gitac.RangeDiff(repo.Dir, base, head, false, cfg)
+	if err != nil {
+		return err
+	}
+	prepared, err := gitac.PrepareDiff(diff, cfg)
+	if err != nil {
+		return err
+	}
+	breaking := llm.DetectBreakingChanges(prepared.Diff)
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	color.FaintPrintf("Describing %d commits in %s..%s...\n", len(commits), base, head)
+	body, err := llmProvider.GenerateText(ctx, llm.BuildReleasePRPrompt(commitLog(commits, cfg.Commit.DiffTokenLimit), breaking, project))
+	if err != nil {
+		return fmt.Errorf("failed to describe the release: %w", err)
+	}
+
+	fmt.Println(body)
+	return nil
+}
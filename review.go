@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git-ac/internal/color"
+	"git-ac/internal/llm"
+	"git-ac/pkg/gitac"
+)
+
+// runReview asks the model to flag bugs, leftover debugging code, TODOs, and
+// missing tests in the staged changes before they are committed
+func runReview() error {
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	if allFlag {
+		if err := repo.StageAllChanges(); err != nil {
+			return fmt.Errorf("failed to stage all changes: %w", err)
+		}
+	}
+	diff, err := gitac.StagedDiff(repo.Dir, cfg)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		return fmt.Errorf("%w (use -a to stage modified files)", gitac.ErrNoChanges)
+	}
+	prepared, err := gitac.PrepareDiff(diff, cfg)
+	if err != nil {
+		return err
+	}
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	color.FaintPrintf("Reviewing staged changes...\n")
+	diff, omitted := llm.FitDiff(prepared.Diff, cfg.Commit.DiffTokenLimit)
+	review, err := llmProvider.GenerateText(ctx, llm.BuildReviewPrompt(diff, project))
+	if err != nil {
+		return fmt.Errorf("failed to review staged changes: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Println(review)
+	if omitted > 0 {
+		color.FaintPrintf("\n%d hunks were left out to fit commit.diff_token_limit and weren't reviewed.\n", omitted)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git-ac/internal/color"
+	"git-ac/internal/git"
+	"git-ac/internal/llm"
+)
+
+// runStandup summarizes the user's recent commits as a standup update
+func runStandup() error {
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	days := cfg.Standup.Days
+	if daysFlag != "" {
+		days, err = strconv.Atoi(daysFlag)
+		if err != nil || days < 1 {
+			return fmt.Errorf("invalid --days '%s' (must be a positive number)", daysFlag)
+		}
+	}
+	since, period := standupPeriod(time.Now(), days)
+
+	author := repo.GetConfig("user.email")
+	if author == "" {
+		return fmt.Errorf("git user.email is not set - git-ac standup finds your commits by it")
+	}
+	commits, err := repo.GetAuthoredCommits(author, since, cfg.Standup.Branches)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Printf("No commits by %s %s.\n", author, period)
+		return nil
+	}
+
+	llmProvider, _, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+
+	color.FaintPrintf("Summarizing %d commits %s...\n", len(commits), period)
+	update, err := llmProvider.GenerateText(ctx, llm.BuildStandupPrompt(commitLog(commits, cfg.Commit.DiffTokenLimit), period))
+	if err != nil {
+		return fmt.Errorf("failed to summarize commits: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Println(update)
+	return nil
+}
+
+// standupPeriod returns when a standup update covering days days starts, and
+// describes the period. One day on a Monday reaches back to Friday.
+func standupPeriod(now time.Time, days int) (time.Time, string) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if days == 1 && now.Weekday() == time.Monday {
+		return midnight.AddDate(0, 0, -3), "since Friday"
+	}
+	if days == 1 {
+		return midnight.AddDate(0, 0, -1), "since yesterday"
+	}
+	return midnight.AddDate(0, 0, -days), fmt.Sprintf("in the last %d days", days)
+}
+
+// commitLog formats commits, oldest first, with their diffstats for a prompt.
+// The diffstats are left out if they don't all fit within tokenLimit, and
+// then the oldest commits.
+func commitLog(commits []git.LogCommit, tokenLimit int) string {
+	format := func(commits []git.LogCommit, stats bool) string {
+		var b strings.Builder
+		for i := len(commits) - 1; i >= 0; i-- {
+			c := commits[i]
+			fmt.Fprintf(&b, "commit %s (%s)\n%s\n", c.Hash[:7], c.Date, c.Message)
+			if stats {
+				b.WriteString(c.Patch + "\n")
+			}
+			b.WriteString("\n")
+		}
+		return strings.TrimSpace(b.String())
+	}
+
+	if text := format(commits, true); llm.EstimateTokens(text) <= tokenLimit {
+		return text
+	}
+	for len(commits) > 1 && llm.EstimateTokens(format(commits, false)) > tokenLimit {
+		commits = commits[:len(commits)-1]
+	}
+	return format(commits, false)
+}
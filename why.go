@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"git-ac/internal/color"
+	"git-ac/internal/filehistory"
+	"git-ac/internal/provider"
+	"git-ac/pkg/gitac"
+)
+
+// defaultWhyCount is the number of commits why reads without --count
+const defaultWhyCount = 30
+
+// runWhy tells how and why a file evolved, from the commits that changed it
+func runWhy() error {
+	if len(commandArgs) != 1 {
+		return fmt.Errorf("why takes one file path")
+	}
+	path := commandArgs[0]
+
+	cmd, err := startSubcommand()
+	if err != nil {
+		return err
+	}
+	defer cmd.close()
+	ctx, repo, cfg := cmd.ctx, cmd.repo, cmd.cfg
+
+	n := defaultWhyCount
+	if countFlag != "" {
+		n, err = strconv.Atoi(countFlag)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid --count '%s' (must be a positive number)", countFlag)
+		}
+	}
+
+	commits, err := repo.GetFileHistory(path, n)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits changed %s", path)
+	}
+	for i := range commits {
+		// Keep secrets and sensitive files out of the prompt, as for new commits
+		prepared, err := gitac.PrepareDiff(commits[i].Patch, cfg)
+		if err != nil {
+			return err
+		}
+		commits[i].Patch = prepared.Diff
+	}
+
+	llmProvider, project, err := cmd.openProvider()
+	if err != nil {
+		return err
+	}
+	summaryCache, err := provider.NewSummaryCache(cfg.Cache)
+	if err != nil {
+		return err
+	}
+
+	color.FaintPrintf("Reading %d commits that changed %s...\n", len(commits), path)
+	narrative, err := filehistory.Narrate(ctx, llmProvider, path, commits, filehistory.Options{
+		TokenLimit: cfg.Commit.DiffTokenLimit,
+		Model:      cfg.Model(),
+		Cache:      summaryCache,
+		Project:    project,
+		Progress: func(chunk, chunks int) {
+			color.FaintPrintf("Summarizing part %d of %d...\n", chunk, chunks)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to summarize the history of %s: %w", path, err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Println(narrative)
+	return nil
+}